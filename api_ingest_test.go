@@ -0,0 +1,426 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	config "github.com/drummonds/goEDMS/config"
+	database "github.com/drummonds/goEDMS/database"
+	engine "github.com/drummonds/goEDMS/engine"
+	"github.com/labstack/echo/v4"
+)
+
+// ingestJobsResponse is the body of GET /api/ingest/jobs
+type ingestJobsResponse struct {
+	Jobs   []database.IngestJob `json:"jobs"`
+	Counts map[string]int       `json:"counts"`
+	Count  int                  `json:"count"`
+}
+
+// quarantineResponse is the body of GET /api/quarantine
+type quarantineResponse struct {
+	Files []engine.QuarantineReport `json:"files"`
+	Count int                       `json:"count"`
+}
+
+// duplicatesResponse is the body of GET /api/duplicates
+type duplicatesResponse struct {
+	Duplicates []database.DuplicateRecord `json:"duplicates"`
+	Counts     map[string]int             `json:"counts"`
+	Count      int                        `json:"count"`
+	Policy     string                     `json:"policy"`
+}
+
+// setupIngestFolders points the server at temporary ingress, document, done, quarantine and duplicate folders
+func setupIngestFolders(t *testing.T, serverHandler *engine.ServerHandler) string {
+	tempDir := t.TempDir()
+	serverHandler.ServerConfig.IngressPath = filepath.Join(tempDir, "ingress")
+	serverHandler.ServerConfig.DocumentPath = filepath.Join(tempDir, "documents")
+	serverHandler.ServerConfig.NewDocumentFolderRel = "New"
+	serverHandler.ServerConfig.NewDocumentFolder = filepath.Join(tempDir, "documents", "New")
+	serverHandler.ServerConfig.IngressMoveFolder = filepath.Join(tempDir, "done")
+	serverHandler.ServerConfig.QuarantineFolder = filepath.Join(tempDir, "quarantine")
+	serverHandler.ServerConfig.QuarantineAttempts = 0
+	serverHandler.ServerConfig.DuplicateFolder = filepath.Join(tempDir, "duplicates")
+	serverHandler.ServerConfig.DuplicatePolicy = config.DuplicatePolicyReject
+	serverHandler.ServerConfig.TrashFolder = filepath.Join(tempDir, "trash")
+	serverHandler.ServerConfig.UploadFolder = filepath.Join(tempDir, "uploads")
+	serverHandler.ServerConfig.ThumbnailFolder = filepath.Join(tempDir, "thumbnails")
+	serverHandler.ServerConfig.IngressPreserve = false
+	serverHandler.ServerConfig.IngressDelete = false
+	serverHandler.ServerConfig.IngressWorkers = 2
+	for _, dir := range []string{serverHandler.ServerConfig.IngressPath, serverHandler.ServerConfig.NewDocumentFolder, serverHandler.ServerConfig.IngressMoveFolder} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatalf("Failed to create %s: %v", dir, err)
+		}
+	}
+	if err := serverHandler.DB.SaveConfig(&serverHandler.ServerConfig); err != nil {
+		t.Fatalf("Failed to save config: %v", err)
+	}
+	return serverHandler.ServerConfig.IngressPath
+}
+
+// waitForIngestJobs polls the job list until nothing is waiting or in progress
+func waitForIngestJobs(t *testing.T, e *echo.Echo) ingestJobsResponse {
+	t.Helper()
+	var response ingestJobsResponse
+	deadline := time.Now().Add(15 * time.Second)
+	for time.Now().Before(deadline) {
+		req := httptest.NewRequest(http.MethodGet, "/api/ingest/jobs", nil)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+		response = ingestJobsResponse{}
+		if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+			t.Fatalf("Failed to parse jobs response: %v", err)
+		}
+		active := response.Counts[database.IngestJobQueued] + response.Counts[database.IngestJobExtracting] + response.Counts[database.IngestJobOCR]
+		if response.Count > 0 && active == 0 {
+			return response
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	t.Fatalf("Ingest jobs did not finish: %+v", response.Counts)
+	return response
+}
+
+// TestIngestJobsAPI tests the ingest job queue endpoints
+func TestIngestJobsAPI(t *testing.T) {
+	e, serverHandler, cleanup := setupTestServer(t)
+	defer cleanup()
+	ingressPath := setupIngestFolders(t, serverHandler)
+
+	goodFile := filepath.Join(ingressPath, "ingest_job_notes.txt")
+	badFile := filepath.Join(ingressPath, "ingest_job_broken.xyz")
+	os.WriteFile(goodFile, []byte("Notes recorded by the ingest job test"), 0644)
+	os.WriteFile(badFile, []byte{0x00, 0x01, 0x02, 0x03}, 0644)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/ingest", nil)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	response := waitForIngestJobs(t, e)
+	jobs := make(map[string]database.IngestJob)
+	for _, job := range response.Jobs {
+		jobs[job.FilePath] = job
+	}
+
+	stored, ok := jobs[goodFile]
+	if !ok {
+		t.Fatalf("No job recorded for %s", goodFile)
+	}
+	if stored.State != database.IngestJobStored || stored.DocumentULID == "" || stored.Attempts != 1 {
+		t.Errorf("Expected stored job with document and 1 attempt, got %+v", stored)
+	}
+	if stored.StartedAt == nil || stored.FinishedAt == nil {
+		t.Errorf("Expected stored job to have timings, got %+v", stored)
+	}
+
+	failed, ok := jobs[badFile]
+	if !ok {
+		t.Fatalf("No job recorded for %s", badFile)
+	}
+	if failed.State != database.IngestJobFailed || failed.Error == "" {
+		t.Errorf("Expected failed job with an error message, got %+v", failed)
+	}
+
+	t.Run("Get job", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/api/ingest/jobs/%d", stored.ID), nil)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+		var job database.IngestJob
+		if err := json.Unmarshal(rec.Body.Bytes(), &job); err != nil {
+			t.Fatalf("Failed to parse job: %v", err)
+		}
+		if job.ID != stored.ID || job.FilePath != goodFile {
+			t.Errorf("Expected job %d for %s, got %+v", stored.ID, goodFile, job)
+		}
+	})
+
+	t.Run("Bad requests", func(t *testing.T) {
+		for url, want := range map[string]int{
+			"/api/ingest/jobs/999999":      http.StatusNotFound,
+			"/api/ingest/jobs/abc":         http.StatusBadRequest,
+			"/api/ingest/jobs?state=bogus": http.StatusBadRequest,
+		} {
+			req := httptest.NewRequest(http.MethodGet, url, nil)
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+			if rec.Code != want {
+				t.Errorf("%s: expected status %d, got %d", url, want, rec.Code)
+			}
+		}
+	})
+
+	t.Run("Filter by state", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/api/ingest/jobs?state=failed", nil)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		var filtered ingestJobsResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &filtered); err != nil {
+			t.Fatalf("Failed to parse jobs response: %v", err)
+		}
+		for _, job := range filtered.Jobs {
+			if job.State != database.IngestJobFailed {
+				t.Errorf("Expected only failed jobs, got %+v", job)
+			}
+		}
+	})
+
+	t.Run("Retry", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, fmt.Sprintf("/api/ingest/jobs/%d/retry", stored.ID), nil)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		if rec.Code != http.StatusConflict {
+			t.Errorf("Expected 409 retrying a stored job, got %d", rec.Code)
+		}
+
+		req = httptest.NewRequest(http.MethodPost, fmt.Sprintf("/api/ingest/jobs/%d/retry", failed.ID), nil)
+		rec = httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		if rec.Code != http.StatusAccepted {
+			t.Fatalf("Expected 202 retrying a failed job, got %d: %s", rec.Code, rec.Body.String())
+		}
+		waitForIngestJobs(t, e)
+
+		req = httptest.NewRequest(http.MethodGet, fmt.Sprintf("/api/ingest/jobs/%d", failed.ID), nil)
+		rec = httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		var job database.IngestJob
+		json.Unmarshal(rec.Body.Bytes(), &job)
+		if job.State != database.IngestJobFailed || job.Attempts != 2 {
+			t.Errorf("Expected retried job to fail again on attempt 2, got %+v", job)
+		}
+	})
+}
+
+// TestQuarantineAPI tests that files which keep failing are quarantined and can be retried or discarded
+func TestQuarantineAPI(t *testing.T) {
+	e, serverHandler, cleanup := setupTestServer(t)
+	defer cleanup()
+	ingressPath := setupIngestFolders(t, serverHandler)
+	serverHandler.ServerConfig.QuarantineAttempts = 1
+
+	badFile := filepath.Join(ingressPath, "quarantine_broken.xyz")
+	os.WriteFile(badFile, []byte{0x00, 0x01, 0x02, 0x03}, 0644)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/ingest", nil)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	waitForIngestJobs(t, e)
+	if _, err := os.Stat(badFile); !os.IsNotExist(err) {
+		t.Errorf("Expected failed file to be moved out of ingress, stat returned %v", err)
+	}
+
+	listQuarantine := func() quarantineResponse {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodGet, "/api/quarantine", nil)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+		var response quarantineResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+			t.Fatalf("Failed to parse quarantine response: %v", err)
+		}
+		return response
+	}
+
+	quarantined := listQuarantine()
+	if quarantined.Count != 1 {
+		t.Fatalf("Expected 1 quarantined file, got %+v", quarantined)
+	}
+	report := quarantined.Files[0]
+	if report.OriginalPath != badFile || report.Reason == "" || len(report.Attempts) != 1 || report.JobID == 0 {
+		t.Errorf("Unexpected quarantine report %+v", report)
+	}
+
+	job, err := serverHandler.DB.GetIngestJob(report.JobID)
+	if err != nil {
+		t.Fatalf("Failed to fetch job: %v", err)
+	}
+	if job.State != database.IngestJobQuarantined {
+		t.Errorf("Expected job to be quarantined, got %s", job.State)
+	}
+
+	t.Run("Retry", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/api/quarantine/"+report.ID+"/retry", nil)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		if rec.Code != http.StatusAccepted {
+			t.Fatalf("Expected 202, got %d: %s", rec.Code, rec.Body.String())
+		}
+		waitForIngestJobs(t, e)
+
+		// Still broken, so after its fresh attempt it goes back to quarantine with both attempts in its report
+		quarantined := listQuarantine()
+		if quarantined.Count != 1 || len(quarantined.Files[0].Attempts) != 2 {
+			t.Fatalf("Expected file back in quarantine after 2 attempts, got %+v", quarantined)
+		}
+		report = quarantined.Files[0]
+		job, err := serverHandler.DB.GetIngestJob(report.JobID)
+		if err != nil {
+			t.Fatalf("Failed to fetch job: %v", err)
+		}
+		if job.State != database.IngestJobQuarantined || job.Attempts != 1 {
+			t.Errorf("Expected the retried job quarantined after 1 new attempt, got %s after %d", job.State, job.Attempts)
+		}
+	})
+
+	t.Run("Discard", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodDelete, "/api/quarantine/"+report.ID, nil)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("Expected 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+		if quarantined := listQuarantine(); quarantined.Count != 0 {
+			t.Errorf("Expected quarantine to be empty, got %+v", quarantined)
+		}
+		job, err := serverHandler.DB.GetIngestJob(report.JobID)
+		if err != nil {
+			t.Fatalf("Failed to fetch job: %v", err)
+		}
+		if job.State != database.IngestJobFailed {
+			t.Errorf("Expected discarded job to be failed, got %s", job.State)
+		}
+	})
+
+	t.Run("Unknown id", func(t *testing.T) {
+		for method, url := range map[string]string{
+			http.MethodPost:   "/api/quarantine/" + report.ID + "/retry",
+			http.MethodDelete: "/api/quarantine/not-an-id",
+		} {
+			req := httptest.NewRequest(method, url, nil)
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+			if rec.Code != http.StatusNotFound {
+				t.Errorf("%s %s: expected 404, got %d", method, url, rec.Code)
+			}
+		}
+	})
+}
+
+// TestDuplicatePolicies tests each way of handling a file with the same content as a stored document
+func TestDuplicatePolicies(t *testing.T) {
+	e, serverHandler, cleanup := setupTestServer(t)
+	defer cleanup()
+	ingressPath := setupIngestFolders(t, serverHandler)
+	content := []byte("Statement sent twice by the scanner")
+
+	// ingest drops a file in ingress, runs the ingress job and returns the job for it
+	ingest := func(name string) database.IngestJob {
+		t.Helper()
+		filePath := filepath.Join(ingressPath, name)
+		if err := os.WriteFile(filePath, content, 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+		req := httptest.NewRequest(http.MethodPost, "/api/ingest", nil)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		for _, job := range waitForIngestJobs(t, e).Jobs {
+			if job.FilePath == filePath {
+				return job
+			}
+		}
+		t.Fatalf("No job recorded for %s", filePath)
+		return database.IngestJob{}
+	}
+
+	original := ingest("statement.txt")
+	if original.State != database.IngestJobStored {
+		t.Fatalf("Expected original to be stored, got %+v", original)
+	}
+
+	t.Run("Reject", func(t *testing.T) {
+		serverHandler.ServerConfig.DuplicatePolicy = config.DuplicatePolicyReject
+		job := ingest("statement_resent.txt")
+		if job.State != database.IngestJobDuplicate || job.DocumentULID != original.DocumentULID {
+			t.Errorf("Expected duplicate job pointing at %s, got %+v", original.DocumentULID, job)
+		}
+		if _, err := os.Stat(filepath.Join(serverHandler.ServerConfig.DuplicateFolder, "statement_resent.txt")); err != nil {
+			t.Errorf("Expected rejected file in the duplicates folder: %v", err)
+		}
+		if _, err := os.Stat(job.FilePath); !os.IsNotExist(err) {
+			t.Errorf("Expected rejected file to leave ingress, stat returned %v", err)
+		}
+	})
+
+	t.Run("Skip", func(t *testing.T) {
+		serverHandler.ServerConfig.DuplicatePolicy = config.DuplicatePolicySkip
+		job := ingest("statement_skipped.txt")
+		if job.State != database.IngestJobDuplicate {
+			t.Errorf("Expected duplicate job, got %+v", job)
+		}
+		if _, err := os.Stat(job.FilePath); !os.IsNotExist(err) {
+			t.Errorf("Expected skipped file to be deleted, stat returned %v", err)
+		}
+	})
+
+	t.Run("Version", func(t *testing.T) {
+		serverHandler.ServerConfig.DuplicatePolicy = config.DuplicatePolicyVersion
+		for _, version := range []struct{ name, want string }{
+			{"statement_copy.txt", "statement_copy.v2.txt"},
+			{"statement_again.txt", "statement_again.v3.txt"},
+		} {
+			job := ingest(version.name)
+			if job.State != database.IngestJobStored || job.DocumentULID == original.DocumentULID {
+				t.Fatalf("Expected version to be stored as a new document, got %+v", job)
+			}
+			document, err := serverHandler.DB.GetDocumentByULID(job.DocumentULID)
+			if err != nil {
+				t.Fatalf("Failed to fetch version: %v", err)
+			}
+			if document.Name != version.want {
+				t.Errorf("Expected version named %s, got %s", version.want, document.Name)
+			}
+			if _, err := os.Stat(document.Path); err != nil {
+				t.Errorf("Expected version file in document storage: %v", err)
+			}
+		}
+	})
+
+	t.Run("Report", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/api/duplicates", nil)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+		var report duplicatesResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &report); err != nil {
+			t.Fatalf("Failed to parse duplicates response: %v", err)
+		}
+		if report.Count != 4 || report.Policy != config.DuplicatePolicyVersion {
+			t.Fatalf("Expected 4 duplicates under the version policy, got %+v", report)
+		}
+		for action, want := range map[string]int{database.DuplicateRejected: 1, database.DuplicateSkipped: 1, database.DuplicateVersioned: 2} {
+			if report.Counts[action] != want {
+				t.Errorf("Expected %d %s, got %d", want, action, report.Counts[action])
+			}
+		}
+		for _, duplicate := range report.Duplicates {
+			if duplicate.ExistingULID != original.DocumentULID || duplicate.ExistingName != "statement.txt" {
+				t.Errorf("Expected every duplicate to match the original, got %+v", duplicate)
+			}
+		}
+	})
+}
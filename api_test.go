@@ -4,11 +4,16 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
+	"image"
+	"image/png"
 	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
 	"testing"
 	"time"
 
@@ -51,12 +56,36 @@ func setupTestServer(t *testing.T) (*echo.Echo, *engine.ServerHandler, func()) {
 	e.GET("/api/document/:id", serverHandler.GetDocument)
 	e.DELETE("/api/document/*", serverHandler.DeleteFile)
 	e.PATCH("/api/document/move/*", serverHandler.MoveDocuments)
+	e.PATCH("/api/document/:id/rename", serverHandler.RenameDocument)
+	e.GET("/api/document/:id/thumbnail", serverHandler.DocumentThumbnail)
+	e.GET("/api/document/:id/page/:n", serverHandler.DocumentPage)
 	e.POST("/api/document/upload", serverHandler.UploadDocuments)
+	e.OPTIONS("/api/upload", serverHandler.TusOptions)
+	e.POST("/api/upload", serverHandler.TusCreate)
+	e.HEAD("/api/upload/:id", serverHandler.TusHead)
+	e.PATCH("/api/upload/:id", serverHandler.TusPatch)
+	e.DELETE("/api/upload/:id", serverHandler.TusDelete)
+	e.GET("/api/upload/:id", serverHandler.GetUpload)
+	e.GET("/document/view/:id", serverHandler.ViewDocument)
 	e.GET("/api/folder/:folder", serverHandler.GetFolder)
 	e.POST("/api/folder/*", serverHandler.CreateFolder)
+	e.PATCH("/api/folder/*", serverHandler.RenameFolder)
 	e.GET("/api/search", serverHandler.SearchDocuments)
 	e.GET("/api/about", serverHandler.GetAboutInfo)
 	e.POST("/api/ingest", serverHandler.RunIngestNow)
+	e.GET("/api/ingest/jobs", serverHandler.ListIngestJobs)
+	e.GET("/api/ingest/jobs/:id", serverHandler.GetIngestJob)
+	e.POST("/api/ingest/jobs/:id/retry", serverHandler.RetryIngestJob)
+	e.GET("/api/quarantine", serverHandler.ListQuarantine)
+	e.POST("/api/quarantine/:id/retry", serverHandler.RetryQuarantined)
+	e.DELETE("/api/quarantine/:id", serverHandler.DiscardQuarantined)
+	e.GET("/api/duplicates", serverHandler.ListDuplicates)
+	e.GET("/api/integrity", serverHandler.GetIntegrityReport)
+	e.POST("/api/integrity", serverHandler.RunIntegrityCheck)
+	e.GET("/api/trash", serverHandler.ListTrash)
+	e.POST("/api/trash/:id/restore", serverHandler.RestoreTrashed)
+	e.DELETE("/api/trash/:id", serverHandler.PurgeTrashed)
+	e.DELETE("/api/trash", serverHandler.EmptyTrash)
 	e.POST("/api/clean", serverHandler.CleanDatabase)
 
 	// Word cloud routes
@@ -180,7 +209,7 @@ func TestGetDocumentFileSystem(t *testing.T) {
 
 // TestSearchDocuments tests the /search/* endpoint
 func TestSearchDocuments(t *testing.T) {
-	e, _, cleanup := setupTestServer(t)
+	e, serverHandler, cleanup := setupTestServer(t)
 	defer cleanup()
 
 	t.Run("Search - empty query term", func(t *testing.T) {
@@ -216,6 +245,93 @@ func TestSearchDocuments(t *testing.T) {
 		}
 	})
 
+	t.Run("Search - filters", func(t *testing.T) {
+		for _, tt := range []struct {
+			target string
+			want   []int
+		}{
+			{"/api/search?from=yesterday", []int{http.StatusBadRequest}},
+			{"/api/search?term=test&sort=size", []int{http.StatusBadRequest}},
+			{"/api/search?folder=../..", []int{http.StatusBadRequest}},
+			{"/api/search?sort=name", []int{http.StatusNotFound}},
+			{"/api/search?term=%22electricity+bill", []int{http.StatusBadRequest}},
+			{"/api/search?term=invoice+OR", []int{http.StatusBadRequest}},
+			{"/api/search?term=foo%26bar", []int{http.StatusOK, http.StatusNoContent}},
+			{"/api/search?term=invoice&fuzzy=maybe", []int{http.StatusBadRequest}},
+			{"/api/search?document_type=pdf&from=2024-01-01&sort=name", []int{http.StatusOK, http.StatusNoContent, http.StatusNotFound}},
+		} {
+			req := httptest.NewRequest(http.MethodGet, tt.target, nil)
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+			ok := false
+			for _, code := range tt.want {
+				ok = ok || rec.Code == code
+			}
+			if !ok {
+				t.Errorf("%s: expected status %v, got %d: %s", tt.target, tt.want, rec.Code, rec.Body.String())
+			}
+		}
+	})
+
+	t.Run("Search - facets", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/api/search?term=test&facets=true", nil)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusOK && rec.Code != http.StatusNoContent {
+			t.Fatalf("Expected status 200 or 204, got %d: %s", rec.Code, rec.Body.String())
+		}
+		if rec.Code == http.StatusOK {
+			var response struct {
+				FileSystem []map[string]interface{}         `json:"fileSystem"`
+				Facets     map[string][]database.FacetCount `json:"facets"`
+			}
+			if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+				t.Fatalf("Failed to parse search results with facets: %v", err)
+			}
+			total := 0
+			for _, count := range response.Facets["documentType"] {
+				total += count.Count
+			}
+			if total != len(response.FileSystem)-1 { //less the root node
+				t.Errorf("Expected document type facets to count the %d results, got %d", len(response.FileSystem)-1, total)
+			}
+		}
+	})
+
+	t.Run("Search - fuzzy fallback", func(t *testing.T) {
+		saveTestDocument(t, serverHandler, t.TempDir(), "Barclays statement.pdf")
+		search := func(target string) *httptest.ResponseRecorder {
+			req := httptest.NewRequest(http.MethodGet, target, nil)
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+			return rec
+		}
+
+		rec := search("/api/search?term=Barclavs")
+		if rec.Code != http.StatusOK {
+			t.Fatalf("Expected the misspelt name to be found fuzzily, got %d: %s", rec.Code, rec.Body.String())
+		}
+		var results []map[string]interface{}
+		if err := json.Unmarshal(rec.Body.Bytes(), &results); err != nil {
+			t.Fatalf("Failed to parse search results: %v", err)
+		}
+		found := false
+		for _, result := range results {
+			if result["name"] == "Barclays statement.pdf" {
+				similarity, _ := result["similarity"].(float64)
+				found = similarity > 0 && similarity <= 1
+			}
+		}
+		if !found {
+			t.Errorf("Expected Barclays statement.pdf with a similarity, got %s", rec.Body.String())
+		}
+
+		if rec := search("/api/search?term=Barclavs&fuzzy=false"); rec.Code != http.StatusNoContent {
+			t.Errorf("Expected no fallback with fuzzy=false, got %d: %s", rec.Code, rec.Body.String())
+		}
+	})
+
 	t.Run("Search - phrase search", func(t *testing.T) {
 		req := httptest.NewRequest(http.MethodGet, "/api/search?term=test+document", nil)
 		rec := httptest.NewRecorder()
@@ -243,7 +359,7 @@ func TestUploadDocument(t *testing.T) {
 		writer := multipart.NewWriter(body)
 
 		// Add file
-		part, err := writer.CreateFormFile("upload", testFileName)
+		part, err := writer.CreateFormFile("file", testFileName)
 		if err != nil {
 			t.Fatalf("Failed to create form file: %v", err)
 		}
@@ -251,11 +367,6 @@ func TestUploadDocument(t *testing.T) {
 			t.Fatalf("Failed to write file content: %v", err)
 		}
 
-		// Add folder field
-		if err := writer.WriteField("Folder", "test_folder"); err != nil {
-			t.Fatalf("Failed to write folder field: %v", err)
-		}
-
 		writer.Close()
 
 		req := httptest.NewRequest(http.MethodPost, "/api/document/upload", body)
@@ -291,12 +402,174 @@ func TestUploadDocument(t *testing.T) {
 		}
 	})
 
+	t.Run("Upload documents - several files with a result each", func(t *testing.T) {
+		setupIngestFolders(t, serverHandler)
+		body := &bytes.Buffer{}
+		writer := multipart.NewWriter(body)
+		if err := writer.WriteField("path", "../../letters"); err != nil {
+			t.Fatalf("Failed to write path field: %v", err)
+		}
+		writer.Close()
+		req := httptest.NewRequest(http.MethodPost, "/api/document/upload", body)
+		req.Header.Set("Content-Type", writer.FormDataContentType())
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("Expected status 400 for a path outside ingress, got %d: %s", rec.Code, rec.Body.String())
+		}
+
+		body = &bytes.Buffer{}
+		writer = multipart.NewWriter(body)
+		if err := writer.WriteField("path", "letters"); err != nil {
+			t.Fatalf("Failed to write path field: %v", err)
+		}
+		for name, content := range map[string]string{
+			"../../first.txt": "The first uploaded letter",
+			"second.txt":      "The second uploaded letter",
+			"picture.xyz":     "\x00\x01 not a document",
+		} {
+			part, err := writer.CreateFormFile("file", name)
+			if err != nil {
+				t.Fatalf("Failed to create form file: %v", err)
+			}
+			part.Write([]byte(content))
+		}
+		writer.Close()
+		req = httptest.NewRequest(http.MethodPost, "/api/document/upload", body)
+		req.Header.Set("Content-Type", writer.FormDataContentType())
+		rec = httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+		var response struct {
+			Files []struct {
+				FileName string `json:"fileName"`
+				ULID     string `json:"ulid"`
+				Error    string `json:"error"`
+			} `json:"files"`
+			Stored int `json:"stored"`
+			Failed int `json:"failed"`
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+			t.Fatalf("Failed to parse response: %v", err)
+		}
+		if response.Stored != 2 || response.Failed != 1 || len(response.Files) != 3 {
+			t.Fatalf("Expected 2 stored and 1 failed, got %+v", response)
+		}
+		for _, file := range response.Files {
+			switch file.FileName {
+			case "first.txt", "second.txt":
+				if file.ULID == "" || file.Error != "" {
+					t.Errorf("Expected %s to be stored, got %+v", file.FileName, file)
+					continue
+				}
+				stored, err := serverHandler.DB.GetDocumentByULID(file.ULID)
+				if err != nil || stored.Name != file.FileName {
+					t.Errorf("Expected %s in the database, got %v (%v)", file.FileName, stored, err)
+				}
+			case "picture.xyz":
+				if file.Error == "" {
+					t.Errorf("Expected an error for an unsupported file, got %+v", file)
+				}
+			default:
+				t.Errorf("Unexpected file name %q in the results", file.FileName)
+			}
+		}
+	})
+
 	// Cleanup uploaded files
 	if serverHandler.ServerConfig.DocumentPath != "" {
 		os.RemoveAll(filepath.Join(serverHandler.ServerConfig.DocumentPath, "test_folder"))
 	}
 }
 
+// TestResumableUpload tests a tus upload sent in two chunks through the /api/upload endpoints
+func TestResumableUpload(t *testing.T) {
+	e, serverHandler, cleanup := setupTestServer(t)
+	defer cleanup()
+	setupIngestFolders(t, serverHandler)
+	content := "A scanned archive uploaded over a flaky link"
+	tus := func(method string, target string, body string, headers map[string]string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		req.Header.Set("Tus-Resumable", "1.0.0")
+		for key, value := range headers {
+			req.Header.Set(key, value)
+		}
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := tus(http.MethodPost, "/api/upload", "", map[string]string{
+		"Upload-Length":   strconv.Itoa(len(content)),
+		"Upload-Metadata": "filename YXJjaGl2ZS50eHQ=", //archive.txt
+	})
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got %d: %s", rec.Code, rec.Body.String())
+	}
+	location := rec.Header().Get("Location")
+	if !strings.HasPrefix(location, "/api/upload/") || rec.Header().Get("Upload-Expires") == "" {
+		t.Fatalf("Expected a Location and Upload-Expires, got %v", rec.Header())
+	}
+
+	chunk := func(offset int, data string) *httptest.ResponseRecorder {
+		return tus(http.MethodPatch, location, data, map[string]string{
+			"Content-Type":  "application/offset+octet-stream",
+			"Upload-Offset": strconv.Itoa(offset),
+		})
+	}
+	if rec := chunk(0, content[:20]); rec.Code != http.StatusNoContent || rec.Header().Get("Upload-Offset") != "20" {
+		t.Fatalf("Expected the first chunk to take the offset to 20, got %d %q", rec.Code, rec.Header().Get("Upload-Offset"))
+	}
+	rec = tus(http.MethodHead, location, "", nil)
+	if rec.Code != http.StatusOK || rec.Header().Get("Upload-Offset") != "20" || rec.Header().Get("Upload-Length") != strconv.Itoa(len(content)) {
+		t.Errorf("Expected HEAD to resume from 20, got %d %v", rec.Code, rec.Header())
+	}
+	if rec := chunk(10, content[10:]); rec.Code != http.StatusConflict {
+		t.Errorf("Expected status 409 for the wrong offset, got %d", rec.Code)
+	}
+	if rec := chunk(20, content[20:]); rec.Code != http.StatusNoContent {
+		t.Fatalf("Expected the last chunk to be accepted, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	rec = tus(http.MethodGet, location, "", nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var upload struct {
+		Result struct {
+			FileName string `json:"fileName"`
+			ULID     string `json:"ulid"`
+			Error    string `json:"error"`
+		} `json:"result"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &upload); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	if upload.Result.ULID == "" || upload.Result.Error != "" {
+		t.Fatalf("Expected the completed upload to be ingested, got %+v", upload.Result)
+	}
+	document, err := serverHandler.DB.GetDocumentByULID(upload.Result.ULID)
+	if err != nil || document.Name != "archive.txt" {
+		t.Errorf("Expected archive.txt in the database, got %v (%v)", document, err)
+	}
+
+	if rec := tus(http.MethodDelete, location, "", nil); rec.Code != http.StatusNoContent {
+		t.Errorf("Expected status 204 removing the upload, got %d", rec.Code)
+	}
+	if rec := tus(http.MethodHead, location, "", nil); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 for a removed upload, got %d", rec.Code)
+	}
+	req := httptest.NewRequest(http.MethodPost, "/api/upload", nil)
+	req.Header.Set("Upload-Length", "10")
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	if rec.Code != http.StatusPreconditionFailed {
+		t.Errorf("Expected status 412 without Tus-Resumable, got %d", rec.Code)
+	}
+}
+
 // TestGetDocument tests the /document/:id endpoint
 func TestGetDocument(t *testing.T) {
 	e, _, cleanup := setupTestServer(t)
@@ -339,6 +612,23 @@ func TestDeleteDocument(t *testing.T) {
 			t.Errorf("Expected status 200, 404, or 500, got %d", rec.Code)
 		}
 	})
+
+	t.Run("Delete - refuses the document folder and paths outside it", func(t *testing.T) {
+		for _, target := range []string{
+			"/api/document/?path=",
+			"/api/document/?path=..",
+			"/api/document/?path=/etc/passwd",
+			"/api/document/?path=..%252f..%252fetc",
+			"/api/document/?path=..%5C..%5Cetc",
+		} {
+			req := httptest.NewRequest(http.MethodDelete, target, nil)
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+			if rec.Code != http.StatusBadRequest && rec.Code != http.StatusNotFound {
+				t.Errorf("%s: expected status 400 or 404, got %d: %s", target, rec.Code, rec.Body.String())
+			}
+		}
+	})
 }
 
 // TestFolderOperations tests folder creation and retrieval
@@ -347,7 +637,7 @@ func TestFolderOperations(t *testing.T) {
 	defer cleanup()
 
 	t.Run("Create folder", func(t *testing.T) {
-		req := httptest.NewRequest(http.MethodPost, "/api/folder/test_api_folder", nil)
+		req := httptest.NewRequest(http.MethodPost, "/api/folder/?folder=test_api_folder", nil)
 		rec := httptest.NewRecorder()
 		e.ServeHTTP(rec, req)
 
@@ -361,6 +651,25 @@ func TestFolderOperations(t *testing.T) {
 		}
 	})
 
+	t.Run("Create folder - refuses paths outside the document folder", func(t *testing.T) {
+		for _, target := range []string{
+			"/api/folder/?path=..&folder=escaped",
+			"/api/folder/?path=%252e%252e&folder=escaped",
+			"/api/folder/?path=&folder=..",
+			"/api/folder/?path=&folder=a%2Fb",
+		} {
+			req := httptest.NewRequest(http.MethodPost, target, nil)
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+			if rec.Code != http.StatusBadRequest {
+				t.Errorf("%s: expected status 400, got %d: %s", target, rec.Code, rec.Body.String())
+			}
+		}
+		if _, err := os.Stat(filepath.Join(filepath.Dir(serverHandler.ServerConfig.DocumentPath), "escaped")); err == nil {
+			t.Error("Folder was created outside the document folder")
+		}
+	})
+
 	t.Run("Get folder contents - non-existent", func(t *testing.T) {
 		req := httptest.NewRequest(http.MethodGet, "/api/folder/nonexistent_folder", nil)
 		rec := httptest.NewRecorder()
@@ -436,9 +745,29 @@ func TestAdminEndpoints(t *testing.T) {
 	})
 }
 
+// saveTestDocument stores a document with a .yaml companion file in folder
+func saveTestDocument(t *testing.T, serverHandler *engine.ServerHandler, folder string, name string) *database.Document {
+	t.Helper()
+	path := filepath.Join(folder, name)
+	for _, file := range []string{path, path + ".yaml"} {
+		if err := os.WriteFile(file, []byte("contents of "+filepath.Base(file)), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", file, err)
+		}
+	}
+	id, _ := database.CalculateUUID(time.Now())
+	document := &database.Document{
+		Name: name, Path: filepath.ToSlash(path), Folder: filepath.ToSlash(folder), IngressTime: time.Now(),
+		ULID: id, DocumentType: filepath.Ext(name), Hash: id.String(), HashAlgorithm: database.HashAlgorithmSHA256,
+	}
+	if err := serverHandler.DB.SaveDocument(document); err != nil {
+		t.Fatalf("Failed to save %s: %v", name, err)
+	}
+	return document
+}
+
 // TestMoveDocument tests the PATCH /document/move/* endpoint
 func TestMoveDocument(t *testing.T) {
-	e, _, cleanup := setupTestServer(t)
+	e, serverHandler, cleanup := setupTestServer(t)
 	defer cleanup()
 
 	t.Run("Move document - non-existent", func(t *testing.T) {
@@ -458,6 +787,578 @@ func TestMoveDocument(t *testing.T) {
 			t.Log("Move operation returned OK for non-existent document (may be a no-op)")
 		}
 	})
+
+	setupIngestFolders(t, serverHandler)
+	archive := filepath.Join(serverHandler.ServerConfig.DocumentPath, "archive")
+	if err := os.MkdirAll(archive, 0755); err != nil {
+		t.Fatalf("Failed to create archive folder: %v", err)
+	}
+	move := func(folder string, ids ...string) *httptest.ResponseRecorder {
+		target := "/api/document/move/?folder=" + folder
+		for _, id := range ids {
+			target += "&id=" + id
+		}
+		req := httptest.NewRequest(http.MethodPatch, target, nil)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec
+	}
+
+	t.Run("Move document - relocates file and companions", func(t *testing.T) {
+		document := saveTestDocument(t, serverHandler, serverHandler.ServerConfig.NewDocumentFolder, "invoice.pdf")
+		rec := move("archive", document.ULID.String())
+		if rec.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+		newPath := filepath.ToSlash(filepath.Join(archive, "invoice.pdf"))
+		for _, file := range []string{newPath, newPath + ".yaml"} {
+			if _, err := os.Stat(file); err != nil {
+				t.Errorf("Expected %s to exist: %v", file, err)
+			}
+		}
+		if _, err := os.Stat(document.Path); !os.IsNotExist(err) {
+			t.Errorf("Expected %s to be gone, stat returned %v", document.Path, err)
+		}
+		stored, err := serverHandler.DB.GetDocumentByULID(document.ULID.String())
+		if err != nil {
+			t.Fatalf("Failed to fetch moved document: %v", err)
+		}
+		if stored.Path != newPath || stored.Folder != filepath.ToSlash(archive) {
+			t.Errorf("Expected path %s in folder %s, got %s in %s", newPath, archive, stored.Path, stored.Folder)
+		}
+
+		req := httptest.NewRequest(http.MethodGet, "/document/view/"+document.ULID.String(), nil)
+		rec = httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		if rec.Code != http.StatusOK || rec.Body.String() != "contents of invoice.pdf" {
+			t.Errorf("Expected view URL to serve the moved file, got %d: %q", rec.Code, rec.Body.String())
+		}
+	})
+
+	t.Run("Move document - conflict moves nothing", func(t *testing.T) {
+		first := saveTestDocument(t, serverHandler, serverHandler.ServerConfig.NewDocumentFolder, "receipt.pdf")
+		second := saveTestDocument(t, serverHandler, serverHandler.ServerConfig.NewDocumentFolder, "statement.pdf")
+		if err := os.WriteFile(filepath.Join(archive, "statement.pdf"), []byte("already here"), 0644); err != nil {
+			t.Fatalf("Failed to write conflicting file: %v", err)
+		}
+		rec := move("archive", first.ULID.String(), second.ULID.String())
+		if rec.Code != http.StatusConflict {
+			t.Fatalf("Expected status 409, got %d: %s", rec.Code, rec.Body.String())
+		}
+		for _, document := range []*database.Document{first, second} {
+			if _, err := os.Stat(document.Path); err != nil {
+				t.Errorf("Expected %s to be left in place: %v", document.Path, err)
+			}
+			stored, err := serverHandler.DB.GetDocumentByULID(document.ULID.String())
+			if err != nil || stored.Path != document.Path {
+				t.Errorf("Expected %s to keep its path, got %+v (%v)", document.Name, stored, err)
+			}
+		}
+		if _, err := os.Stat(filepath.Join(archive, "receipt.pdf")); !os.IsNotExist(err) {
+			t.Errorf("Expected receipt.pdf to be moved back out of archive, stat returned %v", err)
+		}
+	})
+
+	t.Run("Move document - folder outside document path", func(t *testing.T) {
+		document := saveTestDocument(t, serverHandler, serverHandler.ServerConfig.NewDocumentFolder, "letter.pdf")
+		if rec := move("../..", document.ULID.String()); rec.Code != http.StatusBadRequest {
+			t.Errorf("Expected status 400, got %d: %s", rec.Code, rec.Body.String())
+		}
+	})
+}
+
+// TestRenameDocument tests the PATCH /api/document/:id/rename endpoint
+func TestRenameDocument(t *testing.T) {
+	e, serverHandler, cleanup := setupTestServer(t)
+	defer cleanup()
+	setupIngestFolders(t, serverHandler)
+	folder := serverHandler.ServerConfig.NewDocumentFolder
+	rename := func(id string, name string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPatch, "/api/document/"+id+"/rename?name="+url.QueryEscape(name), nil)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec
+	}
+
+	t.Run("Rename keeps extension and companions", func(t *testing.T) {
+		document := saveTestDocument(t, serverHandler, folder, "scan0001.pdf")
+		rec := rename(document.ULID.String(), "Electricity bill March")
+		if rec.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+		newPath := filepath.ToSlash(filepath.Join(folder, "Electricity bill March.pdf"))
+		for _, file := range []string{newPath, newPath + ".yaml"} {
+			if _, err := os.Stat(file); err != nil {
+				t.Errorf("Expected %s to exist: %v", file, err)
+			}
+		}
+		stored, err := serverHandler.DB.GetDocumentByULID(document.ULID.String())
+		if err != nil {
+			t.Fatalf("Failed to fetch renamed document: %v", err)
+		}
+		if stored.Name != "Electricity bill March.pdf" || stored.Path != newPath {
+			t.Errorf("Expected name and path to be updated, got %s at %s", stored.Name, stored.Path)
+		}
+		results, err := serverHandler.DB.SearchDocuments("electricity")
+		if err != nil || len(results) != 1 {
+			t.Errorf("Expected the new name to be searchable, got %d results (%v)", len(results), err)
+		}
+	})
+
+	t.Run("Rename errors", func(t *testing.T) {
+		document := saveTestDocument(t, serverHandler, folder, "letter.pdf")
+		saveTestDocument(t, serverHandler, folder, "taken.pdf")
+		for _, tt := range []struct {
+			id   string
+			name string
+			want int
+		}{
+			{document.ULID.String(), "../escape", http.StatusBadRequest},
+			{document.ULID.String(), "", http.StatusBadRequest},
+			{document.ULID.String(), "taken", http.StatusConflict},
+			{"01ARZ3NDEKTSV4RRFFQ69G5FAV", "anything", http.StatusNotFound},
+		} {
+			if rec := rename(tt.id, tt.name); rec.Code != tt.want {
+				t.Errorf("Renaming to %q: expected status %d, got %d: %s", tt.name, tt.want, rec.Code, rec.Body.String())
+			}
+		}
+		if _, err := os.Stat(document.Path); err != nil {
+			t.Errorf("Expected %s to be left in place: %v", document.Path, err)
+		}
+	})
+}
+
+// TestRenameFolder tests the PATCH /api/folder/* endpoint
+func TestRenameFolder(t *testing.T) {
+	e, serverHandler, cleanup := setupTestServer(t)
+	defer cleanup()
+	setupIngestFolders(t, serverHandler)
+	root := serverHandler.ServerConfig.DocumentPath
+	nested := filepath.Join(root, "archive", "2024")
+	if err := os.MkdirAll(nested, 0755); err != nil {
+		t.Fatalf("Failed to create folders: %v", err)
+	}
+	top := saveTestDocument(t, serverHandler, filepath.Join(root, "archive"), "top.pdf")
+	inner := saveTestDocument(t, serverHandler, nested, "inner.pdf")
+	other := saveTestDocument(t, serverHandler, serverHandler.ServerConfig.NewDocumentFolder, "other.pdf")
+	deleted := saveTestDocument(t, serverHandler, nested, "deleted.pdf")
+	req := httptest.NewRequest(http.MethodDelete, "/api/document/?id="+deleted.ULID.String()+"&path=archive/2024/deleted.pdf", nil)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Failed to trash %s, got %d: %s", deleted.Name, rec.Code, rec.Body.String())
+	}
+	rename := func(folder string, name string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPatch, "/api/folder/?folder="+url.QueryEscape(folder)+"&name="+url.QueryEscape(name), nil)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec = rename("archive", "old_archive")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var response map[string]interface{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	if response["documents"] != float64(2) {
+		t.Errorf("Expected 2 documents to move with the folder, got %v", response["documents"])
+	}
+	renamed := filepath.ToSlash(filepath.Join(root, "old_archive"))
+	for _, tt := range []struct {
+		document   *database.Document
+		wantFolder string
+	}{
+		{top, renamed},
+		{inner, renamed + "/2024"},
+		{other, other.Folder},
+	} {
+		stored, err := serverHandler.DB.GetDocumentByULID(tt.document.ULID.String())
+		if err != nil {
+			t.Fatalf("Failed to fetch %s: %v", tt.document.Name, err)
+		}
+		wantPath := tt.wantFolder + "/" + tt.document.Name
+		if stored.Folder != tt.wantFolder || stored.Path != wantPath {
+			t.Errorf("%s: expected %s in %s, got %s in %s", tt.document.Name, wantPath, tt.wantFolder, stored.Path, stored.Folder)
+		}
+		if _, err := os.Stat(stored.Path); err != nil {
+			t.Errorf("%s: expected file at %s: %v", tt.document.Name, stored.Path, err)
+		}
+	}
+
+	// A document trashed from the folder is restored into the renamed folder, not a recreated old one
+	req = httptest.NewRequest(http.MethodPost, "/api/trash/"+deleted.ULID.String()+"/restore", nil)
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Failed to restore %s, got %d: %s", deleted.Name, rec.Code, rec.Body.String())
+	}
+	restored, err := serverHandler.DB.GetDocumentByULID(deleted.ULID.String())
+	if err != nil {
+		t.Fatalf("Failed to fetch %s: %v", deleted.Name, err)
+	}
+	if wantPath := renamed + "/2024/" + deleted.Name; restored.Path != wantPath || restored.Folder != renamed+"/2024" {
+		t.Errorf("Expected %s restored to %s, got %s in %s", deleted.Name, wantPath, restored.Path, restored.Folder)
+	}
+	if _, err := os.Stat(filepath.Join(root, "archive")); !os.IsNotExist(err) {
+		t.Errorf("Expected the old folder not to be recreated by a restore, stat returned %v", err)
+	}
+
+	for _, tt := range []struct {
+		folder string
+		name   string
+		want   int
+	}{
+		{"missing", "anything", http.StatusNotFound},
+		{"old_archive", "New", http.StatusConflict},
+		{"old_archive", "a/b", http.StatusBadRequest},
+		{"", "renamed_root", http.StatusBadRequest},
+	} {
+		if rec := rename(tt.folder, tt.name); rec.Code != tt.want {
+			t.Errorf("Renaming %q to %q: expected status %d, got %d: %s", tt.folder, tt.name, tt.want, rec.Code, rec.Body.String())
+		}
+	}
+}
+
+// TestViewDocument tests the GET /document/view/:id endpoint
+func TestViewDocument(t *testing.T) {
+	e, serverHandler, cleanup := setupTestServer(t)
+	defer cleanup()
+	setupIngestFolders(t, serverHandler)
+	document := saveTestDocument(t, serverHandler, serverHandler.ServerConfig.NewDocumentFolder, "statement.pdf")
+	view := func(target string, header string, value string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		if header != "" {
+			req.Header.Set(header, value)
+		}
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec
+	}
+	target := "/document/view/" + document.ULID.String()
+
+	rec := view(target, "", "")
+	if rec.Code != http.StatusOK || rec.Body.String() != "contents of statement.pdf" {
+		t.Fatalf("Expected the document file, got %d: %q", rec.Code, rec.Body.String())
+	}
+	if got := rec.Header().Get("Content-Type"); got != "application/pdf" {
+		t.Errorf("Expected Content-Type application/pdf, got %q", got)
+	}
+	if got := rec.Header().Get("Content-Disposition"); got != `inline; filename=statement.pdf` {
+		t.Errorf("Expected inline Content-Disposition, got %q", got)
+	}
+	etag := rec.Header().Get("ETag")
+	if etag != `"`+document.Hash+`"` {
+		t.Errorf("Expected ETag of the document hash, got %q", etag)
+	}
+	if rec.Header().Get("Last-Modified") == "" {
+		t.Errorf("Expected Last-Modified to be set")
+	}
+
+	if rec := view(target, "If-None-Match", etag); rec.Code != http.StatusNotModified {
+		t.Errorf("Expected status 304 for a matching ETag, got %d", rec.Code)
+	}
+	rec = view(target, "Range", "bytes=0-7")
+	if rec.Code != http.StatusPartialContent || rec.Body.String() != "contents" {
+		t.Errorf("Expected the first 8 bytes, got %d: %q", rec.Code, rec.Body.String())
+	}
+	if got := rec.Header().Get("Content-Range"); got != "bytes 0-7/25" {
+		t.Errorf("Expected Content-Range bytes 0-7/25, got %q", got)
+	}
+	if got := view(target+"?download=1", "", "").Header().Get("Content-Disposition"); got != `attachment; filename=statement.pdf` {
+		t.Errorf("Expected attachment Content-Disposition, got %q", got)
+	}
+
+	renamed, err := serverHandler.DB.GetDocumentByULID(document.ULID.String())
+	if err != nil {
+		t.Fatalf("Failed to fetch document: %v", err)
+	}
+	newPath := filepath.Join(filepath.Dir(renamed.Path), "renamed.pdf")
+	if err := os.Rename(renamed.Path, newPath); err != nil {
+		t.Fatalf("Failed to rename file: %v", err)
+	}
+	if err := serverHandler.DB.RenameDocument(document.ULID.String(), "renamed.pdf", filepath.ToSlash(newPath)); err != nil {
+		t.Fatalf("Failed to rename document: %v", err)
+	}
+	if rec := view(target, "", ""); rec.Code != http.StatusOK || rec.Body.String() != "contents of statement.pdf" {
+		t.Errorf("Expected the renamed file without a restart, got %d: %q", rec.Code, rec.Body.String())
+	}
+
+	if rec := view("/document/view/01ARZ3NDEKTSV4RRFFQ69G5FAV", "", ""); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 for an unknown document, got %d", rec.Code)
+	}
+}
+
+// TestDocumentPreviews tests the GET /api/document/:id/thumbnail and /api/document/:id/page/:n endpoints
+func TestDocumentPreviews(t *testing.T) {
+	e, serverHandler, cleanup := setupTestServer(t)
+	defer cleanup()
+	setupIngestFolders(t, serverHandler)
+	get := func(target string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec
+	}
+	photo := saveTestDocument(t, serverHandler, serverHandler.ServerConfig.NewDocumentFolder, "photo.png")
+	file, err := os.Create(photo.Path)
+	if err != nil {
+		t.Fatalf("Failed to create image: %v", err)
+	}
+	png.Encode(file, image.NewGray(image.Rect(0, 0, 600, 400)))
+	file.Close()
+	notes := saveTestDocument(t, serverHandler, serverHandler.ServerConfig.NewDocumentFolder, "notes.txt")
+
+	rec := get("/api/document/" + photo.ULID.String() + "/thumbnail")
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "image/jpeg" {
+		t.Fatalf("Expected a JPEG thumbnail, got %d %q: %s", rec.Code, rec.Header().Get("Content-Type"), rec.Body.String())
+	}
+	thumbnail := filepath.Join(serverHandler.ServerConfig.ThumbnailFolder, photo.ULID.String(), "thumbnail.jpg")
+	if _, err := os.Stat(thumbnail); err != nil {
+		t.Errorf("Thumbnail not cached: %v", err)
+	}
+	os.RemoveAll(filepath.Dir(thumbnail))
+	if rec := get("/api/document/" + photo.ULID.String() + "/thumbnail"); rec.Code != http.StatusOK {
+		t.Errorf("Expected a missing thumbnail to be rendered again, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	for _, tt := range []struct {
+		target string
+		want   int
+	}{
+		{"/api/document/" + photo.ULID.String() + "/page/1", http.StatusOK},
+		{"/api/document/" + photo.ULID.String() + "/page/2", http.StatusNotFound},
+		{"/api/document/" + photo.ULID.String() + "/page/0", http.StatusBadRequest},
+		{"/api/document/" + photo.ULID.String() + "/page/first", http.StatusBadRequest},
+		{"/api/document/" + notes.ULID.String() + "/thumbnail", http.StatusUnsupportedMediaType},
+		{"/api/document/01ARZ3NDEKTSV4RRFFQ69G5FAV/thumbnail", http.StatusNotFound},
+	} {
+		if rec := get(tt.target); rec.Code != tt.want {
+			t.Errorf("%s: expected status %d, got %d: %s", tt.target, tt.want, rec.Code, rec.Body.String())
+		}
+	}
+}
+
+// TestTrash tests deleting to the trash and the /api/trash endpoints
+func TestTrash(t *testing.T) {
+	e, serverHandler, cleanup := setupTestServer(t)
+	defer cleanup()
+	setupIngestFolders(t, serverHandler)
+	root := serverHandler.ServerConfig.DocumentPath
+	serve := func(method string, target string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, nil)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec
+	}
+	listTrash := func() []database.Document {
+		t.Helper()
+		rec := serve(http.MethodGet, "/api/trash")
+		if rec.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+		var response struct {
+			Documents []database.Document `json:"documents"`
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+			t.Fatalf("Failed to parse response: %v", err)
+		}
+		return response.Documents
+	}
+
+	t.Run("Delete document - moves it to the trash and restore puts it back", func(t *testing.T) {
+		folder := serverHandler.ServerConfig.NewDocumentFolder
+		document := saveTestDocument(t, serverHandler, folder, "electricity.pdf")
+		rec := serve(http.MethodDelete, "/api/document/?id="+document.ULID.String()+"&path=New/electricity.pdf")
+		if rec.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+		for _, file := range []string{document.Path, document.Path + ".yaml"} {
+			if _, err := os.Stat(file); !os.IsNotExist(err) {
+				t.Errorf("Expected %s to be moved to the trash", file)
+			}
+		}
+		trashed, err := serverHandler.DB.GetDocumentByULID(document.ULID.String())
+		if err != nil {
+			t.Fatalf("Failed to fetch trashed document: %v", err)
+		}
+		if trashed.DeletedAt == nil || trashed.DeletedFrom != document.Path {
+			t.Errorf("Expected document to be marked deleted from %s, got %v from %s", document.Path, trashed.DeletedAt, trashed.DeletedFrom)
+		}
+		if _, err := os.Stat(trashed.Path + ".yaml"); err != nil {
+			t.Errorf("Expected companion in the trash: %v", err)
+		}
+		if results, _ := serverHandler.DB.SearchDocuments("electricity"); len(results) != 0 {
+			t.Errorf("Expected trashed document to be left out of search, got %d results", len(results))
+		}
+		if inFolder, _ := serverHandler.DB.GetDocumentsByFolder(document.Folder); len(inFolder) != 0 {
+			t.Errorf("Expected trashed document to be left out of its folder, got %d documents", len(inFolder))
+		}
+		if trash := listTrash(); len(trash) != 1 || trash[0].ULID != document.ULID {
+			t.Errorf("Expected the document in the trash, got %d documents", len(trash))
+		}
+		for _, target := range []string{"/document/view/%s", "/api/document/%s/thumbnail", "/api/document/%s/page/1"} {
+			target = fmt.Sprintf(target, document.ULID.String())
+			if rec := serve(http.MethodGet, target); rec.Code != http.StatusNotFound {
+				t.Errorf("GET %s: expected status 404 for a trashed document, got %d", target, rec.Code)
+			}
+		}
+
+		rec = serve(http.MethodPost, "/api/trash/"+document.ULID.String()+"/restore")
+		if rec.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+		restored, err := serverHandler.DB.GetDocumentByULID(document.ULID.String())
+		if err != nil {
+			t.Fatalf("Failed to fetch restored document: %v", err)
+		}
+		if restored.DeletedAt != nil || restored.Path != document.Path {
+			t.Errorf("Expected document restored to %s, got %s (deleted %v)", document.Path, restored.Path, restored.DeletedAt)
+		}
+		for _, file := range []string{document.Path, document.Path + ".yaml"} {
+			if _, err := os.Stat(file); err != nil {
+				t.Errorf("Expected %s to be restored: %v", file, err)
+			}
+		}
+		if results, _ := serverHandler.DB.SearchDocuments("electricity"); len(results) != 1 {
+			t.Errorf("Expected restored document to be searchable, got %d results", len(results))
+		}
+		if rec := serve(http.MethodPost, "/api/trash/"+document.ULID.String()+"/restore"); rec.Code != http.StatusBadRequest {
+			t.Errorf("Expected status 400 restoring a document not in the trash, got %d", rec.Code)
+		}
+	})
+
+	t.Run("Delete folder - trashes every document and purge removes them", func(t *testing.T) {
+		nested := filepath.Join(root, "receipts", "2024")
+		if err := os.MkdirAll(nested, 0755); err != nil {
+			t.Fatalf("Failed to create folders: %v", err)
+		}
+		top := saveTestDocument(t, serverHandler, filepath.Join(root, "receipts"), "top.pdf")
+		inner := saveTestDocument(t, serverHandler, nested, "inner.pdf")
+		missing := saveTestDocument(t, serverHandler, nested, "missing.pdf")
+		if err := os.Remove(missing.Path); err != nil {
+			t.Fatalf("Failed to remove %s: %v", missing.Path, err)
+		}
+		rec := serve(http.MethodDelete, "/api/document/?path=receipts")
+		if rec.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+		var response map[string]interface{}
+		if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+			t.Fatalf("Failed to parse response: %v", err)
+		}
+		if response["count"] != float64(3) || response["folderRemoved"] != false {
+			t.Errorf("Expected 3 documents trashed and the folder kept for the stray companion, got %v", response)
+		}
+		if documents, _ := response["documents"].([]interface{}); len(documents) == 3 {
+			first, _ := documents[0].(map[string]interface{})
+			if _, ok := first["FullText"]; ok || first["ulid"] == nil || first["name"] == nil || first["trashPath"] == nil {
+				t.Errorf("Expected only the ulid, name and trash path of each document, got %v", first)
+			}
+			for _, listed := range documents {
+				listed, _ := listed.(map[string]interface{})
+				if wantMissing := listed["ulid"] == missing.ULID.String(); listed["fileMissing"] != wantMissing {
+					t.Errorf("Expected fileMissing %v for %v", wantMissing, listed)
+				}
+			}
+		} else {
+			t.Errorf("Expected the 3 trashed documents listed, got %v", response["documents"])
+		}
+		if _, err := os.Stat(missing.Path + ".yaml"); err != nil {
+			t.Errorf("Expected the companion of the missing document to be left in place: %v", err)
+		}
+		if inFolder, _ := serverHandler.DB.GetDocumentsInFolder(filepath.ToSlash(filepath.Join(root, "receipts"))); len(inFolder) != 0 {
+			t.Errorf("Expected no documents left in the deleted folder, got %d", len(inFolder))
+		}
+		if trash := listTrash(); len(trash) != 3 {
+			t.Fatalf("Expected 3 documents in the trash, got %d", len(trash))
+		}
+
+		// The document whose file was already missing comes back as a record only
+		if rec := serve(http.MethodPost, "/api/trash/"+missing.ULID.String()+"/restore"); rec.Code != http.StatusOK {
+			t.Fatalf("Expected status 200 restoring a document with no file, got %d: %s", rec.Code, rec.Body.String())
+		}
+		restored, err := serverHandler.DB.GetDocumentByULID(missing.ULID.String())
+		if err != nil || restored.DeletedAt != nil || restored.Path != missing.Path {
+			t.Errorf("Expected the record restored to %s, got %+v (%v)", missing.Path, restored, err)
+		}
+		if trash := listTrash(); len(trash) != 2 {
+			t.Fatalf("Expected 2 documents left in the trash, got %d", len(trash))
+		}
+
+		trashed, err := serverHandler.DB.GetDocumentByULID(top.ULID.String())
+		if err != nil {
+			t.Fatalf("Failed to fetch trashed document: %v", err)
+		}
+		if rec := serve(http.MethodDelete, "/api/trash/"+top.ULID.String()); rec.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+		if _, err := serverHandler.DB.GetDocumentByULID(top.ULID.String()); err == nil {
+			t.Errorf("Expected purged document to be removed from the database")
+		}
+		if _, err := os.Stat(trashed.Path); !os.IsNotExist(err) {
+			t.Errorf("Expected purged file to be removed from the trash")
+		}
+
+		rec = serve(http.MethodDelete, "/api/trash")
+		if rec.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+		if trash := listTrash(); len(trash) != 0 {
+			t.Errorf("Expected empty trash, got %d documents", len(trash))
+		}
+		if _, err := serverHandler.DB.GetDocumentByULID(inner.ULID.String()); err == nil {
+			t.Errorf("Expected emptying the trash to purge %s", inner.Name)
+		}
+	})
+
+	t.Run("Restore - refused once the same content has been ingested again", func(t *testing.T) {
+		folder := serverHandler.ServerConfig.NewDocumentFolder
+		document := saveTestDocument(t, serverHandler, folder, "gas.pdf")
+		if rec := serve(http.MethodDelete, "/api/document/?id="+document.ULID.String()+"&path=New/gas.pdf"); rec.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+		id, _ := database.CalculateUUID(time.Now())
+		again := &database.Document{
+			Name: "gas again.pdf", Path: filepath.ToSlash(filepath.Join(folder, "gas again.pdf")), Folder: filepath.ToSlash(folder),
+			IngressTime: time.Now(), ULID: id, DocumentType: ".pdf", Hash: document.Hash, HashAlgorithm: database.HashAlgorithmSHA256,
+		}
+		if err := serverHandler.DB.SaveDocument(again); err != nil {
+			t.Fatalf("Failed to save %s: %v", again.Name, err)
+		}
+		rec := serve(http.MethodPost, "/api/trash/"+document.ULID.String()+"/restore")
+		if rec.Code != http.StatusConflict {
+			t.Fatalf("Expected status 409, got %d: %s", rec.Code, rec.Body.String())
+		}
+		if trashed, err := serverHandler.DB.GetDocumentByULID(document.ULID.String()); err != nil || trashed.DeletedAt == nil {
+			t.Errorf("Expected the document to stay in the trash")
+		}
+		if _, err := os.Stat(document.Path); !os.IsNotExist(err) {
+			t.Errorf("Expected %s not to be restored", document.Path)
+		}
+	})
+
+	t.Run("Trash errors", func(t *testing.T) {
+		document := saveTestDocument(t, serverHandler, serverHandler.ServerConfig.NewDocumentFolder, "kept.pdf")
+		for _, tt := range []struct {
+			method string
+			target string
+			want   int
+		}{
+			{http.MethodDelete, "/api/trash/" + document.ULID.String(), http.StatusBadRequest},
+			{http.MethodPost, "/api/trash/01ARZ3NDEKTSV4RRFFQ69G5FAV/restore", http.StatusNotFound},
+			{http.MethodDelete, "/api/trash/01ARZ3NDEKTSV4RRFFQ69G5FAV", http.StatusNotFound},
+		} {
+			if rec := serve(tt.method, tt.target); rec.Code != tt.want {
+				t.Errorf("%s %s: expected status %d, got %d: %s", tt.method, tt.target, tt.want, rec.Code, rec.Body.String())
+			}
+		}
+		if _, err := os.Stat(document.Path); err != nil {
+			t.Errorf("Expected %s to be left in place: %v", document.Path, err)
+		}
+	})
 }
 
 // TestAPIPerformance tests API endpoint performance
@@ -668,6 +1569,10 @@ func TestGetAboutInfo(t *testing.T) {
 			t.Errorf("databaseType should be a string, got %T", aboutInfo["databaseType"])
 		}
 
+		if extensions, ok := aboutInfo["supportedExtensions"].([]interface{}); !ok || len(extensions) == 0 {
+			t.Errorf("supportedExtensions should be a list of extensions, got %v", aboutInfo["supportedExtensions"])
+		}
+
 		// Log the actual values
 		t.Logf("Version: %v", aboutInfo["version"])
 		t.Logf("OCR Configured: %v", aboutInfo["ocrConfigured"])
@@ -13,6 +13,7 @@ func main() {
 	app.Route("/", func() app.Composer { return &webapp.App{} })
 	app.Route("/browse", func() app.Composer { return &webapp.App{} })
 	app.Route("/ingest", func() app.Composer { return &webapp.App{} })
+	app.Route("/quarantine", func() app.Composer { return &webapp.App{} })
 	app.Route("/clean", func() app.Composer { return &webapp.App{} })
 	app.Route("/search", func() app.Composer { return &webapp.App{} })
 	app.Route("/wordcloud", func() app.Composer { return &webapp.App{} })
@@ -7,11 +7,20 @@ import (
 	"net"
 	"os"
 	"path/filepath"
+	"runtime"
 	"strconv"
+	"strings"
 
 	"github.com/joho/godotenv"
 )
 
+// Duplicate policies decide what happens to an ingested file with the same content as a stored document
+const (
+	DuplicatePolicyReject  = "reject"  //move the file to the duplicates folder
+	DuplicatePolicySkip    = "skip"    //delete the file
+	DuplicatePolicyVersion = "version" //store the file as a new version of the existing document
+)
+
 // Logger is global since we will need it everywhere
 var Logger *slog.Logger
 
@@ -34,9 +43,24 @@ type ServerConfig struct {
 	ClientPassword       string
 	PushBulletToken      string `json:"-"`
 	TesseractPath        string
+	OCRDPI               int //resolution PDF pages are rendered at before OCR
 	UseReverseProxy      bool
 	BaseURL              string
 	IngressInterval      int
+	IngressWorkers       int //number of documents ingested in parallel
+	IngressWatch         bool
+	IngressQuietPeriod   int    //seconds a file must stop changing before the watcher ingests it
+	UploadMaxSize        int    //largest file accepted by upload in megabytes, 0 for no limit
+	UploadFolder         string //absolute path resumable uploads are kept in until they are complete
+	UploadExpiry         int    //hours an unfinished resumable upload is kept without any progress
+	QuarantineFolder     string //absolute path files that keep failing ingestion are moved to
+	QuarantineAttempts   int    //failed attempts before a file is quarantined, 0 leaves failed files in ingress
+	DuplicatePolicy      string //one of the DuplicatePolicy constants
+	DuplicateFolder      string //absolute path rejected duplicates are moved to
+	IntegrityInterval    int    //hours between checks of stored documents against their hash, 0 disables the check
+	TrashFolder          string //absolute path deleted documents are kept in until the trash is emptied
+	TrashRetention       int    //days a deleted document stays in the trash, 0 keeps it until purged by hand
+	ThumbnailFolder      string //absolute path thumbnails and page previews are cached in, a folder per document
 	FrontEndConfig
 }
 
@@ -169,6 +193,34 @@ func SetupServer() (ServerConfig, *slog.Logger) {
 	serverConfigLive.IngressInterval = getEnvInt("INGRESS_INTERVAL", 10)
 	serverConfigLive.IngressPreserve = getEnvBool("INGRESS_PRESERVE_STRUCTURE", true)
 	serverConfigLive.IngressDelete = getEnvBool("INGRESS_DELETE", false)
+	serverConfigLive.IngressWorkers = getEnvInt("INGRESS_WORKERS", runtime.NumCPU())
+	if serverConfigLive.IngressWorkers < 1 {
+		logger.Warn("INGRESS_WORKERS must be at least 1, using 1", "value", serverConfigLive.IngressWorkers)
+		serverConfigLive.IngressWorkers = 1
+	}
+	serverConfigLive.IngressWatch = getEnvBool("INGRESS_WATCH", true)
+	serverConfigLive.IngressQuietPeriod = getEnvInt("INGRESS_QUIET_PERIOD", 5)
+	if serverConfigLive.IngressQuietPeriod < 1 {
+		logger.Warn("INGRESS_QUIET_PERIOD must be at least 1 second, using 1", "value", serverConfigLive.IngressQuietPeriod)
+		serverConfigLive.IngressQuietPeriod = 1
+	}
+	serverConfigLive.UploadMaxSize = getEnvInt("UPLOAD_MAX_SIZE_MB", 512)
+	if serverConfigLive.UploadMaxSize < 0 {
+		logger.Warn("UPLOAD_MAX_SIZE_MB must not be negative, upload size not limited", "value", serverConfigLive.UploadMaxSize)
+		serverConfigLive.UploadMaxSize = 0
+	}
+	uploadFolder := filepath.ToSlash(getEnv("UPLOAD_FOLDER", "uploads"))
+	uploadFolderABS, err := filepath.Abs(uploadFolder)
+	if err != nil {
+		logger.Error("Failed creating absolute path for upload folder", "error", err)
+	}
+	serverConfigLive.UploadFolder = uploadFolderABS
+	os.MkdirAll(uploadFolderABS, os.ModePerm)
+	serverConfigLive.UploadExpiry = getEnvInt("UPLOAD_EXPIRY_HOURS", 24)
+	if serverConfigLive.UploadExpiry < 1 {
+		logger.Warn("UPLOAD_EXPIRY_HOURS must be at least 1, using 1", "value", serverConfigLive.UploadExpiry)
+		serverConfigLive.UploadExpiry = 1
+	}
 
 	ingressMoveFolder := filepath.ToSlash(getEnv("INGRESS_MOVE_FOLDER", "done"))
 	ingressMoveFolderABS, err := filepath.Abs(ingressMoveFolder)
@@ -178,6 +230,34 @@ func SetupServer() (ServerConfig, *slog.Logger) {
 	serverConfigLive.IngressMoveFolder = ingressMoveFolderABS
 	os.MkdirAll(ingressMoveFolderABS, os.ModePerm)
 
+	quarantineFolder := filepath.ToSlash(getEnv("INGRESS_QUARANTINE_FOLDER", "quarantine"))
+	quarantineFolderABS, err := filepath.Abs(quarantineFolder)
+	if err != nil {
+		logger.Error("Failed creating absolute path for quarantine folder", "error", err)
+	}
+	serverConfigLive.QuarantineFolder = quarantineFolderABS
+	os.MkdirAll(quarantineFolderABS, os.ModePerm)
+	serverConfigLive.QuarantineAttempts = getEnvInt("INGRESS_QUARANTINE_AFTER", 3)
+	if serverConfigLive.QuarantineAttempts < 0 {
+		logger.Warn("INGRESS_QUARANTINE_AFTER must not be negative, quarantine disabled", "value", serverConfigLive.QuarantineAttempts)
+		serverConfigLive.QuarantineAttempts = 0
+	}
+
+	serverConfigLive.DuplicatePolicy = strings.ToLower(getEnv("INGRESS_DUPLICATE_POLICY", DuplicatePolicyReject))
+	switch serverConfigLive.DuplicatePolicy {
+	case DuplicatePolicyReject, DuplicatePolicySkip, DuplicatePolicyVersion:
+	default:
+		logger.Warn("Unknown INGRESS_DUPLICATE_POLICY, using reject", "value", serverConfigLive.DuplicatePolicy)
+		serverConfigLive.DuplicatePolicy = DuplicatePolicyReject
+	}
+	duplicateFolder := filepath.ToSlash(getEnv("INGRESS_DUPLICATE_FOLDER", "duplicates"))
+	duplicateFolderABS, err := filepath.Abs(duplicateFolder)
+	if err != nil {
+		logger.Error("Failed creating absolute path for duplicate folder", "error", err)
+	}
+	serverConfigLive.DuplicateFolder = duplicateFolderABS
+	os.MkdirAll(duplicateFolderABS, os.ModePerm)
+
 	fmt.Println("Ingress Interval: ", serverConfigLive.IngressInterval)
 	fmt.Println("\n========================================")
 	fmt.Println("   goEDMS - Document Management System")
@@ -201,6 +281,33 @@ func SetupServer() (ServerConfig, *slog.Logger) {
 	serverConfigLive.NewDocumentFolderRel = newDocumentPath
 	serverConfigLive.NewDocumentFolder = filepath.Join(documentPathAbs, newDocumentPath)
 
+	trashFolder := filepath.ToSlash(getEnv("TRASH_FOLDER", "trash"))
+	trashFolderABS, err := filepath.Abs(trashFolder)
+	if err != nil {
+		logger.Error("Failed creating absolute path for trash folder", "error", err)
+	}
+	serverConfigLive.TrashFolder = trashFolderABS
+	os.MkdirAll(trashFolderABS, os.ModePerm)
+	serverConfigLive.TrashRetention = getEnvInt("TRASH_RETENTION_DAYS", 30)
+	if serverConfigLive.TrashRetention < 0 {
+		logger.Warn("TRASH_RETENTION_DAYS must not be negative, trash kept until emptied", "value", serverConfigLive.TrashRetention)
+		serverConfigLive.TrashRetention = 0
+	}
+
+	thumbnailFolder := filepath.ToSlash(getEnv("THUMBNAIL_FOLDER", "thumbnails"))
+	thumbnailFolderABS, err := filepath.Abs(thumbnailFolder)
+	if err != nil {
+		logger.Error("Failed creating absolute path for thumbnail folder", "error", err)
+	}
+	serverConfigLive.ThumbnailFolder = thumbnailFolderABS
+	os.MkdirAll(thumbnailFolderABS, os.ModePerm)
+
+	serverConfigLive.IntegrityInterval = getEnvInt("INTEGRITY_INTERVAL", 24)
+	if serverConfigLive.IntegrityInterval < 0 {
+		logger.Warn("INTEGRITY_INTERVAL must not be negative, integrity check disabled", "value", serverConfigLive.IntegrityInterval)
+		serverConfigLive.IntegrityInterval = 0
+	}
+
 	// OCR configuration
 	tesseractPathConfig := getEnv("TESSERACT_PATH", "/usr/bin/tesseract")
 	logger.Info("Checking tesseract executable path...")
@@ -213,6 +320,12 @@ func SetupServer() (ServerConfig, *slog.Logger) {
 		serverConfigLive.TesseractPath = ""
 	}
 
+	serverConfigLive.OCRDPI = getEnvInt("OCR_DPI", 300)
+	if serverConfigLive.OCRDPI < 72 || serverConfigLive.OCRDPI > 1200 {
+		logger.Warn("OCR_DPI out of range (72-1200), using default", "value", serverConfigLive.OCRDPI, "default", 300)
+		serverConfigLive.OCRDPI = 300
+	}
+
 	// Authentication configuration
 	serverConfigLive.WebUIPass = getEnvBool("WEB_UI_AUTH", false)
 	serverConfigLive.ClientUsername = getEnv("WEB_UI_USER", "admin")
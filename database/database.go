@@ -2,15 +2,18 @@ package database
 
 import (
 	"crypto/md5"
+	"crypto/sha256"
 	"database/sql"
 	"errors"
 	"fmt"
+	"hash"
 	"io"
 	"log/slog"
 	"math/rand"
 	"net/http"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
 
 	config "github.com/drummonds/goEDMS/config"
@@ -19,18 +22,31 @@ import (
 
 // Document is all of the document information stored in the database
 type Document struct {
-	StormID      int // ID field (kept as StormID for backward compatibility)
-	Name         string
-	Path         string // full path to the file
-	IngressTime  time.Time
-	Folder       string
-	Hash         string
-	ULID         ulid.ULID // Have a smaller (than hash) id that can be used in URL's, hopefully speed things up
-	DocumentType string    // type of document (pdf, txt, etc)
-	FullText     string
-	URL          string
+	StormID       int // ID field (kept as StormID for backward compatibility)
+	Name          string
+	Path          string // full path to the file
+	IngressTime   time.Time
+	Folder        string
+	Hash          string
+	HashAlgorithm string    // algorithm Hash was made with, sha256 or md5 for documents stored before the switch
+	ULID          ulid.ULID // Have a smaller (than hash) id that can be used in URL's, hopefully speed things up
+	DocumentType  string    // type of document (pdf, txt, etc)
+	FullText      string
+	URL           string
+	DeletedAt     *time.Time       // set while the document is in the trash
+	DeletedFrom   string           // path the document is restored to from the trash
+	Size          int64            // size of the file in bytes, 0 for documents stored before sizes were recorded until it is read
+	PageCount     int              // 0 when the format has no notion of pages
+	Metadata      DocumentMetadata // properties such as title and author found when the text was extracted
 }
 
+// Hash algorithms a document hash can be made with.  New documents use SHA-256, MD5 is only kept for documents
+// stored before the switch until the rehash job replaces their hash.
+const (
+	HashAlgorithmSHA256 = "sha256"
+	HashAlgorithmMD5    = "md5"
+)
+
 // Logger is global since we will need it everywhere
 var Logger *slog.Logger
 
@@ -49,15 +65,57 @@ type DBInterface interface {
 	DeleteDocument(ulid string) error
 	UpdateDocumentURL(ulid string, url string) error
 	UpdateDocumentFolder(ulid string, folder string) error
+	UpdateDocumentLocation(ulid string, path string, folder string) error
+	RenameDocument(ulid string, name string, path string) error
+	RenameFolder(oldFolder string, newFolder string) (map[string]string, error)
+	// Trash methods
+	TrashDocument(ulid string, trashPath string) error
+	TrashDocuments(trashPaths map[string]string) error
+	RestoreDocument(ulid string, path string) error
+	GetTrashedDocuments() ([]Document, error)
+	GetDocumentsInFolder(folder string) ([]Document, error)
 	SaveConfig(config *config.ServerConfig) error
 	GetConfig() (*config.ServerConfig, error)
-	SearchDocuments(searchTerm string) ([]Document, error)
+	SearchDocuments(searchTerm string) ([]SearchResult, error)
+	SearchDocumentsFiltered(filter SearchFilter) ([]SearchResult, error)
+	SearchDocumentsWithFacets(filter SearchFilter) ([]SearchResult, SearchFacets, error)
 	ReindexSearchDocuments() (int, error)
 	// Word cloud methods
 	GetTopWords(limit int) ([]WordFrequency, error)
 	GetWordCloudMetadata() (*WordCloudMetadata, error)
 	RecalculateAllWordFrequencies() error
 	UpdateWordFrequencies(docID string) error
+	// Per page text methods
+	SaveDocumentPages(ulid string, pages []string) error
+	GetDocumentPages(ulid string) ([]DocumentPage, error)
+	// Ingest job methods
+	QueueIngestJob(filePath string, source string) (*IngestJob, error)
+	UpdateIngestJob(job *IngestJob) error
+	GetIngestJob(id int) (*IngestJob, error)
+	ListIngestJobs(state string, limit int) ([]IngestJob, error)
+	CountIngestJobs() (map[string]int, error)
+	FailInterruptedIngestJobs() (int, error)
+	// Hash methods
+	GetDocumentsByHashAlgorithm(algorithm string, afterID int, limit int) ([]Document, error)
+	UpdateDocumentHash(ulid string, hash string, algorithm string) error
+	// Size methods
+	GetDocumentsWithoutSize(afterID int, limit int) ([]Document, error)
+	UpdateDocumentSize(ulid string, size int64) error
+	UpdateDocumentProperties(ulid string, pageCount int, metadata DocumentMetadata) error
+	// Integrity methods
+	GetDocumentsAfter(afterID int, limit int) ([]Document, error)
+	StartIntegrityRun() (*IntegrityRun, error)
+	FinishIntegrityRun(run *IntegrityRun) error
+	RecordIntegrityIssue(issue *IntegrityIssue) error
+	GetIntegrityRun(id int) (*IntegrityRun, error)
+	ListIntegrityRuns(limit int) ([]IntegrityRun, error)
+	ListIntegrityIssues(runID int) ([]IntegrityIssue, error)
+	FailInterruptedIntegrityRuns() (int, error)
+	// Duplicate methods
+	RecordDuplicate(record *DuplicateRecord) error
+	ListDuplicates(limit int) ([]DuplicateRecord, error)
+	CountDuplicates() (map[string]int, error)
+	NextDocumentVersion(ulid string) (int, error)
 }
 
 // SetupDatabase initializes the database based on configuration
@@ -115,53 +173,93 @@ func WriteConfigToDB(serverConfig config.ServerConfig, db DBInterface) {
 	}
 }
 
-// AddNewDocument adds a new document to the database
+// AddNewDocument adds a new document to the database, returning a *DuplicateError if a document with the same
+// content is already stored
 func AddNewDocument(filePath string, fullText string, db DBInterface) (*Document, error) {
-	serverConfig, err := FetchConfigFromDB(db)
+	hashes, err := CalculateFileHashes(filePath)
 	if err != nil {
-		Logger.Error("Unable to fetch config to add new document", "filePath", filePath, "error", err)
+		return nil, err
 	}
-	var newDocument Document
-	fileHash, err := calculateHash(filePath)
+	if duplicate := checkDuplicateDocument(hashes, filePath, db); duplicate != nil {
+		return nil, duplicate
+	}
+	return saveNewDocument(filePath, filepath.Base(filePath), hashes.SHA256, fullText, db)
+}
+
+// CheckDuplicateDocument hashes the file and returns a DuplicateError if a document with the same content is already
+// stored, so duplicates can be caught before any text is extracted
+func CheckDuplicateDocument(filePath string, db DBInterface) error {
+	hashes, err := CalculateFileHashes(filePath)
 	if err != nil {
-		return nil, err
+		return err
 	}
-	duplicate := checkDuplicateDocument(fileHash, filePath, db)
-	if duplicate {
-		err = errors.New("Duplicate document found on import (Hash collision) ! " + filePath)
-		Logger.Error("Duplicate document detected", "error", err)
-		return nil, err // TODO return actual error
+	if duplicate := checkDuplicateDocument(hashes, filePath, db); duplicate != nil {
+		return duplicate
 	}
+	return nil
+}
+
+// checkDuplicateDocument returns the DuplicateError for a file whose hashes match a stored document, or nil
+func checkDuplicateDocument(hashes FileHashes, filePath string, db DBInterface) *DuplicateError {
+	existing := findDuplicateDocument(hashes, filePath, db)
+	if existing == nil {
+		return nil
+	}
+	duplicate := &DuplicateError{FilePath: filePath, Hash: hashes.SHA256, Existing: existing}
+	Logger.Warn("Duplicate document detected", "filePath", filePath, "error", duplicate)
+	return duplicate
+}
+
+// AddDocumentVersion stores a duplicate as a new document named <name>.v<version><ext> alongside the existing one
+func AddDocumentVersion(duplicate *DuplicateError, version int, fullText string, db DBInterface) (*Document, error) {
+	fileName := filepath.Base(duplicate.FilePath)
+	ext := filepath.Ext(fileName)
+	versionName := fmt.Sprintf("%s.v%d%s", strings.TrimSuffix(fileName, ext), version, ext)
+	return saveNewDocument(duplicate.FilePath, versionName, duplicate.Hash, fullText, db)
+}
+
+// saveNewDocument writes a new document for the file in ingress, name is the file name it will have in the document
+// folder and fileHash its SHA-256 hash
+func saveNewDocument(filePath string, name string, fileHash string, fullText string, db DBInterface) (*Document, error) {
+	serverConfig, err := FetchConfigFromDB(db)
+	if err != nil {
+		Logger.Error("Unable to fetch config to add new document", "filePath", filePath, "error", err)
+	}
+	var newDocument Document
 	newTime := time.Now()
 	newULID, err := CalculateUUID(newTime)
 	if err != nil {
 		Logger.Error("Cannot generate ULID", "filePath", filePath, "error", err)
 	}
 
-	newDocument.Name = filepath.Base(filePath)
+	newDocument.Name = name
 	if serverConfig.IngressPreserve { //if we are preserving the entire path of the document generate the full path
 		basePath := serverConfig.IngressPath
 		newFileNameRoot := serverConfig.DocumentPath
-		relativePath, err := filepath.Rel(basePath, filePath)
+		relativePath, err := filepath.Rel(basePath, filepath.Dir(filePath))
 		if err != nil {
 			return nil, err
 		}
-		newFilePath := filepath.Join(newFileNameRoot, relativePath)
+		newFilePath := filepath.Join(newFileNameRoot, relativePath, name)
 		fmt.Println("NEW PATH: ", newFilePath)
 		fmt.Println("New FOLDER", filepath.Dir(newFilePath))
 		newDocument.Path = filepath.ToSlash(newFilePath)
 		newDocument.Folder = filepath.Dir(newFilePath)
 	} else {
-		documentPath := filepath.ToSlash(serverConfig.DocumentPath + "/" + serverConfig.NewDocumentFolderRel + "/" + filepath.Base(filePath))
+		documentPath := filepath.ToSlash(serverConfig.DocumentPath + "/" + serverConfig.NewDocumentFolderRel + "/" + name)
 		newDocument.Path = documentPath
 		documentFolder := filepath.ToSlash(serverConfig.DocumentPath + "/" + serverConfig.NewDocumentFolderRel)
 		newDocument.Folder = documentFolder
 	}
 	newDocument.Hash = fileHash
+	newDocument.HashAlgorithm = HashAlgorithmSHA256
 	newDocument.IngressTime = newTime
 	newDocument.ULID = newULID
-	newDocument.DocumentType = filepath.Ext(filePath)
+	newDocument.DocumentType = strings.ToLower(filepath.Ext(filePath))
 	newDocument.FullText = fullText
+	if info, err := os.Stat(filePath); err == nil {
+		newDocument.Size = info.Size()
+	}
 	Logger.Debug("Adding document to database", "fullText", newDocument.FullText)
 	// PostgreSQL full-text search will be automatically indexed via trigger
 	err = db.SaveDocument(&newDocument) // Writing it in document bucket
@@ -282,31 +380,81 @@ func DeleteDocument(docULIDSt string, db DBInterface) error {
 }
 
 
-func checkDuplicateDocument(fileHash string, fileName string, db DBInterface) bool { // TODO: Check for duplicates before you do a shit ton of processing, why wasn't this obvious?
-	document, err := db.GetDocumentByHash(fileHash)
-	if err != nil || document == nil {
-		Logger.Info("No record found, assume no duplicate hash", "error", err)
-		return false
+// findDuplicateDocument returns the stored document with the same content, or nil if there is none.  Documents
+// stored before the switch to SHA-256 may still only have an MD5 hash so that is checked too.
+func findDuplicateDocument(hashes FileHashes, fileName string, db DBInterface) *Document {
+	for _, hash := range []string{hashes.SHA256, hashes.MD5} {
+		document, err := db.GetDocumentByHash(hash)
+		if err != nil {
+			Logger.Error("Unable to check for duplicate hash", "fileName", fileName, "error", err)
+			continue
+		}
+		if document != nil {
+			Logger.Info("Duplicate document found on import (Hash collision)", "fileName", fileName, "existingDocument", document.Name)
+			return document
+		}
 	}
-	Logger.Info("Duplicate document found on import (Hash collision)", "fileName", fileName, "existingDocument", document.Name)
-	return true
+	Logger.Info("No record found, assume no duplicate hash", "fileName", fileName)
+	return nil
+}
+
+// FileHashes holds the hash of a file under each algorithm a document hash can be made with
+type FileHashes struct {
+	SHA256 string
+	MD5    string
 }
 
-// calculate the hash of the incoming file
-func calculateHash(fileName string) (string, error) {
-	var fileHash string
+// Get returns the hash for the named algorithm, or an empty string for an unknown algorithm
+func (hashes FileHashes) Get(algorithm string) string {
+	switch algorithm {
+	case HashAlgorithmSHA256:
+		return hashes.SHA256
+	case HashAlgorithmMD5:
+		return hashes.MD5
+	}
+	return ""
+}
+
+// CalculateFileHashes reads the file once and returns its SHA-256 and MD5 hashes
+func CalculateFileHashes(fileName string) (FileHashes, error) {
+	var hashes FileHashes
 	file, err := os.Open(fileName)
 	if err != nil {
-		return fileHash, err
+		return hashes, err
 	}
 	defer file.Close()
-	hash := md5.New()
-	_, err = io.Copy(hash, file)
+	sha256Hash := sha256.New()
+	md5Hash := md5.New()
+	_, err = io.Copy(io.MultiWriter(sha256Hash, md5Hash), file)
+	if err != nil {
+		return hashes, err
+	}
+	hashes.SHA256 = fmt.Sprintf("%x", sha256Hash.Sum(nil))
+	hashes.MD5 = fmt.Sprintf("%x", md5Hash.Sum(nil))
+	return hashes, nil
+}
+
+// CalculateFileHash returns the hash of the file under one algorithm, for checking a stored document without also
+// paying for the hash it was not made with
+func CalculateFileHash(fileName string, algorithm string) (string, error) {
+	var hasher hash.Hash
+	switch algorithm {
+	case HashAlgorithmSHA256:
+		hasher = sha256.New()
+	case HashAlgorithmMD5:
+		hasher = md5.New()
+	default:
+		return "", fmt.Errorf("unknown hash algorithm %q", algorithm)
+	}
+	file, err := os.Open(fileName)
 	if err != nil {
-		return fileHash, err
+		return "", err
+	}
+	defer file.Close()
+	if _, err := io.Copy(hasher, file); err != nil {
+		return "", err
 	}
-	fileHash = fmt.Sprintf("%x", hash.Sum(nil))
-	return fileHash, nil
+	return fmt.Sprintf("%x", hasher.Sum(nil)), nil
 }
 
 // CalculateUUID for the incoming file
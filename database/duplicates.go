@@ -0,0 +1,128 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// Actions recorded for a duplicate, one per duplicate policy
+const (
+	DuplicateRejected  = "rejected"
+	DuplicateSkipped   = "skipped"
+	DuplicateVersioned = "versioned"
+)
+
+// DuplicateError is returned by AddNewDocument when a document with the same content is already stored
+type DuplicateError struct {
+	FilePath string
+	Hash     string
+	Existing *Document
+}
+
+func (e *DuplicateError) Error() string {
+	return fmt.Sprintf("duplicate of existing document %s (%s)", e.Existing.Name, e.Existing.ULID)
+}
+
+// DuplicateRecord is one ingested file whose content matched a stored document
+type DuplicateRecord struct {
+	ID           int       `json:"id"`
+	Hash         string    `json:"hash"`
+	FilePath     string    `json:"filePath"` // where the duplicate was found
+	ExistingULID string    `json:"existingULID"`
+	ExistingName string    `json:"existingName"` // filled in when listing
+	ExistingPath string    `json:"existingPath"` // filled in when listing
+	Action       string    `json:"action"`
+	MovedTo      string    `json:"movedTo"`     // set when rejected
+	VersionULID  string    `json:"versionULID"` // set when versioned, cleared if the version is deleted
+	Version      int       `json:"version"`     // set when versioned, the existing document is version 1
+	DetectedAt   time.Time `json:"detectedAt"`
+}
+
+// RecordDuplicate saves a duplicate and the action taken for it
+func (p *PostgresDB) RecordDuplicate(record *DuplicateRecord) error {
+	query := `
+		INSERT INTO duplicates (hash, file_path, existing_ulid, action, moved_to, version_ulid, version)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id, detected_at
+	`
+	var versionULID sql.NullString
+	var version sql.NullInt64
+	if record.VersionULID != "" {
+		versionULID = sql.NullString{String: record.VersionULID, Valid: true}
+		version = sql.NullInt64{Int64: int64(record.Version), Valid: true}
+	}
+	err := p.db.QueryRow(query, record.Hash, record.FilePath, record.ExistingULID, record.Action,
+		record.MovedTo, versionULID, version).Scan(&record.ID, &record.DetectedAt)
+	if err != nil {
+		return fmt.Errorf("failed to record duplicate: %w", err)
+	}
+	return nil
+}
+
+// ListDuplicates returns the most recent duplicates with the name and path of the document they matched
+func (p *PostgresDB) ListDuplicates(limit int) ([]DuplicateRecord, error) {
+	query := `
+		SELECT dup.id, dup.hash, dup.file_path, dup.existing_ulid, d.name, d.path, dup.action,
+			dup.moved_to, dup.version_ulid, dup.version, dup.detected_at
+		FROM duplicates dup
+		JOIN documents d ON d.ulid = dup.existing_ulid
+		ORDER BY dup.detected_at DESC, dup.id DESC
+		LIMIT $1
+	`
+	rows, err := p.db.Query(query, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query duplicates: %w", err)
+	}
+	defer rows.Close()
+
+	records := make([]DuplicateRecord, 0)
+	for rows.Next() {
+		var record DuplicateRecord
+		var versionULID sql.NullString
+		var version sql.NullInt64
+		err := rows.Scan(&record.ID, &record.Hash, &record.FilePath, &record.ExistingULID, &record.ExistingName,
+			&record.ExistingPath, &record.Action, &record.MovedTo, &versionULID, &version, &record.DetectedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan duplicate: %w", err)
+		}
+		record.VersionULID = versionULID.String
+		record.Version = int(version.Int64)
+		records = append(records, record)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+	return records, nil
+}
+
+// CountDuplicates returns the number of duplicates recorded for each action
+func (p *PostgresDB) CountDuplicates() (map[string]int, error) {
+	rows, err := p.db.Query(`SELECT action, COUNT(*) FROM duplicates GROUP BY action`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count duplicates: %w", err)
+	}
+	defer rows.Close()
+
+	counts := map[string]int{DuplicateRejected: 0, DuplicateSkipped: 0, DuplicateVersioned: 0}
+	for rows.Next() {
+		var action string
+		var count int
+		if err := rows.Scan(&action, &count); err != nil {
+			return nil, fmt.Errorf("failed to scan duplicate count: %w", err)
+		}
+		counts[action] = count
+	}
+	return counts, rows.Err()
+}
+
+// NextDocumentVersion returns the version number the next copy of a document will be stored as
+func (p *PostgresDB) NextDocumentVersion(ulid string) (int, error) {
+	var latest int
+	err := p.db.QueryRow(`SELECT COALESCE(MAX(version), 1) FROM duplicates WHERE existing_ulid = $1 AND action = $2`,
+		ulid, DuplicateVersioned).Scan(&latest)
+	if err != nil {
+		return 0, fmt.Errorf("failed to find latest version of %s: %w", ulid, err)
+	}
+	return latest + 1, nil
+}
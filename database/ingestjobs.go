@@ -0,0 +1,204 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Ingest job states, a job moves queued -> extracting (-> ocr) -> stored or failed, a file that keeps failing
+// is moved to the quarantine folder and a file already stored ends as a duplicate
+const (
+	IngestJobQueued      = "queued"
+	IngestJobExtracting  = "extracting"
+	IngestJobOCR         = "ocr"
+	IngestJobStored      = "stored"
+	IngestJobFailed      = "failed"
+	IngestJobQuarantined = "quarantined"
+	IngestJobDuplicate   = "duplicate"
+)
+
+// IngestAttempt records how one attempt at ingesting a file ended
+type IngestAttempt struct {
+	Attempt    int       `json:"attempt"`
+	StartedAt  time.Time `json:"startedAt"`
+	FinishedAt time.Time `json:"finishedAt"`
+	Error      string    `json:"error"` // empty if the attempt succeeded
+}
+
+// IngestJob is the progress of one file through ingestion
+type IngestJob struct {
+	ID           int             `json:"id"`
+	FilePath     string          `json:"filePath"`
+	Source       string          `json:"source"` // ingress or upload
+	State        string          `json:"state"`
+	Error        string          `json:"error"`
+	Attempts     int             `json:"attempts"`
+	DocumentULID string          `json:"documentULID"` // set once stored, or the existing document for a duplicate
+	QueuedAt     time.Time       `json:"queuedAt"`
+	StartedAt    *time.Time      `json:"startedAt"`
+	FinishedAt   *time.Time      `json:"finishedAt"`
+	UpdatedAt    time.Time       `json:"updatedAt"`
+	History      []IngestAttempt `json:"history"` // every finished attempt, oldest first
+}
+
+const ingestJobColumns = `id, file_path, source, state, error, attempts, document_ulid, queued_at, started_at, finished_at, updated_at, history`
+
+// QueueIngestJob records a file as waiting for ingestion.  If the file already has an unfinished or failed job that
+// job is queued again, so a file that keeps failing builds up attempts rather than a new row every scan.  A
+// quarantined or duplicate job is a file put back after it was dealt with, so its attempts and error start again.
+func (p *PostgresDB) QueueIngestJob(filePath string, source string) (*IngestJob, error) {
+	query := `
+		UPDATE ingest_jobs SET state = $1, source = $2, queued_at = CURRENT_TIMESTAMP,
+			attempts = CASE WHEN state IN ($5, $6) THEN 0 ELSE attempts END,
+			error = CASE WHEN state IN ($5, $6) THEN '' ELSE error END
+		WHERE id = (
+			SELECT id FROM ingest_jobs
+			WHERE file_path = $3 AND state <> $4
+			ORDER BY id DESC LIMIT 1
+		)
+		RETURNING ` + ingestJobColumns
+	job, err := scanIngestJob(p.db.QueryRow(query, IngestJobQueued, source, filePath, IngestJobStored,
+		IngestJobQuarantined, IngestJobDuplicate))
+	if err == nil {
+		return job, nil
+	}
+	if err != sql.ErrNoRows {
+		return nil, fmt.Errorf("failed to requeue ingest job: %w", err)
+	}
+
+	query = `
+		INSERT INTO ingest_jobs (file_path, source, state)
+		VALUES ($1, $2, $3)
+		RETURNING ` + ingestJobColumns
+	job, err = scanIngestJob(p.db.QueryRow(query, filePath, source, IngestJobQueued))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create ingest job: %w", err)
+	}
+	return job, nil
+}
+
+// UpdateIngestJob saves the state, error, attempts, document, timings and history of a job
+func (p *PostgresDB) UpdateIngestJob(job *IngestJob) error {
+	query := `
+		UPDATE ingest_jobs SET state = $1, error = $2, attempts = $3, document_ulid = $4,
+			started_at = $5, finished_at = $6, history = $7
+		WHERE id = $8
+		RETURNING updated_at
+	`
+	var documentULID sql.NullString
+	if job.DocumentULID != "" {
+		documentULID = sql.NullString{String: job.DocumentULID, Valid: true}
+	}
+	history := job.History
+	if history == nil {
+		history = []IngestAttempt{}
+	}
+	historyJSON, err := json.Marshal(history)
+	if err != nil {
+		return fmt.Errorf("failed to encode ingest job %d history: %w", job.ID, err)
+	}
+	err = p.db.QueryRow(query, job.State, job.Error, job.Attempts, documentULID,
+		job.StartedAt, job.FinishedAt, string(historyJSON), job.ID).Scan(&job.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to update ingest job %d: %w", job.ID, err)
+	}
+	return nil
+}
+
+// GetIngestJob retrieves a job by ID, returning sql.ErrNoRows if there is none
+func (p *PostgresDB) GetIngestJob(id int) (*IngestJob, error) {
+	query := `SELECT ` + ingestJobColumns + ` FROM ingest_jobs WHERE id = $1`
+	return scanIngestJob(p.db.QueryRow(query, id))
+}
+
+// ListIngestJobs returns the most recently queued jobs, optionally only those in one state
+func (p *PostgresDB) ListIngestJobs(state string, limit int) ([]IngestJob, error) {
+	query := `SELECT ` + ingestJobColumns + ` FROM ingest_jobs
+		WHERE ($1 = '' OR state = $1)
+		ORDER BY queued_at DESC, id DESC
+		LIMIT $2`
+	rows, err := p.db.Query(query, state, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query ingest jobs: %w", err)
+	}
+	defer rows.Close()
+
+	jobs := make([]IngestJob, 0)
+	for rows.Next() {
+		job, err := scanIngestJob(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan ingest job: %w", err)
+		}
+		jobs = append(jobs, *job)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+	return jobs, nil
+}
+
+// CountIngestJobs returns the number of jobs in each state
+func (p *PostgresDB) CountIngestJobs() (map[string]int, error) {
+	rows, err := p.db.Query(`SELECT state, COUNT(*) FROM ingest_jobs GROUP BY state`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count ingest jobs: %w", err)
+	}
+	defer rows.Close()
+
+	counts := map[string]int{
+		IngestJobQueued: 0, IngestJobExtracting: 0, IngestJobOCR: 0, IngestJobStored: 0, IngestJobFailed: 0,
+		IngestJobQuarantined: 0, IngestJobDuplicate: 0,
+	}
+	for rows.Next() {
+		var state string
+		var count int
+		if err := rows.Scan(&state, &count); err != nil {
+			return nil, fmt.Errorf("failed to scan ingest job count: %w", err)
+		}
+		counts[state] = count
+	}
+	return counts, rows.Err()
+}
+
+// FailInterruptedIngestJobs marks jobs left mid way by a server restart as failed so they can be retried
+func (p *PostgresDB) FailInterruptedIngestJobs() (int, error) {
+	result, err := p.db.Exec(`
+		UPDATE ingest_jobs SET state = $1, error = 'interrupted by server restart', finished_at = CURRENT_TIMESTAMP
+		WHERE state IN ($2, $3, $4)
+	`, IngestJobFailed, IngestJobQueued, IngestJobExtracting, IngestJobOCR)
+	if err != nil {
+		return 0, fmt.Errorf("failed to fail interrupted ingest jobs: %w", err)
+	}
+	count, err := result.RowsAffected()
+	return int(count), err
+}
+
+// rowScanner is satisfied by both *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanIngestJob(row rowScanner) (*IngestJob, error) {
+	var job IngestJob
+	var documentULID sql.NullString
+	var startedAt, finishedAt sql.NullTime
+	var history []byte
+	err := row.Scan(&job.ID, &job.FilePath, &job.Source, &job.State, &job.Error, &job.Attempts,
+		&documentULID, &job.QueuedAt, &startedAt, &finishedAt, &job.UpdatedAt, &history)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(history, &job.History); err != nil {
+		return nil, fmt.Errorf("failed to decode ingest job %d history: %w", job.ID, err)
+	}
+	job.DocumentULID = documentULID.String
+	if startedAt.Valid {
+		job.StartedAt = &startedAt.Time
+	}
+	if finishedAt.Valid {
+		job.FinishedAt = &finishedAt.Time
+	}
+	return &job, nil
+}
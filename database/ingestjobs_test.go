@@ -0,0 +1,71 @@
+package database
+
+import (
+	"log/slog"
+	"os"
+	"testing"
+)
+
+func TestQueueIngestJob(t *testing.T) {
+	Logger = slog.New(slog.NewTextHandler(os.Stdout, nil))
+
+	postgresDB, err := SetupPostgresDatabase("")
+	if err != nil {
+		t.Fatalf("Failed to setup ephemeral database: %v", err)
+	}
+	defer postgresDB.Close()
+
+	// finish queues the file and ends its job in state after the given number of attempts
+	finish := func(filePath string, state string, attempts int) *IngestJob {
+		t.Helper()
+		job, err := postgresDB.QueueIngestJob(filePath, "ingress")
+		if err != nil {
+			t.Fatalf("QueueIngestJob failed: %v", err)
+		}
+		job.State = state
+		job.Error = "could not read file"
+		job.Attempts = attempts
+		if err := postgresDB.UpdateIngestJob(job); err != nil {
+			t.Fatalf("UpdateIngestJob failed: %v", err)
+		}
+		return job
+	}
+
+	tests := []struct {
+		name         string
+		state        string
+		wantAttempts int
+		wantError    string
+	}{
+		{"Failed job keeps its attempts", IngestJobFailed, 2, "could not read file"},
+		{"Quarantined job starts again", IngestJobQuarantined, 0, ""},
+		{"Duplicate job starts again", IngestJobDuplicate, 0, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filePath := "/ingress/" + tt.state + ".pdf"
+			previous := finish(filePath, tt.state, 2)
+			job, err := postgresDB.QueueIngestJob(filePath, "ingress")
+			if err != nil {
+				t.Fatalf("QueueIngestJob failed: %v", err)
+			}
+			if job.ID != previous.ID || job.State != IngestJobQueued {
+				t.Errorf("Expected job %d queued again, got job %d in state %s", previous.ID, job.ID, job.State)
+			}
+			if job.Attempts != tt.wantAttempts || job.Error != tt.wantError {
+				t.Errorf("Expected %d attempts and error %q, got %d and %q", tt.wantAttempts, tt.wantError, job.Attempts, job.Error)
+			}
+		})
+	}
+
+	t.Run("Stored job gets a new job", func(t *testing.T) {
+		previous := finish("/ingress/stored.pdf", IngestJobStored, 1)
+		job, err := postgresDB.QueueIngestJob("/ingress/stored.pdf", "ingress")
+		if err != nil {
+			t.Fatalf("QueueIngestJob failed: %v", err)
+		}
+		if job.ID == previous.ID || job.Attempts != 0 {
+			t.Errorf("Expected a new job for a stored file, got job %d with %d attempts", job.ID, job.Attempts)
+		}
+	})
+}
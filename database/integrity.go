@@ -0,0 +1,168 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// Problems an integrity check can find with a stored document
+const (
+	IntegrityMismatch   = "mismatch"   // the file no longer matches its hash
+	IntegrityMissing    = "missing"    // the file is not on disk
+	IntegrityUnreadable = "unreadable" // the file could not be read
+)
+
+// IntegrityRun is one check of every stored document against its hash
+type IntegrityRun struct {
+	ID         int        `json:"id"`
+	StartedAt  time.Time  `json:"startedAt"`
+	FinishedAt *time.Time `json:"finishedAt"` // nil while the run is in progress
+	Checked    int        `json:"checked"`
+	Mismatched int        `json:"mismatched"`
+	Missing    int        `json:"missing"`
+	Unreadable int        `json:"unreadable"`
+	Error      string     `json:"error"` // set if the run could not check every document
+}
+
+// IntegrityIssue is a problem found with one document during a run
+type IntegrityIssue struct {
+	ID            int       `json:"id"`
+	RunID         int       `json:"runID"`
+	DocumentULID  string    `json:"documentULID"`
+	Name          string    `json:"name"`
+	Path          string    `json:"path"`
+	Issue         string    `json:"issue"`
+	HashAlgorithm string    `json:"hashAlgorithm"`
+	ExpectedHash  string    `json:"expectedHash"`
+	ActualHash    string    `json:"actualHash"` // set for a mismatch
+	Error         string    `json:"error"`      // set when the file could not be read
+	DetectedAt    time.Time `json:"detectedAt"`
+}
+
+const integrityRunColumns = `id, started_at, finished_at, checked, mismatched, missing, unreadable, error`
+
+// StartIntegrityRun records the start of a run
+func (p *PostgresDB) StartIntegrityRun() (*IntegrityRun, error) {
+	run, err := scanIntegrityRun(p.db.QueryRow(`INSERT INTO integrity_runs DEFAULT VALUES RETURNING ` + integrityRunColumns))
+	if err != nil {
+		return nil, fmt.Errorf("failed to start integrity run: %w", err)
+	}
+	return run, nil
+}
+
+// FinishIntegrityRun saves the totals of a run and marks it finished
+func (p *PostgresDB) FinishIntegrityRun(run *IntegrityRun) error {
+	query := `
+		UPDATE integrity_runs SET finished_at = CURRENT_TIMESTAMP, checked = $1, mismatched = $2, missing = $3,
+			unreadable = $4, error = $5
+		WHERE id = $6
+		RETURNING finished_at
+	`
+	var finishedAt time.Time
+	err := p.db.QueryRow(query, run.Checked, run.Mismatched, run.Missing, run.Unreadable, run.Error, run.ID).Scan(&finishedAt)
+	if err != nil {
+		return fmt.Errorf("failed to finish integrity run %d: %w", run.ID, err)
+	}
+	run.FinishedAt = &finishedAt
+	return nil
+}
+
+// RecordIntegrityIssue saves a problem found during a run
+func (p *PostgresDB) RecordIntegrityIssue(issue *IntegrityIssue) error {
+	query := `
+		INSERT INTO integrity_issues (run_id, document_ulid, name, path, issue, hash_algorithm, expected_hash, actual_hash, error)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		RETURNING id, detected_at
+	`
+	err := p.db.QueryRow(query, issue.RunID, issue.DocumentULID, issue.Name, issue.Path, issue.Issue,
+		issue.HashAlgorithm, issue.ExpectedHash, issue.ActualHash, issue.Error).Scan(&issue.ID, &issue.DetectedAt)
+	if err != nil {
+		return fmt.Errorf("failed to record integrity issue: %w", err)
+	}
+	return nil
+}
+
+// GetIntegrityRun retrieves a run by ID, returning sql.ErrNoRows if there is none
+func (p *PostgresDB) GetIntegrityRun(id int) (*IntegrityRun, error) {
+	return scanIntegrityRun(p.db.QueryRow(`SELECT `+integrityRunColumns+` FROM integrity_runs WHERE id = $1`, id))
+}
+
+// ListIntegrityRuns returns the most recent runs, newest first
+func (p *PostgresDB) ListIntegrityRuns(limit int) ([]IntegrityRun, error) {
+	rows, err := p.db.Query(`SELECT `+integrityRunColumns+` FROM integrity_runs ORDER BY started_at DESC, id DESC LIMIT $1`, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query integrity runs: %w", err)
+	}
+	defer rows.Close()
+
+	runs := make([]IntegrityRun, 0)
+	for rows.Next() {
+		run, err := scanIntegrityRun(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan integrity run: %w", err)
+		}
+		runs = append(runs, *run)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+	return runs, nil
+}
+
+// ListIntegrityIssues returns the problems found during a run in the order they were found
+func (p *PostgresDB) ListIntegrityIssues(runID int) ([]IntegrityIssue, error) {
+	query := `
+		SELECT id, run_id, document_ulid, name, path, issue, hash_algorithm, expected_hash, actual_hash, error, detected_at
+		FROM integrity_issues
+		WHERE run_id = $1
+		ORDER BY id
+	`
+	rows, err := p.db.Query(query, runID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query integrity issues: %w", err)
+	}
+	defer rows.Close()
+
+	issues := make([]IntegrityIssue, 0)
+	for rows.Next() {
+		var issue IntegrityIssue
+		err := rows.Scan(&issue.ID, &issue.RunID, &issue.DocumentULID, &issue.Name, &issue.Path, &issue.Issue,
+			&issue.HashAlgorithm, &issue.ExpectedHash, &issue.ActualHash, &issue.Error, &issue.DetectedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan integrity issue: %w", err)
+		}
+		issues = append(issues, issue)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+	return issues, nil
+}
+
+// FailInterruptedIntegrityRuns marks runs left unfinished by a server restart as failed
+func (p *PostgresDB) FailInterruptedIntegrityRuns() (int, error) {
+	result, err := p.db.Exec(`
+		UPDATE integrity_runs SET finished_at = CURRENT_TIMESTAMP, error = 'interrupted by server restart'
+		WHERE finished_at IS NULL
+	`)
+	if err != nil {
+		return 0, fmt.Errorf("failed to fail interrupted integrity runs: %w", err)
+	}
+	count, err := result.RowsAffected()
+	return int(count), err
+}
+
+func scanIntegrityRun(row rowScanner) (*IntegrityRun, error) {
+	var run IntegrityRun
+	var finishedAt sql.NullTime
+	err := row.Scan(&run.ID, &run.StartedAt, &finishedAt, &run.Checked, &run.Mismatched, &run.Missing,
+		&run.Unreadable, &run.Error)
+	if err != nil {
+		return nil, err
+	}
+	if finishedAt.Valid {
+		run.FinishedAt = &finishedAt.Time
+	}
+	return &run, nil
+}
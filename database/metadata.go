@@ -0,0 +1,50 @@
+package database
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+)
+
+// DocumentMetadata holds the document properties found when its text was extracted, such as title and author.  It
+// is stored as a JSONB object.
+type DocumentMetadata map[string]string
+
+// Value stores the metadata as a JSON object, nil is stored as an empty one
+func (metadata DocumentMetadata) Value() (driver.Value, error) {
+	if metadata == nil {
+		return []byte("{}"), nil
+	}
+	return json.Marshal(metadata)
+}
+
+// Scan reads the metadata back from its JSON object
+func (metadata *DocumentMetadata) Scan(src interface{}) error {
+	var body []byte
+	switch value := src.(type) {
+	case nil:
+		*metadata = nil
+		return nil
+	case []byte:
+		body = value
+	case string:
+		body = []byte(value)
+	default:
+		return fmt.Errorf("cannot read document metadata from %T", src)
+	}
+	decoded := DocumentMetadata{}
+	if err := json.Unmarshal(body, &decoded); err != nil {
+		return err
+	}
+	if len(decoded) == 0 {
+		decoded = nil
+	}
+	*metadata = decoded
+	return nil
+}
+
+// UpdateDocumentProperties records the page count and metadata found when a document's text was extracted
+func (p *PostgresDB) UpdateDocumentProperties(ulid string, pageCount int, metadata DocumentMetadata) error {
+	query := `UPDATE documents SET page_count = $1, metadata = $2, updated_at = CURRENT_TIMESTAMP WHERE ulid = $3`
+	return expectOneRow(p.db.Exec(query, pageCount, metadata, ulid))
+}
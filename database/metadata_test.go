@@ -0,0 +1,56 @@
+package database
+
+import (
+	"log/slog"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestDocumentProperties(t *testing.T) {
+	Logger = slog.New(slog.NewTextHandler(os.Stdout, nil))
+
+	postgresDB, err := SetupPostgresDatabase("")
+	if err != nil {
+		t.Fatalf("Failed to setup ephemeral database: %v", err)
+	}
+	defer postgresDB.Close()
+
+	ulid, _ := CalculateUUID(time.Now())
+	doc := &Document{
+		Name:         "Report.docx",
+		Path:         "/test/Report.docx",
+		Folder:       "/test",
+		Hash:         "propertieshash",
+		IngressTime:  time.Now(),
+		DocumentType: ".docx",
+		ULID:         ulid,
+	}
+	if err := postgresDB.SaveDocument(doc); err != nil {
+		t.Fatalf("Failed to save document: %v", err)
+	}
+	stored, err := postgresDB.GetDocumentByULID(ulid.String())
+	if err != nil {
+		t.Fatalf("Failed to fetch document: %v", err)
+	}
+	if stored.PageCount != 0 || stored.Metadata != nil {
+		t.Errorf("Expected no properties before they are recorded, got %d pages and %v", stored.PageCount, stored.Metadata)
+	}
+
+	metadata := DocumentMetadata{"title": "Quarterly report", "author": "Jane Smith"}
+	if err := postgresDB.UpdateDocumentProperties(ulid.String(), 3, metadata); err != nil {
+		t.Fatalf("UpdateDocumentProperties failed: %v", err)
+	}
+	stored, err = postgresDB.GetDocumentByULID(ulid.String())
+	if err != nil {
+		t.Fatalf("Failed to fetch document: %v", err)
+	}
+	if stored.PageCount != 3 || !reflect.DeepEqual(stored.Metadata, metadata) {
+		t.Errorf("Expected 3 pages and %v, got %d pages and %v", metadata, stored.PageCount, stored.Metadata)
+	}
+
+	if err := postgresDB.UpdateDocumentProperties("01ARZ3NDEKTSV4RRFFQ69G5FAV", 1, nil); err == nil {
+		t.Error("Expected an error updating an unknown document")
+	}
+}
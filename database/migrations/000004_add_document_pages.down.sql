@@ -0,0 +1,3 @@
+-- Rollback per page text storage
+
+DROP TABLE IF EXISTS document_pages CASCADE;
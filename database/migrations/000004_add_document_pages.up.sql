@@ -0,0 +1,14 @@
+-- Store extracted text per page so search can report which page matched
+-- Full text on the documents table is assembled from these pages
+
+CREATE TABLE IF NOT EXISTS document_pages (
+    id SERIAL PRIMARY KEY,
+    document_ulid TEXT NOT NULL REFERENCES documents(ulid) ON DELETE CASCADE ON UPDATE CASCADE,
+    page_number INTEGER NOT NULL CHECK (page_number > 0),
+    text TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (document_ulid, page_number)
+);
+
+-- Create index for fetching the pages of a document in order
+CREATE INDEX IF NOT EXISTS idx_document_pages_document ON document_pages(document_ulid, page_number);
@@ -0,0 +1,3 @@
+-- Rollback ingest job tracking
+
+DROP TABLE IF EXISTS ingest_jobs CASCADE;
@@ -0,0 +1,27 @@
+-- Track every file through ingestion so failures are visible and can be retried
+-- A file that keeps failing reuses its job row, attempts counts the tries
+
+CREATE TABLE IF NOT EXISTS ingest_jobs (
+    id SERIAL PRIMARY KEY,
+    file_path TEXT NOT NULL,
+    source TEXT NOT NULL DEFAULT 'ingress',
+    state TEXT NOT NULL DEFAULT 'queued' CHECK (state IN ('queued', 'extracting', 'ocr', 'stored', 'failed')),
+    error TEXT NOT NULL DEFAULT '',
+    attempts INTEGER NOT NULL DEFAULT 0,
+    document_ulid TEXT,
+    queued_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    started_at TIMESTAMP,
+    finished_at TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Create indexes for the queue view and for finding the open job of a file
+CREATE INDEX IF NOT EXISTS idx_ingest_jobs_state ON ingest_jobs(state);
+CREATE INDEX IF NOT EXISTS idx_ingest_jobs_file_path ON ingest_jobs(file_path);
+CREATE INDEX IF NOT EXISTS idx_ingest_jobs_queued_at ON ingest_jobs(queued_at DESC);
+
+DROP TRIGGER IF EXISTS update_ingest_jobs_timestamp ON ingest_jobs;
+CREATE TRIGGER update_ingest_jobs_timestamp
+    BEFORE UPDATE ON ingest_jobs
+    FOR EACH ROW
+    EXECUTE FUNCTION update_updated_at_column();
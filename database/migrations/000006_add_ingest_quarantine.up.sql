@@ -0,0 +1,8 @@
+-- Files that keep failing ingestion are moved to the quarantine folder
+-- history keeps every attempt so the quarantine report can show what went wrong each time
+
+ALTER TABLE ingest_jobs ADD COLUMN IF NOT EXISTS history JSONB NOT NULL DEFAULT '[]';
+
+ALTER TABLE ingest_jobs DROP CONSTRAINT IF EXISTS ingest_jobs_state_check;
+ALTER TABLE ingest_jobs ADD CONSTRAINT ingest_jobs_state_check
+    CHECK (state IN ('queued', 'extracting', 'ocr', 'stored', 'failed', 'quarantined'));
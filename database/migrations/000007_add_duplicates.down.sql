@@ -0,0 +1,9 @@
+-- Rollback duplicate tracking
+
+UPDATE ingest_jobs SET state = 'failed' WHERE state = 'duplicate';
+
+ALTER TABLE ingest_jobs DROP CONSTRAINT IF EXISTS ingest_jobs_state_check;
+ALTER TABLE ingest_jobs ADD CONSTRAINT ingest_jobs_state_check
+    CHECK (state IN ('queued', 'extracting', 'ocr', 'stored', 'failed', 'quarantined'));
+
+DROP TABLE IF EXISTS duplicates CASCADE;
@@ -0,0 +1,22 @@
+-- Record every ingested file whose content matched a stored document and what was done with it
+-- Versioned duplicates are stored as documents of their own and linked back here
+
+CREATE TABLE IF NOT EXISTS duplicates (
+    id SERIAL PRIMARY KEY,
+    hash TEXT NOT NULL,
+    file_path TEXT NOT NULL,
+    existing_ulid TEXT NOT NULL REFERENCES documents(ulid) ON DELETE CASCADE ON UPDATE CASCADE,
+    action TEXT NOT NULL CHECK (action IN ('rejected', 'skipped', 'versioned')),
+    moved_to TEXT NOT NULL DEFAULT '',
+    version_ulid TEXT REFERENCES documents(ulid) ON DELETE SET NULL ON UPDATE CASCADE,
+    version INTEGER,
+    detected_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_duplicates_existing_ulid ON duplicates(existing_ulid);
+CREATE INDEX IF NOT EXISTS idx_duplicates_detected_at ON duplicates(detected_at DESC);
+
+-- Ingest jobs for duplicates end in their own state rather than failed
+ALTER TABLE ingest_jobs DROP CONSTRAINT IF EXISTS ingest_jobs_state_check;
+ALTER TABLE ingest_jobs ADD CONSTRAINT ingest_jobs_state_check
+    CHECK (state IN ('queued', 'extracting', 'ocr', 'stored', 'failed', 'quarantined', 'duplicate'));
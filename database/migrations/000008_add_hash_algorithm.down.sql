@@ -0,0 +1,5 @@
+-- Rollback hash algorithm tracking
+-- SHA-256 hashes are left in place, duplicate detection will not match them against MD5 hashes of new files
+
+DROP INDEX IF EXISTS idx_documents_hash_md5;
+ALTER TABLE documents DROP COLUMN IF EXISTS hash_algorithm;
@@ -0,0 +1,8 @@
+-- Documents are now hashed with SHA-256, existing rows keep their MD5 hash until the rehash job replaces it
+
+ALTER TABLE documents ADD COLUMN IF NOT EXISTS hash_algorithm TEXT NOT NULL DEFAULT 'md5'
+    CHECK (hash_algorithm IN ('md5', 'sha256'));
+ALTER TABLE documents ALTER COLUMN hash_algorithm SET DEFAULT 'sha256';
+
+-- The rehash job walks the remaining MD5 rows
+CREATE INDEX IF NOT EXISTS idx_documents_hash_md5 ON documents(id) WHERE hash_algorithm = 'md5';
@@ -0,0 +1,4 @@
+-- Rollback integrity verification
+
+DROP TABLE IF EXISTS integrity_issues CASCADE;
+DROP TABLE IF EXISTS integrity_runs CASCADE;
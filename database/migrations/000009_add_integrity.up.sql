@@ -0,0 +1,31 @@
+-- Record each check of stored documents against their hash and every problem it found
+-- Issues keep the name and path of the document so the report survives the document being deleted
+
+CREATE TABLE IF NOT EXISTS integrity_runs (
+    id SERIAL PRIMARY KEY,
+    started_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    finished_at TIMESTAMP,
+    checked INTEGER NOT NULL DEFAULT 0,
+    mismatched INTEGER NOT NULL DEFAULT 0,
+    missing INTEGER NOT NULL DEFAULT 0,
+    unreadable INTEGER NOT NULL DEFAULT 0,
+    error TEXT NOT NULL DEFAULT ''
+);
+
+CREATE TABLE IF NOT EXISTS integrity_issues (
+    id SERIAL PRIMARY KEY,
+    run_id INTEGER NOT NULL REFERENCES integrity_runs(id) ON DELETE CASCADE,
+    document_ulid TEXT NOT NULL,
+    name TEXT NOT NULL,
+    path TEXT NOT NULL,
+    issue TEXT NOT NULL CHECK (issue IN ('mismatch', 'missing', 'unreadable')),
+    hash_algorithm TEXT NOT NULL,
+    expected_hash TEXT NOT NULL,
+    actual_hash TEXT NOT NULL DEFAULT '',
+    error TEXT NOT NULL DEFAULT '',
+    detected_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_integrity_runs_started_at ON integrity_runs(started_at DESC);
+CREATE INDEX IF NOT EXISTS idx_integrity_issues_run_id ON integrity_issues(run_id);
+CREATE INDEX IF NOT EXISTS idx_integrity_issues_document_ulid ON integrity_issues(document_ulid);
@@ -0,0 +1,5 @@
+-- Rollback the trash, documents still in it become ordinary documents whose file is in the trash folder
+
+DROP INDEX IF EXISTS idx_documents_deleted_at;
+ALTER TABLE documents DROP COLUMN IF EXISTS deleted_from;
+ALTER TABLE documents DROP COLUMN IF EXISTS deleted_at;
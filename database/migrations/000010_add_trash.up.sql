@@ -0,0 +1,7 @@
+-- Deleted documents go to the trash rather than being removed straight away
+-- While in the trash path points at the file in the trash folder and deleted_from is where it is restored to
+
+ALTER TABLE documents ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP;
+ALTER TABLE documents ADD COLUMN IF NOT EXISTS deleted_from TEXT NOT NULL DEFAULT '';
+
+CREATE INDEX IF NOT EXISTS idx_documents_deleted_at ON documents(deleted_at) WHERE deleted_at IS NOT NULL;
@@ -0,0 +1,4 @@
+-- Rollback document sizes
+
+DROP INDEX IF EXISTS idx_documents_size_unknown;
+ALTER TABLE documents DROP COLUMN IF EXISTS size;
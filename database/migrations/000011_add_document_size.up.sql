@@ -0,0 +1,7 @@
+-- Record the size of each document file so search can filter on it
+-- Existing rows are 0 until the startup job reads the size of their file
+
+ALTER TABLE documents ADD COLUMN IF NOT EXISTS size BIGINT NOT NULL DEFAULT 0;
+
+-- The startup job walks the rows whose size is not known yet
+CREATE INDEX IF NOT EXISTS idx_documents_size_unknown ON documents(id) WHERE size = 0;
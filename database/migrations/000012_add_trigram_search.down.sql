@@ -0,0 +1,5 @@
+-- Rollback fuzzy search
+
+DROP INDEX IF EXISTS idx_documents_name_trgm;
+DROP INDEX IF EXISTS idx_documents_full_text_trgm;
+DROP EXTENSION IF EXISTS pg_trgm;
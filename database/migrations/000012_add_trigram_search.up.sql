@@ -0,0 +1,8 @@
+-- Add fuzzy search with trigram similarity
+-- OCR errors ("lnvoice") mean words are missing from the English full-text index, trigrams still find them
+
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+-- GIN indexes make the word similarity operator (<%) fast over the full text and names
+CREATE INDEX IF NOT EXISTS idx_documents_full_text_trgm ON documents USING GIN (full_text gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS idx_documents_name_trgm ON documents USING GIN (name gin_trgm_ops);
@@ -0,0 +1,6 @@
+-- Rollback full-text search on document pages
+
+DROP TRIGGER IF EXISTS trigger_update_page_text_search ON document_pages;
+DROP FUNCTION IF EXISTS update_page_text_search();
+DROP INDEX IF EXISTS idx_document_pages_text_search;
+ALTER TABLE document_pages DROP COLUMN IF EXISTS text_search;
@@ -0,0 +1,24 @@
+-- Add full-text search on document pages
+-- Search looks up the best matching page of every result, so store the search vector rather than building it per row
+
+ALTER TABLE document_pages ADD COLUMN IF NOT EXISTS text_search tsvector;
+
+CREATE INDEX IF NOT EXISTS idx_document_pages_text_search ON document_pages USING GIN(text_search);
+
+-- Keep the search vector up to date as pages are saved
+CREATE OR REPLACE FUNCTION update_page_text_search()
+RETURNS TRIGGER AS $$
+BEGIN
+    NEW.text_search = to_tsvector('english', COALESCE(NEW.text, ''));
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS trigger_update_page_text_search ON document_pages;
+CREATE TRIGGER trigger_update_page_text_search
+    BEFORE INSERT OR UPDATE OF text ON document_pages
+    FOR EACH ROW
+    EXECUTE FUNCTION update_page_text_search();
+
+-- Populate the search vector for pages already stored
+UPDATE document_pages SET text_search = to_tsvector('english', COALESCE(text, ''));
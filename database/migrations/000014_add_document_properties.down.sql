@@ -0,0 +1,4 @@
+-- Rollback document properties
+
+ALTER TABLE documents DROP COLUMN IF EXISTS metadata;
+ALTER TABLE documents DROP COLUMN IF EXISTS page_count;
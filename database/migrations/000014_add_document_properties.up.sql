@@ -0,0 +1,5 @@
+-- Store the page count and properties (title, author ...) found when a document's text is extracted
+-- Existing rows keep 0 pages and no properties until they are ingested again
+
+ALTER TABLE documents ADD COLUMN IF NOT EXISTS page_count INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE documents ADD COLUMN IF NOT EXISTS metadata JSONB NOT NULL DEFAULT '{}';
@@ -0,0 +1,73 @@
+package database
+
+import (
+	"fmt"
+)
+
+// DocumentPage is the extracted text of a single page of a document
+type DocumentPage struct {
+	DocumentULID string `json:"documentULID"`
+	PageNumber   int    `json:"pageNumber"` // 1 based
+	Text         string `json:"text"`
+}
+
+// SaveDocumentPages replaces the stored pages of a document, pages[0] is saved as page 1
+func (p *PostgresDB) SaveDocumentPages(ulidStr string, pages []string) error {
+	tx, err := p.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM document_pages WHERE document_ulid = $1`, ulidStr); err != nil {
+		return fmt.Errorf("failed to clear document pages: %w", err)
+	}
+
+	stmt, err := tx.Prepare(`
+		INSERT INTO document_pages (document_ulid, page_number, text)
+		VALUES ($1, $2, $3)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare statement: %w", err)
+	}
+	defer stmt.Close()
+
+	for i, text := range pages {
+		if _, err := stmt.Exec(ulidStr, i+1, text); err != nil {
+			return fmt.Errorf("failed to insert page %d: %w", i+1, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// GetDocumentPages returns the stored pages of a document in page order
+func (p *PostgresDB) GetDocumentPages(ulidStr string) ([]DocumentPage, error) {
+	query := `
+		SELECT document_ulid, page_number, text
+		FROM document_pages
+		WHERE document_ulid = $1
+		ORDER BY page_number
+	`
+	rows, err := p.db.Query(query, ulidStr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query document pages: %w", err)
+	}
+	defer rows.Close()
+
+	var pages []DocumentPage
+	for rows.Next() {
+		var page DocumentPage
+		if err := rows.Scan(&page.DocumentULID, &page.PageNumber, &page.Text); err != nil {
+			return nil, fmt.Errorf("failed to scan page: %w", err)
+		}
+		pages = append(pages, page)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+	return pages, nil
+}
@@ -0,0 +1,68 @@
+package database
+
+import (
+	"log/slog"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestDocumentPages(t *testing.T) {
+	// Initialize logger
+	Logger = slog.New(slog.NewTextHandler(os.Stdout, nil))
+
+	// Setup ephemeral database for testing
+	postgresDB, err := SetupPostgresDatabase("")
+	if err != nil {
+		t.Fatalf("Failed to setup ephemeral database: %v", err)
+	}
+	defer postgresDB.Close()
+
+	ulid, _ := CalculateUUID(time.Now())
+	doc := &Document{
+		Name:         "Scan.pdf",
+		Path:         "/test/Scan.pdf",
+		Folder:       "/test",
+		Hash:         "pagehash",
+		FullText:     "first page\n\nsecond page",
+		IngressTime:  time.Now(),
+		DocumentType: ".pdf",
+		ULID:         ulid,
+	}
+	if err := postgresDB.SaveDocument(doc); err != nil {
+		t.Fatalf("Failed to save document: %v", err)
+	}
+
+	if err := postgresDB.SaveDocumentPages(ulid.String(), []string{"first page", "second page"}); err != nil {
+		t.Fatalf("SaveDocumentPages failed: %v", err)
+	}
+	// Saving again replaces rather than appends
+	if err := postgresDB.SaveDocumentPages(ulid.String(), []string{"first page", "", "third page"}); err != nil {
+		t.Fatalf("SaveDocumentPages (replace) failed: %v", err)
+	}
+
+	pages, err := postgresDB.GetDocumentPages(ulid.String())
+	if err != nil {
+		t.Fatalf("GetDocumentPages failed: %v", err)
+	}
+	if len(pages) != 3 {
+		t.Fatalf("Expected 3 pages, got %d", len(pages))
+	}
+	for i, want := range []string{"first page", "", "third page"} {
+		if pages[i].PageNumber != i+1 || pages[i].Text != want {
+			t.Errorf("Page %d: expected (%d, %q), got (%d, %q)", i, i+1, want, pages[i].PageNumber, pages[i].Text)
+		}
+	}
+
+	// Pages go with the document
+	if err := postgresDB.DeleteDocument(ulid.String()); err != nil {
+		t.Fatalf("DeleteDocument failed: %v", err)
+	}
+	pages, err = postgresDB.GetDocumentPages(ulid.String())
+	if err != nil {
+		t.Fatalf("GetDocumentPages after delete failed: %v", err)
+	}
+	if len(pages) != 0 {
+		t.Errorf("Expected pages to be deleted with the document, got %d", len(pages))
+	}
+}
@@ -5,7 +5,6 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
-	"strings"
 
 	"github.com/golang-migrate/migrate/v4"
 	"github.com/golang-migrate/migrate/v4/database/postgres"
@@ -137,27 +136,35 @@ func (p *PostgresDB) Close() error {
 	return nil
 }
 
-// SaveDocument saves or updates a document
+// SaveDocument saves or updates a document, a document without a hash algorithm is taken to be hashed with SHA-256
 func (p *PostgresDB) SaveDocument(doc *Document) error {
+	if doc.HashAlgorithm == "" {
+		doc.HashAlgorithm = HashAlgorithmSHA256
+	}
 	query := `
-		INSERT INTO documents (name, path, ingress_time, folder, hash, ulid, document_type, full_text, url)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		INSERT INTO documents (name, path, ingress_time, folder, hash, hash_algorithm, ulid, document_type, full_text, url, size,
+			page_count, metadata)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
 		ON CONFLICT(path) DO UPDATE SET
 			name = EXCLUDED.name,
 			ingress_time = EXCLUDED.ingress_time,
 			folder = EXCLUDED.folder,
 			hash = EXCLUDED.hash,
+			hash_algorithm = EXCLUDED.hash_algorithm,
 			ulid = EXCLUDED.ulid,
 			document_type = EXCLUDED.document_type,
 			full_text = EXCLUDED.full_text,
 			url = EXCLUDED.url,
+			size = EXCLUDED.size,
+			page_count = EXCLUDED.page_count,
+			metadata = EXCLUDED.metadata,
 			updated_at = CURRENT_TIMESTAMP
 		RETURNING id
 	`
 
 	err := p.db.QueryRow(query,
-		doc.Name, doc.Path, doc.IngressTime, doc.Folder, doc.Hash,
-		doc.ULID.String(), doc.DocumentType, doc.FullText, doc.URL,
+		doc.Name, doc.Path, doc.IngressTime, doc.Folder, doc.Hash, doc.HashAlgorithm,
+		doc.ULID.String(), doc.DocumentType, doc.FullText, doc.URL, doc.Size, doc.PageCount, doc.Metadata,
 	).Scan(&doc.StormID)
 
 	return err
@@ -165,7 +172,8 @@ func (p *PostgresDB) SaveDocument(doc *Document) error {
 
 // GetDocumentByID retrieves a document by ID
 func (p *PostgresDB) GetDocumentByID(id int) (*Document, error) {
-	query := `SELECT id, name, path, ingress_time, folder, hash, ulid, document_type, full_text, url
+	query := `SELECT id, name, path, ingress_time, folder, hash, hash_algorithm, ulid, document_type, full_text, url,
+	          deleted_at, deleted_from, size, page_count, metadata
 	          FROM documents WHERE id = $1`
 
 	doc := &Document{}
@@ -173,8 +181,8 @@ func (p *PostgresDB) GetDocumentByID(id int) (*Document, error) {
 
 	err := p.db.QueryRow(query, id).Scan(
 		&doc.StormID, &doc.Name, &doc.Path, &doc.IngressTime,
-		&doc.Folder, &doc.Hash, &ulidStr, &doc.DocumentType,
-		&doc.FullText, &doc.URL,
+		&doc.Folder, &doc.Hash, &doc.HashAlgorithm, &ulidStr, &doc.DocumentType,
+		&doc.FullText, &doc.URL, &doc.DeletedAt, &doc.DeletedFrom, &doc.Size, &doc.PageCount, &doc.Metadata,
 	)
 
 	if err != nil {
@@ -192,7 +200,8 @@ func (p *PostgresDB) GetDocumentByID(id int) (*Document, error) {
 
 // GetDocumentByULID retrieves a document by ULID
 func (p *PostgresDB) GetDocumentByULID(ulidStr string) (*Document, error) {
-	query := `SELECT id, name, path, ingress_time, folder, hash, ulid, document_type, full_text, url
+	query := `SELECT id, name, path, ingress_time, folder, hash, hash_algorithm, ulid, document_type, full_text, url,
+	          deleted_at, deleted_from, size, page_count, metadata
 	          FROM documents WHERE ulid = $1`
 
 	doc := &Document{}
@@ -200,8 +209,8 @@ func (p *PostgresDB) GetDocumentByULID(ulidStr string) (*Document, error) {
 
 	err := p.db.QueryRow(query, ulidStr).Scan(
 		&doc.StormID, &doc.Name, &doc.Path, &doc.IngressTime,
-		&doc.Folder, &doc.Hash, &docUlidStr, &doc.DocumentType,
-		&doc.FullText, &doc.URL,
+		&doc.Folder, &doc.Hash, &doc.HashAlgorithm, &docUlidStr, &doc.DocumentType,
+		&doc.FullText, &doc.URL, &doc.DeletedAt, &doc.DeletedFrom, &doc.Size, &doc.PageCount, &doc.Metadata,
 	)
 
 	if err != nil {
@@ -219,7 +228,8 @@ func (p *PostgresDB) GetDocumentByULID(ulidStr string) (*Document, error) {
 
 // GetDocumentByPath retrieves a document by file path
 func (p *PostgresDB) GetDocumentByPath(path string) (*Document, error) {
-	query := `SELECT id, name, path, ingress_time, folder, hash, ulid, document_type, full_text, url
+	query := `SELECT id, name, path, ingress_time, folder, hash, hash_algorithm, ulid, document_type, full_text, url,
+	          deleted_at, deleted_from, size, page_count, metadata
 	          FROM documents WHERE path = $1`
 
 	doc := &Document{}
@@ -227,8 +237,8 @@ func (p *PostgresDB) GetDocumentByPath(path string) (*Document, error) {
 
 	err := p.db.QueryRow(query, path).Scan(
 		&doc.StormID, &doc.Name, &doc.Path, &doc.IngressTime,
-		&doc.Folder, &doc.Hash, &ulidStr, &doc.DocumentType,
-		&doc.FullText, &doc.URL,
+		&doc.Folder, &doc.Hash, &doc.HashAlgorithm, &ulidStr, &doc.DocumentType,
+		&doc.FullText, &doc.URL, &doc.DeletedAt, &doc.DeletedFrom, &doc.Size, &doc.PageCount, &doc.Metadata,
 	)
 
 	if err != nil {
@@ -244,18 +254,19 @@ func (p *PostgresDB) GetDocumentByPath(path string) (*Document, error) {
 	return doc, nil
 }
 
-// GetDocumentByHash retrieves a document by hash
+// GetDocumentByHash retrieves a document by hash, the first one ingested if there are several versions
 func (p *PostgresDB) GetDocumentByHash(hash string) (*Document, error) {
-	query := `SELECT id, name, path, ingress_time, folder, hash, ulid, document_type, full_text, url
-	          FROM documents WHERE hash = $1`
+	query := `SELECT id, name, path, ingress_time, folder, hash, hash_algorithm, ulid, document_type, full_text, url,
+	          deleted_at, deleted_from, size, page_count, metadata
+	          FROM documents WHERE hash = $1 AND deleted_at IS NULL ORDER BY ingress_time, id LIMIT 1`
 
 	doc := &Document{}
 	var ulidStr string
 
 	err := p.db.QueryRow(query, hash).Scan(
 		&doc.StormID, &doc.Name, &doc.Path, &doc.IngressTime,
-		&doc.Folder, &doc.Hash, &ulidStr, &doc.DocumentType,
-		&doc.FullText, &doc.URL,
+		&doc.Folder, &doc.Hash, &doc.HashAlgorithm, &ulidStr, &doc.DocumentType,
+		&doc.FullText, &doc.URL, &doc.DeletedAt, &doc.DeletedFrom, &doc.Size, &doc.PageCount, &doc.Metadata,
 	)
 
 	if err == sql.ErrNoRows {
@@ -284,8 +295,8 @@ func scanDocuments(rows *sql.Rows) ([]Document, error) {
 
 		err := rows.Scan(
 			&doc.StormID, &doc.Name, &doc.Path, &doc.IngressTime,
-			&doc.Folder, &doc.Hash, &ulidStr, &doc.DocumentType,
-			&doc.FullText, &doc.URL,
+			&doc.Folder, &doc.Hash, &doc.HashAlgorithm, &ulidStr, &doc.DocumentType,
+			&doc.FullText, &doc.URL, &doc.DeletedAt, &doc.DeletedFrom, &doc.Size, &doc.PageCount, &doc.Metadata,
 		)
 		if err != nil {
 			return nil, err
@@ -305,8 +316,9 @@ func scanDocuments(rows *sql.Rows) ([]Document, error) {
 
 // GetNewestDocuments retrieves the newest documents
 func (p *PostgresDB) GetNewestDocuments(limit int) ([]Document, error) {
-	query := `SELECT id, name, path, ingress_time, folder, hash, ulid, document_type, full_text, url
-	          FROM documents ORDER BY ingress_time DESC LIMIT $1`
+	query := `SELECT id, name, path, ingress_time, folder, hash, hash_algorithm, ulid, document_type, full_text, url,
+	          deleted_at, deleted_from, size, page_count, metadata
+	          FROM documents WHERE deleted_at IS NULL ORDER BY ingress_time DESC LIMIT $1`
 
 	rows, err := p.db.Query(query, limit)
 	if err != nil {
@@ -319,7 +331,8 @@ func (p *PostgresDB) GetNewestDocuments(limit int) ([]Document, error) {
 
 // GetAllDocuments retrieves all documents
 func (p *PostgresDB) GetAllDocuments() ([]Document, error) {
-	query := `SELECT id, name, path, ingress_time, folder, hash, ulid, document_type, full_text, url
+	query := `SELECT id, name, path, ingress_time, folder, hash, hash_algorithm, ulid, document_type, full_text, url,
+	          deleted_at, deleted_from, size, page_count, metadata
 	          FROM documents ORDER BY id`
 
 	rows, err := p.db.Query(query)
@@ -331,10 +344,92 @@ func (p *PostgresDB) GetAllDocuments() ([]Document, error) {
 	return scanDocuments(rows)
 }
 
+// GetDocumentsByHashAlgorithm retrieves up to limit documents hashed with algorithm whose id is after afterID, in id order
+func (p *PostgresDB) GetDocumentsByHashAlgorithm(algorithm string, afterID int, limit int) ([]Document, error) {
+	query := `SELECT id, name, path, ingress_time, folder, hash, hash_algorithm, ulid, document_type, full_text, url,
+	          deleted_at, deleted_from, size, page_count, metadata
+	          FROM documents WHERE hash_algorithm = $1 AND id > $2 ORDER BY id LIMIT $3`
+
+	rows, err := p.db.Query(query, algorithm, afterID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanDocuments(rows)
+}
+
+// GetDocumentsWithoutSize retrieves up to limit documents whose file size has not been recorded and whose id is
+// after afterID, in id order
+func (p *PostgresDB) GetDocumentsWithoutSize(afterID int, limit int) ([]Document, error) {
+	query := `SELECT id, name, path, ingress_time, folder, hash, hash_algorithm, ulid, document_type, full_text, url,
+	          deleted_at, deleted_from, size, page_count, metadata
+	          FROM documents WHERE size = 0 AND id > $1 ORDER BY id LIMIT $2`
+
+	rows, err := p.db.Query(query, afterID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanDocuments(rows)
+}
+
+// GetDocumentsAfter retrieves up to limit documents whose id is after afterID, in id order, so every document can
+// be read in batches
+func (p *PostgresDB) GetDocumentsAfter(afterID int, limit int) ([]Document, error) {
+	query := `SELECT id, name, path, ingress_time, folder, hash, hash_algorithm, ulid, document_type, full_text, url,
+	          deleted_at, deleted_from, size, page_count, metadata
+	          FROM documents WHERE id > $1 ORDER BY id LIMIT $2`
+
+	rows, err := p.db.Query(query, afterID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanDocuments(rows)
+}
+
+// UpdateDocumentHash replaces the hash of a document and records the algorithm it was made with
+func (p *PostgresDB) UpdateDocumentHash(ulid string, hash string, algorithm string) error {
+	query := `UPDATE documents SET hash = $1, hash_algorithm = $2, updated_at = CURRENT_TIMESTAMP WHERE ulid = $3`
+	result, err := p.db.Exec(query, hash, algorithm, ulid)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// UpdateDocumentSize records the size of a document's file in bytes
+func (p *PostgresDB) UpdateDocumentSize(ulid string, size int64) error {
+	query := `UPDATE documents SET size = $1, updated_at = CURRENT_TIMESTAMP WHERE ulid = $2`
+	result, err := p.db.Exec(query, size, ulid)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
 // GetDocumentsByFolder retrieves documents in a specific folder
 func (p *PostgresDB) GetDocumentsByFolder(folder string) ([]Document, error) {
-	query := `SELECT id, name, path, ingress_time, folder, hash, ulid, document_type, full_text, url
-	          FROM documents WHERE folder = $1`
+	query := `SELECT id, name, path, ingress_time, folder, hash, hash_algorithm, ulid, document_type, full_text, url,
+	          deleted_at, deleted_from, size, page_count, metadata
+	          FROM documents WHERE folder = $1 AND deleted_at IS NULL`
 
 	rows, err := p.db.Query(query, folder)
 	if err != nil {
@@ -366,6 +461,100 @@ func (p *PostgresDB) UpdateDocumentFolder(ulidStr string, folder string) error {
 	return err
 }
 
+// UpdateDocumentLocation updates the Path and Folder of a document whose file has been moved, returning
+// sql.ErrNoRows if there is no such document
+func (p *PostgresDB) UpdateDocumentLocation(ulidStr string, path string, folder string) error {
+	query := `UPDATE documents SET path = $1, folder = $2, updated_at = CURRENT_TIMESTAMP WHERE ulid = $3`
+	result, err := p.db.Exec(query, path, folder, ulidStr)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// RenameDocument updates the Name and Path of a document whose file has been renamed, the search vector follows the
+// name through its trigger.  Returns sql.ErrNoRows if there is no such document.
+func (p *PostgresDB) RenameDocument(ulidStr string, name string, path string) error {
+	query := `UPDATE documents SET name = $1, path = $2, updated_at = CURRENT_TIMESTAMP WHERE ulid = $3`
+	result, err := p.db.Exec(query, name, path, ulidStr)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// RenameFolder rewrites the Path and Folder of every document in oldFolder or below it to be under newFolder, in a
+// single transaction.  Documents in the trash that were deleted from the folder have their Folder and DeletedFrom
+// rewritten too, so they are restored into the renamed folder.  It returns the moved documents' ULIDs mapped to
+// their new paths, documents in the trash are not included.
+func (p *PostgresDB) RenameFolder(oldFolder string, newFolder string) (map[string]string, error) {
+	tx, err := p.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Prefixes are compared with left() rather than LIKE so _ and % in folder names are not wildcards
+	query := `
+		UPDATE documents SET
+			folder = $2 || substr(folder, length($1) + 1),
+			path = $2 || substr(path, length($1) + 1),
+			updated_at = CURRENT_TIMESTAMP
+		WHERE (folder = $1 OR left(folder, length($1) + 1) = $1 || '/')
+			AND left(path, length($1) + 1) = $1 || '/'
+		RETURNING ulid, path
+	`
+	rows, err := tx.Query(query, oldFolder, newFolder)
+	if err != nil {
+		return nil, fmt.Errorf("failed to rename folder: %w", err)
+	}
+	defer rows.Close()
+
+	moved := make(map[string]string)
+	for rows.Next() {
+		var ulidStr, path string
+		if err := rows.Scan(&ulidStr, &path); err != nil {
+			return nil, fmt.Errorf("failed to scan renamed document: %w", err)
+		}
+		moved[ulidStr] = path
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+	rows.Close()
+
+	query = `
+		UPDATE documents SET
+			folder = CASE WHEN folder = $1 OR left(folder, length($1) + 1) = $1 || '/'
+				THEN $2 || substr(folder, length($1) + 1) ELSE folder END,
+			deleted_from = $2 || substr(deleted_from, length($1) + 1),
+			updated_at = CURRENT_TIMESTAMP
+		WHERE deleted_at IS NOT NULL AND left(deleted_from, length($1) + 1) = $1 || '/'
+	`
+	if _, err := tx.Exec(query, oldFolder, newFolder); err != nil {
+		return nil, fmt.Errorf("failed to rename folder of trashed documents: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return moved, nil
+}
+
 // SaveConfig saves server configuration
 func (p *PostgresDB) SaveConfig(cfg *config.ServerConfig) error {
 	query := `
@@ -442,15 +631,16 @@ func (p *PostgresDB) GetNewestDocumentsWithPagination(page int, pageSize int) ([
 
 	// Get total count
 	var totalCount int
-	countQuery := `SELECT COUNT(*) FROM documents`
+	countQuery := `SELECT COUNT(*) FROM documents WHERE deleted_at IS NULL`
 	err := p.db.QueryRow(countQuery).Scan(&totalCount)
 	if err != nil {
 		return nil, 0, err
 	}
 
 	// Get paginated documents
-	query := `SELECT id, name, path, ingress_time, folder, hash, ulid, document_type, full_text, url
-	          FROM documents ORDER BY ingress_time DESC LIMIT $1 OFFSET $2`
+	query := `SELECT id, name, path, ingress_time, folder, hash, hash_algorithm, ulid, document_type, full_text, url,
+	          deleted_at, deleted_from, size, page_count, metadata
+	          FROM documents WHERE deleted_at IS NULL ORDER BY ingress_time DESC LIMIT $1 OFFSET $2`
 
 	rows, err := p.db.Query(query, pageSize, offset)
 	if err != nil {
@@ -466,53 +656,6 @@ func (p *PostgresDB) GetNewestDocumentsWithPagination(page int, pageSize int) ([
 	return docs, totalCount, nil
 }
 
-// SearchDocuments performs full-text search using PostgreSQL's native search capabilities
-// Supports both prefix matching and phrase search
-func (p *PostgresDB) SearchDocuments(searchTerm string) ([]Document, error) {
-	// Convert search term to tsquery format
-	// For prefix search: "test" becomes "test:*"
-	// For phrase search: "test document" becomes "test <-> document"
-
-	query := `SELECT id, name, path, ingress_time, folder, hash, ulid, document_type, full_text, url
-	          FROM documents
-	          WHERE full_text_search @@ to_tsquery('english', $1)
-	          ORDER BY ts_rank(full_text_search, to_tsquery('english', $1)) DESC`
-
-	// Format the search term for PostgreSQL full-text search
-	// Add prefix matching support with :*
-	formattedTerm := formatSearchTerm(searchTerm)
-
-	rows, err := p.db.Query(query, formattedTerm)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-
-	return scanDocuments(rows)
-}
-
-// formatSearchTerm converts a search term into PostgreSQL tsquery format
-func formatSearchTerm(term string) string {
-	// Remove special characters that would break tsquery
-	term = strings.TrimSpace(term)
-	if term == "" {
-		return ""
-	}
-
-	// Check if it's a phrase (contains spaces)
-	if strings.Contains(term, " ") {
-		// Phrase search: split into words and join with <->
-		words := strings.Fields(term)
-		for i := range words {
-			words[i] = strings.ToLower(words[i]) + ":*"
-		}
-		return strings.Join(words, " <-> ")
-	}
-
-	// Single word: add prefix matching
-	return strings.ToLower(term) + ":*"
-}
-
 // ReindexSearchDocuments reindexes all documents to populate the full_text_search column
 // Returns the number of documents reindexed
 func (p *PostgresDB) ReindexSearchDocuments() (int, error) {
@@ -0,0 +1,396 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// ErrInvalidQuery is wrapped by every error parsing a search query, the message says what is wrong and where
+var ErrInvalidQuery = errors.New("invalid search query")
+
+// Kinds of searchQuery node
+const (
+	queryText  = iota // a word or quoted phrase matched against the full text
+	queryField        // a field qualifier such as name:invoice
+	queryAnd
+	queryOr
+	queryNot
+)
+
+// searchFields are the field qualifiers a query can use and the column each one matches
+var searchFields = map[string]string{
+	"name":   "d.name",
+	"folder": "d.folder",
+	"type":   "d.document_type",
+}
+
+// searchQuery is a parsed search query.  The grammar is
+//
+//	query   = or
+//	or      = and { "OR" and }
+//	and     = not { ["AND"] not }
+//	not     = ("NOT" | "-") not | primary
+//	primary = "(" or ")" | field ":" value | phrase | word
+//
+// A bare word matches any word starting with it, a quoted phrase matches its words next to each other and exactly
+// unless they end in *.  Operators are only recognised in capitals so "and" and "or" can still be searched for.
+type searchQuery struct {
+	kind     int
+	tsquery  string // queryText: the word or phrase in tsquery syntax, only letters and digits are kept
+	words    string // queryText: the letters and digits of the word or phrase, for fuzzy matching
+	field    string // queryField: key of searchFields
+	value    string // queryField: the value as typed
+	children []*searchQuery
+}
+
+// queryToken is a lexical token of a search query, pos counts characters from 1 for error messages
+type queryToken struct {
+	kind   string // one of the query tokens below
+	text   string
+	quoted bool
+	pos    int
+}
+
+const (
+	tokenWord   = "word"
+	tokenLParen = "("
+	tokenRParen = ")"
+	tokenAnd    = "AND"
+	tokenOr     = "OR"
+	tokenNot    = "NOT"
+	tokenField  = "field"
+)
+
+// parseSearchQuery parses a search query, errors wrap ErrInvalidQuery
+func parseSearchQuery(query string) (*searchQuery, error) {
+	tokens, err := tokenizeSearchQuery(query)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return nil, fmt.Errorf("%w: nothing to search for", ErrInvalidQuery)
+	}
+	parser := &queryParser{tokens: tokens}
+	node, err := parser.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if token := parser.peek(); token != nil {
+		if token.kind == tokenRParen {
+			return nil, fmt.Errorf("%w: ) at character %d has no matching (", ErrInvalidQuery, token.pos)
+		}
+		return nil, fmt.Errorf("%w: unexpected %q at character %d", ErrInvalidQuery, token.text, token.pos)
+	}
+	return node, nil
+}
+
+// tokenizeSearchQuery splits a query into words, quoted phrases, parentheses, operators and field qualifiers
+func tokenizeSearchQuery(query string) ([]queryToken, error) {
+	runes := []rune(query)
+	var tokens []queryToken
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '(' || r == ')':
+			tokens = append(tokens, queryToken{kind: string(r), text: string(r), pos: i + 1})
+			i++
+		case r == '"':
+			phrase, next, err := readPhrase(runes, i)
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, queryToken{kind: tokenWord, text: phrase, quoted: true, pos: i + 1})
+			i = next
+		case r == '-' && i+1 < len(runes) && !unicode.IsSpace(runes[i+1]) && (i == 0 || unicode.IsSpace(runes[i-1]) || runes[i-1] == '('):
+			tokens = append(tokens, queryToken{kind: tokenNot, text: "-", pos: i + 1})
+			i++
+		default:
+			start := i
+			for i < len(runes) && !unicode.IsSpace(runes[i]) && !strings.ContainsRune(`()"`, runes[i]) {
+				i++
+			}
+			word := string(runes[start:i])
+			if word == tokenAnd || word == tokenOr || word == tokenNot {
+				tokens = append(tokens, queryToken{kind: word, text: word, pos: start + 1})
+				continue
+			}
+			field, value, qualified := strings.Cut(word, ":")
+			field = strings.ToLower(field)
+			if _, known := searchFields[field]; !qualified || !known {
+				tokens = append(tokens, queryToken{kind: tokenWord, text: word, pos: start + 1})
+				continue
+			}
+			if value == "" && i < len(runes) && runes[i] == '"' { //name:"March bill"
+				phrase, next, err := readPhrase(runes, i)
+				if err != nil {
+					return nil, err
+				}
+				value, i = phrase, next
+			}
+			if strings.TrimSpace(value) == "" {
+				return nil, fmt.Errorf("%w: %s: at character %d needs a value", ErrInvalidQuery, field, start+1)
+			}
+			tokens = append(tokens, queryToken{kind: tokenField, text: field + ":" + value, pos: start + 1})
+		}
+	}
+	return tokens, nil
+}
+
+// readPhrase reads the quoted phrase starting at runes[start], returning it and the index after the closing quote
+func readPhrase(runes []rune, start int) (string, int, error) {
+	for end := start + 1; end < len(runes); end++ {
+		if runes[end] == '"' {
+			return string(runes[start+1 : end]), end + 1, nil
+		}
+	}
+	return "", 0, fmt.Errorf("%w: quote at character %d is not closed", ErrInvalidQuery, start+1)
+}
+
+// queryParser is a recursive descent parser over the tokens of a query
+type queryParser struct {
+	tokens []queryToken
+	next   int
+}
+
+func (p *queryParser) peek() *queryToken {
+	if p.next < len(p.tokens) {
+		return &p.tokens[p.next]
+	}
+	return nil
+}
+
+func (p *queryParser) parseOr() (*searchQuery, error) {
+	node, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for token := p.peek(); token != nil && token.kind == tokenOr; token = p.peek() {
+		p.next++
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		node = &searchQuery{kind: queryOr, children: []*searchQuery{node, right}}
+	}
+	return node, nil
+}
+
+func (p *queryParser) parseAnd() (*searchQuery, error) {
+	node, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for token := p.peek(); token != nil && token.kind != tokenOr && token.kind != tokenRParen; token = p.peek() {
+		if token.kind == tokenAnd {
+			p.next++
+		}
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		node = &searchQuery{kind: queryAnd, children: []*searchQuery{node, right}}
+	}
+	return node, nil
+}
+
+func (p *queryParser) parseNot() (*searchQuery, error) {
+	if token := p.peek(); token != nil && token.kind == tokenNot {
+		p.next++
+		child, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return &searchQuery{kind: queryNot, children: []*searchQuery{child}}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *queryParser) parsePrimary() (*searchQuery, error) {
+	token := p.peek()
+	if token == nil {
+		previous := p.tokens[len(p.tokens)-1]
+		return nil, fmt.Errorf("%w: %s at character %d is missing what follows it", ErrInvalidQuery, previous.text, previous.pos)
+	}
+	p.next++
+	switch token.kind {
+	case tokenLParen:
+		if inner := p.peek(); inner != nil && inner.kind == tokenRParen {
+			return nil, fmt.Errorf("%w: () at character %d is empty", ErrInvalidQuery, token.pos)
+		}
+		node, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if closing := p.peek(); closing == nil || closing.kind != tokenRParen {
+			return nil, fmt.Errorf("%w: ( at character %d is not closed", ErrInvalidQuery, token.pos)
+		}
+		p.next++
+		return node, nil
+	case tokenField:
+		field, value, _ := strings.Cut(token.text, ":")
+		return &searchQuery{kind: queryField, field: field, value: value}, nil
+	case tokenWord:
+		tsquery := wordTSQuery(token.text, token.quoted)
+		if tsquery == "" {
+			return nil, fmt.Errorf("%w: %q at character %d has no letters or digits to search for", ErrInvalidQuery, token.text, token.pos)
+		}
+		words := strings.Join(strings.FieldsFunc(token.text, notLetterOrDigit), " ")
+		return &searchQuery{kind: queryText, tsquery: tsquery, words: words}, nil
+	case tokenAnd, tokenOr:
+		return nil, fmt.Errorf("%w: %s at character %d is missing what comes before it", ErrInvalidQuery, token.text, token.pos)
+	default:
+		return nil, fmt.Errorf("%w: unexpected %s at character %d", ErrInvalidQuery, token.text, token.pos)
+	}
+}
+
+// wordTSQuery turns a word or phrase into tsquery syntax.  Anything but letters and digits splits words, so nothing
+// typed can become tsquery syntax, and the words are matched next to each other.  A bare word matches as a prefix,
+// the words of a phrase only when they end in *.
+func wordTSQuery(text string, quoted bool) string {
+	var lexemes []string
+	for _, word := range strings.Fields(text) {
+		prefix := !quoted || strings.HasSuffix(word, "*")
+		parts := strings.FieldsFunc(word, notLetterOrDigit)
+		for i, part := range parts {
+			part = strings.ToLower(part)
+			if prefix && i == len(parts)-1 {
+				part += ":*"
+			}
+			lexemes = append(lexemes, part)
+		}
+	}
+	if len(lexemes) > 1 {
+		return "(" + strings.Join(lexemes, " <-> ") + ")"
+	}
+	return strings.Join(lexemes, "")
+}
+
+func notLetterOrDigit(r rune) bool {
+	return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+}
+
+// textOnly reports whether the query only searches the full text, so it compiles into a single tsquery
+func (q *searchQuery) textOnly() bool {
+	switch q.kind {
+	case queryText:
+		return true
+	case queryField:
+		return false
+	}
+	for _, child := range q.children {
+		if !child.textOnly() {
+			return false
+		}
+	}
+	return true
+}
+
+// rankQuery is the full-text part of the query as a tsquery, to rank and highlight with.  Field qualifiers are left
+// out, "" when there is no full-text part at all.
+func (q *searchQuery) rankQuery() string {
+	if q == nil {
+		return ""
+	}
+	switch q.kind {
+	case queryText:
+		return q.tsquery
+	case queryField:
+		return ""
+	case queryNot:
+		if child := q.children[0].rankQuery(); child != "" {
+			return "!" + child
+		}
+		return ""
+	}
+	operator := " & "
+	if q.kind == queryOr {
+		operator = " | "
+	}
+	var parts []string
+	for _, child := range q.children {
+		if part := child.rankQuery(); part != "" {
+			parts = append(parts, part)
+		}
+	}
+	if len(parts) > 1 {
+		return "(" + strings.Join(parts, operator) + ")"
+	}
+	return strings.Join(parts, "")
+}
+
+// fuzzyText is the words the query looks for, those it excludes left out, to score fuzzy matches with.  "" when it
+// has no full-text part.
+func (q *searchQuery) fuzzyText() string {
+	if q == nil {
+		return ""
+	}
+	switch q.kind {
+	case queryText:
+		return q.words
+	case queryField, queryNot:
+		return ""
+	}
+	var parts []string
+	for _, child := range q.children {
+		if part := child.fuzzyText(); part != "" {
+			parts = append(parts, part)
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// condition compiles the query into an SQL condition on documents d.  A query that only searches the full text
+// uses q.query, the query's rankQuery, otherwise each full-text part gets its own tsquery.  When fuzzy the words
+// and phrases only have to be similar to some of the document's text or its name, those after NOT are still
+// excluded only when they are in it.  Values are passed with arg, which returns the placeholder for them.
+func (q *searchQuery) condition(arg func(interface{}) string, fuzzy bool) string {
+	if !fuzzy && q.textOnly() {
+		return "d.full_text_search @@ q.query"
+	}
+	return q.sql(arg, fuzzy)
+}
+
+func (q *searchQuery) sql(arg func(interface{}) string, fuzzy bool) string {
+	switch q.kind {
+	case queryText:
+		if fuzzy {
+			words := arg(q.words)
+			return "(" + words + " <% d.full_text OR " + words + " <% d.name)"
+		}
+		return "d.full_text_search @@ to_tsquery('english', " + arg(q.rankQuery()) + ")"
+	case queryField:
+		column := searchFields[q.field]
+		if q.field == "type" {
+			documentType := strings.ToLower(strings.TrimSpace(q.value))
+			if !strings.HasPrefix(documentType, ".") {
+				documentType = "." + documentType
+			}
+			return column + " = " + arg(documentType)
+		}
+		return column + ` ILIKE ` + arg(likePattern(q.value)) + ` ESCAPE '\'`
+	case queryNot:
+		return "NOT (" + q.children[0].sql(arg, false) + ")"
+	}
+	if !fuzzy && q.textOnly() {
+		return "d.full_text_search @@ to_tsquery('english', " + arg(q.rankQuery()) + ")"
+	}
+	operator := " AND "
+	if q.kind == queryOr {
+		operator = " OR "
+	}
+	parts := make([]string, len(q.children))
+	for i, child := range q.children {
+		parts[i] = child.sql(arg, fuzzy)
+	}
+	return "(" + strings.Join(parts, operator) + ")"
+}
+
+// likePattern matches value anywhere in a column, * in it matching any run of characters
+func likePattern(value string) string {
+	escaped := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`, `*`, `%`).Replace(strings.TrimSpace(value))
+	return "%" + escaped + "%"
+}
@@ -0,0 +1,125 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestParseSearchQuery(t *testing.T) {
+	tests := []struct {
+		query         string
+		wantRank      string
+		wantCondition string
+		wantArgs      []interface{}
+	}{
+		{"invoice", "invoice:*", "d.full_text_search @@ q.query", nil},
+		{"test invoice", "(test:* & invoice:*)", "d.full_text_search @@ q.query", nil},
+		{"invoice OR receipt", "(invoice:* | receipt:*)", "d.full_text_search @@ q.query", nil},
+		{"invoice AND NOT draft", "(invoice:* & !draft:*)", "d.full_text_search @@ q.query", nil},
+		{"invoice -draft", "(invoice:* & !draft:*)", "d.full_text_search @@ q.query", nil},
+		{"(invoice OR receipt) 2024", "((invoice:* | receipt:*) & 2024:*)", "d.full_text_search @@ q.query", nil},
+		{"a OR b c", "(a:* | (b:* & c:*))", "d.full_text_search @@ q.query", nil},
+		{`"electricity bill"`, "(electricity <-> bill)", "d.full_text_search @@ q.query", nil},
+		{`"electric* bill"`, "(electric:* <-> bill)", "d.full_text_search @@ q.query", nil},
+		{"foo&bar", "(foo <-> bar:*)", "d.full_text_search @@ q.query", nil},
+		{"'quote' !bang", "(quote:* & bang:*)", "d.full_text_search @@ q.query", nil},
+		{"Invoice and or", "((invoice:* & and:*) & or:*)", "d.full_text_search @@ q.query", nil},
+		{"e-mail", "(e <-> mail:*)", "d.full_text_search @@ q.query", nil},
+		{"name:bill", "", `d.name ILIKE $1 ESCAPE '\'`, []interface{}{"%bill%"}},
+		{`name:"March bill*"`, "", `d.name ILIKE $1 ESCAPE '\'`, []interface{}{"%March bill%%"}},
+		{"name:100%_off", "", `d.name ILIKE $1 ESCAPE '\'`, []interface{}{`%100\%\_off%`}},
+		{"Folder:bills type:PDF", "", `(d.folder ILIKE $1 ESCAPE '\' AND d.document_type = $2)`, []interface{}{"%bills%", ".pdf"}},
+		{"electricity -type:.png", "electricity:*",
+			"(d.full_text_search @@ to_tsquery('english', $1) AND NOT (d.document_type = $2))", []interface{}{"electricity:*", ".png"}},
+		{"(gas OR electricity) OR name:bill", "(gas:* | electricity:*)",
+			`(d.full_text_search @@ to_tsquery('english', $1) OR d.name ILIKE $2 ESCAPE '\')`, []interface{}{"(gas:* | electricity:*)", "%bill%"}},
+		{"http://example.com", "(http <-> example <-> com:*)", "d.full_text_search @@ q.query", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			parsed, err := parseSearchQuery(tt.query)
+			if err != nil {
+				t.Fatalf("parseSearchQuery() error = %v", err)
+			}
+			if rank := parsed.rankQuery(); rank != tt.wantRank {
+				t.Errorf("rankQuery() = %q, want %q", rank, tt.wantRank)
+			}
+			var args []interface{}
+			condition := parsed.condition(func(value interface{}) string {
+				args = append(args, value)
+				return fmt.Sprintf("$%d", len(args))
+			}, false)
+			if condition != tt.wantCondition || !reflect.DeepEqual(args, tt.wantArgs) {
+				t.Errorf("condition() = %s %v, want %s %v", condition, args, tt.wantCondition, tt.wantArgs)
+			}
+		})
+	}
+}
+
+func TestFuzzySearchQuery(t *testing.T) {
+	tests := []struct {
+		query         string
+		wantText      string
+		wantCondition string
+		wantArgs      []interface{}
+	}{
+		{"lnvoice", "lnvoice", "($1 <% d.full_text OR $1 <% d.name)", []interface{}{"lnvoice"}},
+		{`"Barclavs bank" 2024`, "Barclavs bank 2024",
+			"(($1 <% d.full_text OR $1 <% d.name) AND ($2 <% d.full_text OR $2 <% d.name))", []interface{}{"Barclavs bank", "2024"}},
+		{"lnvoice OR recelpt", "lnvoice recelpt",
+			"(($1 <% d.full_text OR $1 <% d.name) OR ($2 <% d.full_text OR $2 <% d.name))", []interface{}{"lnvoice", "recelpt"}},
+		{"lnvoice -draft type:pdf", "lnvoice",
+			"((($1 <% d.full_text OR $1 <% d.name) AND NOT (d.full_text_search @@ to_tsquery('english', $2))) AND d.document_type = $3)",
+			[]interface{}{"lnvoice", "draft:*", ".pdf"}},
+		{"e-mail", "e mail", "($1 <% d.full_text OR $1 <% d.name)", []interface{}{"e mail"}},
+		{"name:bill", "", `d.name ILIKE $1 ESCAPE '\'`, []interface{}{"%bill%"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			parsed, err := parseSearchQuery(tt.query)
+			if err != nil {
+				t.Fatalf("parseSearchQuery() error = %v", err)
+			}
+			if text := parsed.fuzzyText(); text != tt.wantText {
+				t.Errorf("fuzzyText() = %q, want %q", text, tt.wantText)
+			}
+			var args []interface{}
+			condition := parsed.condition(func(value interface{}) string {
+				args = append(args, value)
+				return fmt.Sprintf("$%d", len(args))
+			}, true)
+			if condition != tt.wantCondition || !reflect.DeepEqual(args, tt.wantArgs) {
+				t.Errorf("condition() = %s %v, want %s %v", condition, args, tt.wantCondition, tt.wantArgs)
+			}
+		})
+	}
+}
+
+func TestParseSearchQueryErrors(t *testing.T) {
+	tests := []struct {
+		query   string
+		wantErr string
+	}{
+		{`"electricity bill`, "invalid search query: quote at character 1 is not closed"},
+		{"(invoice OR receipt", "invalid search query: ( at character 1 is not closed"},
+		{"invoice)", "invalid search query: ) at character 8 has no matching ("},
+		{"()", "invalid search query: () at character 1 is empty"},
+		{"invoice OR", "invalid search query: OR at character 9 is missing what follows it"},
+		{"AND invoice", "invalid search query: AND at character 1 is missing what comes before it"},
+		{"invoice OR OR receipt", "invalid search query: OR at character 12 is missing what comes before it"},
+		{"NOT", "invalid search query: NOT at character 1 is missing what follows it"},
+		{"&&", `invalid search query: "&&" at character 1 has no letters or digits to search for`},
+		{"name: bill", "invalid search query: name: at character 1 needs a value"},
+		{`folder:"bills`, "invalid search query: quote at character 8 is not closed"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			_, err := parseSearchQuery(tt.query)
+			if !errors.Is(err, ErrInvalidQuery) || err.Error() != tt.wantErr {
+				t.Errorf("parseSearchQuery() error = %v, want %s", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,259 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/oklog/ulid/v2"
+)
+
+// SearchResult is a document found by search, with why it matched
+type SearchResult struct {
+	Document
+	Snippet string  // extract of the matching text, matched words wrapped in <mark></mark>
+	Rank    float64 // ts_rank of the document against the query, higher is better
+	Page    int     // best matching page counting from 1, 0 when the document has no per page text
+	// Similarity of the words searched for to the closest text in the document or its name, from 0 to 1, for
+	// fuzzy searches only
+	Similarity float64
+}
+
+// Highlight markers ts_headline wraps matched words in.  The text between them is not HTML escaped, so clients
+// should split on the markers rather than render the snippet as HTML.
+const (
+	SnippetStart = "<mark>"
+	SnippetStop  = "</mark>"
+)
+
+// headlineOptions configures ts_headline, up to three short fragments around the matches
+const headlineOptions = "StartSel=" + SnippetStart + ", StopSel=" + SnippetStop +
+	`, MaxWords=25, MinWords=10, ShortWord=3, MaxFragments=3, FragmentDelimiter=" … "`
+
+// Sort orders for SearchFilter
+const (
+	SortRelevance = "relevance" // best match first, the default with a search term
+	SortNewest    = "newest"    // most recently ingested first, the default without one
+	SortName      = "name"
+)
+
+// SearchFilter narrows a search.  Every field is optional and they all have to match, the zero value finds every
+// document.
+type SearchFilter struct {
+	Term          string     // search query, see searchQuery for its syntax
+	IngressAfter  *time.Time // ingested at or after
+	IngressBefore *time.Time // ingested before
+	DocumentTypes []string   // file extensions with the dot, any of them matches
+	Folder        string     // absolute folder path, documents in it or below it match
+	MinSize       int64      // bytes
+	MaxSize       int64      // bytes, 0 for no limit
+	Sort          string     // one of the Sort constants, empty for the default
+	Fuzzy         bool       // match words similar to those searched for, see searchQuery.condition
+}
+
+// facetLimit is the most values a facet has, the most common ones (or the latest dates) are kept
+const facetLimit = 20
+
+// FacetCount is how many search results have a value
+type FacetCount struct {
+	Value string `json:"value"`
+	Count int    `json:"count"`
+}
+
+// SearchFacets counts search results by document type, folder and the year and month (YYYY-MM) they were ingested
+type SearchFacets struct {
+	DocumentType []FacetCount `json:"documentType"`
+	Folder       []FacetCount `json:"folder"` // the folder a document is directly in
+	Year         []FacetCount `json:"year"`
+	Month        []FacetCount `json:"month"`
+}
+
+// SearchDocuments performs full-text search using PostgreSQL's native search capabilities
+// Supports prefix matching, quoted phrases, AND/OR/NOT and field qualifiers, see searchQuery.
+func (p *PostgresDB) SearchDocuments(searchTerm string) ([]SearchResult, error) {
+	if strings.TrimSpace(searchTerm) == "" { //a filter without a term finds everything, a search for nothing finds nothing
+		return nil, nil
+	}
+	return p.SearchDocumentsFiltered(SearchFilter{Term: searchTerm})
+}
+
+// SearchDocumentsFiltered finds the documents matching every part of filter, leaving out those in the trash.  When
+// the term searches the full text each result carries a highlighted snippet, its rank and, when the document's text
+// was stored page by page, the page that matches best (the snippet is then taken from it).  A term that does not
+// parse returns an error wrapping ErrInvalidQuery.
+func (p *PostgresDB) SearchDocumentsFiltered(filter SearchFilter) ([]SearchResult, error) {
+	query, order, args, err := searchSQL(filter)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := p.db.Query(query+" ORDER BY "+order, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var results []SearchResult
+	for rows.Next() {
+		result, err := scanSearchResult(rows)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, result)
+	}
+	return results, rows.Err()
+}
+
+// SearchDocumentsWithFacets is SearchDocumentsFiltered that also counts the results by facet, in the same query
+func (p *PostgresDB) SearchDocumentsWithFacets(filter SearchFilter) ([]SearchResult, SearchFacets, error) {
+	var facets SearchFacets
+	query, order, args, err := searchSQL(filter)
+	if err != nil {
+		return nil, facets, err
+	}
+	// The facets are an uncorrelated sub query so are counted once, and come back as JSON on every row
+	rows, err := p.db.Query(`WITH matches AS (`+query+`)
+	          SELECT m.*, (SELECT json_build_object(
+	              'documentType', `+facetSQL("document_type", "count DESC, value")+`,
+	              'folder', `+facetSQL("folder", "count DESC, value")+`,
+	              'year', `+facetSQL("to_char(ingress_time, 'YYYY')", "value DESC")+`,
+	              'month', `+facetSQL("to_char(ingress_time, 'YYYY-MM')", "value DESC")+`
+	          )) AS facets
+	          FROM matches m ORDER BY `+order, args...)
+	if err != nil {
+		return nil, facets, err
+	}
+	defer rows.Close()
+
+	var results []SearchResult
+	for rows.Next() {
+		var facetJSON []byte
+		result, err := scanSearchResult(rows, &facetJSON)
+		if err != nil {
+			return nil, facets, err
+		}
+		if results == nil {
+			if err := json.Unmarshal(facetJSON, &facets); err != nil {
+				return nil, facets, fmt.Errorf("failed to parse search facets: %w", err)
+			}
+		}
+		results = append(results, result)
+	}
+	return results, facets, rows.Err()
+}
+
+// facetSQL counts the matches by the value of expression as a JSON array of FacetCount, at most facetLimit of them
+func facetSQL(expression string, order string) string {
+	return fmt.Sprintf(`(SELECT COALESCE(json_agg(json_build_object('value', value, 'count', count)), '[]')
+	              FROM (SELECT %s AS value, count(*) AS count FROM matches GROUP BY 1 ORDER BY %s LIMIT %d) f)`,
+		expression, order, facetLimit)
+}
+
+// searchSQL builds the query selecting the columns of scanSearchResult for the documents matching filter, and the
+// ORDER BY for them in terms of those columns so it can be used on the query or a CTE of it
+func searchSQL(filter SearchFilter) (query string, order string, args []interface{}, err error) {
+	var parsed *searchQuery
+	if term := strings.TrimSpace(filter.Term); term != "" {
+		if parsed, err = parseSearchQuery(term); err != nil {
+			return "", "", nil, err
+		}
+	}
+	arg := func(value interface{}) string {
+		args = append(args, value)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	query = `SELECT d.id, d.name, d.path, d.ingress_time, d.folder, d.hash, d.hash_algorithm, d.ulid, d.document_type,
+	          d.full_text, d.url, d.deleted_at, d.deleted_from, d.size, d.page_count, d.metadata, `
+	conditions := []string{"d.deleted_at IS NULL"}
+	fuzzyText := parsed.fuzzyText()
+	fuzzy := filter.Fuzzy && fuzzyText != ""
+	rankQuery := parsed.rankQuery()
+	if fuzzy {
+		// OCR errors mean the words are not in the full-text index, so score by trigram similarity instead and
+		// take the snippet from the start of the document, highlighting any words that are there exactly
+		words := arg(fuzzyText)
+		query += `0::real AS rank, 0 AS page,
+	          ts_headline('english', COALESCE(d.full_text, ''), plainto_tsquery('english', ` + words + `), ` + arg(headlineOptions) + `) AS snippet,
+	          GREATEST(word_similarity(` + words + `, COALESCE(d.full_text, '')), word_similarity(` + words + `, d.name)) AS similarity
+	          FROM documents d`
+	} else if rankQuery != "" {
+		term := arg(rankQuery)
+		query += `ts_rank(d.full_text_search, q.query) AS rank,
+	          COALESCE(p.page_number, 0) AS page,
+	          ts_headline('english', COALESCE(p.text, d.full_text, ''), q.query, ` + arg(headlineOptions) + `) AS snippet,
+	          0::real AS similarity
+	          FROM documents d
+	          CROSS JOIN (SELECT to_tsquery('english', ` + term + `) AS query) q
+	          LEFT JOIN LATERAL (
+	              SELECT page_number, text FROM document_pages
+	              WHERE document_ulid = d.ulid AND text_search @@ q.query
+	              ORDER BY ts_rank(text_search, q.query) DESC, page_number
+	              LIMIT 1
+	          ) p ON true`
+	} else {
+		query += `0::real AS rank, 0 AS page, '' AS snippet, 0::real AS similarity
+	          FROM documents d`
+	}
+	if parsed != nil {
+		conditions = append(conditions, parsed.condition(arg, fuzzy))
+	}
+	if filter.IngressAfter != nil {
+		conditions = append(conditions, "d.ingress_time >= "+arg(*filter.IngressAfter))
+	}
+	if filter.IngressBefore != nil {
+		conditions = append(conditions, "d.ingress_time < "+arg(*filter.IngressBefore))
+	}
+	if len(filter.DocumentTypes) > 0 {
+		conditions = append(conditions, "d.document_type = ANY("+arg(pq.Array(filter.DocumentTypes))+")")
+	}
+	if filter.Folder != "" {
+		folder := arg(strings.TrimSuffix(filter.Folder, "/"))
+		conditions = append(conditions, "(d.folder = "+folder+" OR left(d.folder, length("+folder+") + 1) = "+folder+" || '/')")
+	}
+	if filter.MinSize > 0 {
+		conditions = append(conditions, "d.size >= "+arg(filter.MinSize))
+	}
+	if filter.MaxSize > 0 {
+		conditions = append(conditions, "d.size > 0 AND d.size <= "+arg(filter.MaxSize)) //0 is a size not read yet
+	}
+	query += " WHERE " + strings.Join(conditions, " AND ")
+
+	sort := filter.Sort
+	if sort == "" || (sort == SortRelevance && rankQuery == "" && !fuzzy) {
+		sort = SortNewest
+		if rankQuery != "" || fuzzy {
+			sort = SortRelevance
+		}
+	}
+	switch sort {
+	case SortRelevance:
+		order = "rank DESC, similarity DESC, ingress_time DESC"
+	case SortName:
+		order = "lower(name), ingress_time DESC"
+	default:
+		order = "ingress_time DESC"
+	}
+	return query, order, args, nil
+}
+
+// scanSearchResult scans a row of searchSQL, followed by any extra columns into extra
+func scanSearchResult(rows *sql.Rows, extra ...interface{}) (SearchResult, error) {
+	result := SearchResult{}
+	var ulidStr string
+	err := rows.Scan(append([]interface{}{
+		&result.StormID, &result.Name, &result.Path, &result.IngressTime,
+		&result.Folder, &result.Hash, &result.HashAlgorithm, &ulidStr, &result.DocumentType,
+		&result.FullText, &result.URL, &result.DeletedAt, &result.DeletedFrom, &result.Size, &result.PageCount, &result.Metadata,
+		&result.Rank, &result.Page, &result.Snippet, &result.Similarity,
+	}, extra...)...)
+	if err != nil {
+		return result, err
+	}
+	if result.ULID, err = ulid.Parse(ulidStr); err != nil {
+		return result, fmt.Errorf("failed to parse ULID: %w", err)
+	}
+	return result, nil
+}
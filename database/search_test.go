@@ -4,6 +4,9 @@ import (
 	"fmt"
 	"log/slog"
 	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
 	"time"
 )
@@ -33,6 +36,7 @@ func TestPostgresFullTextSearch(t *testing.T) {
 	}
 
 	// Add documents to database
+	ulids := make(map[string]string)
 	for i, doc := range testDocs {
 		ulid, err := CalculateUUID(time.Now().Add(time.Duration(i) * time.Millisecond))
 		if err != nil {
@@ -54,6 +58,7 @@ func TestPostgresFullTextSearch(t *testing.T) {
 		if err != nil {
 			t.Fatalf("Failed to save document %s: %v", doc.name, err)
 		}
+		ulids[doc.name] = ulid.String()
 	}
 
 	// Test 1: Single word search
@@ -97,7 +102,34 @@ func TestPostgresFullTextSearch(t *testing.T) {
 		}
 	})
 
-	// Test 3: Prefix search
+	// Test 3: Snippets, rank and the best matching page
+	t.Run("SnippetsAndPages", func(t *testing.T) {
+		err := postgresDB.SaveDocumentPages(ulids["Invoice_Q1.pdf"], []string{"Cover page", "First quarter invoice summary report"})
+		if err != nil {
+			t.Fatalf("Failed to save pages: %v", err)
+		}
+		results, err := postgresDB.SearchDocuments("invoice")
+		if err != nil {
+			t.Fatalf("Search failed: %v", err)
+		}
+		for i, result := range results {
+			if !strings.Contains(result.Snippet, SnippetStart+"invoice"+SnippetStop) {
+				t.Errorf("%s: expected the match to be highlighted, got snippet %q", result.Name, result.Snippet)
+			}
+			if result.Rank <= 0 || (i > 0 && result.Rank > results[i-1].Rank) {
+				t.Errorf("%s: expected results in descending rank, got %v", result.Name, result.Rank)
+			}
+			wantPage := 0
+			if result.Name == "Invoice_Q1.pdf" {
+				wantPage = 2
+			}
+			if result.Page != wantPage {
+				t.Errorf("%s: expected page %d, got %d", result.Name, wantPage, result.Page)
+			}
+		}
+	})
+
+	// Test 4: Prefix search
 	t.Run("PrefixSearch", func(t *testing.T) {
 		results, err := postgresDB.SearchDocuments("invoi")
 		if err != nil {
@@ -109,7 +141,7 @@ func TestPostgresFullTextSearch(t *testing.T) {
 		}
 	})
 
-	// Test 4: No results search
+	// Test 5: No results search
 	t.Run("NoResultsSearch", func(t *testing.T) {
 		results, err := postgresDB.SearchDocuments("xyz123nonexistent")
 		if err != nil {
@@ -121,7 +153,7 @@ func TestPostgresFullTextSearch(t *testing.T) {
 		}
 	})
 
-	// Test 5: Empty search term
+	// Test 6: Empty search term
 	t.Run("EmptySearchTerm", func(t *testing.T) {
 		results, err := postgresDB.SearchDocuments("")
 		if err != nil {
@@ -133,3 +165,128 @@ func TestPostgresFullTextSearch(t *testing.T) {
 		}
 	})
 }
+
+func TestSearchDocumentsFiltered(t *testing.T) {
+	Logger = slog.New(slog.NewTextHandler(os.Stdout, nil))
+	postgresDB, err := SetupPostgresDatabase("")
+	if err != nil {
+		t.Fatalf("Failed to setup ephemeral database: %v", err)
+	}
+	defer postgresDB.Close()
+
+	day := func(d int) time.Time { return time.Date(2024, 1, d, 12, 0, 0, 0, time.UTC) }
+	testDocs := []struct {
+		name    string
+		folder  string
+		content string
+		ingress time.Time
+		size    int64
+	}{
+		{"Bill.pdf", "/docs/bills", "Electricity bill for January", day(3), 5000},
+		{"Scan.png", "/docs/bills/2024", "Gas bill scanned", day(10), 250000},
+		{"Notes.txt", "/docs/notes", "Notes about the electricity meter", day(20), 0}, //size not read yet
+		{"Archive.pdf", "/docs/billsarchive", "Old billing archive", day(25), 8000},
+	}
+	for i, doc := range testDocs {
+		id, _ := CalculateUUID(doc.ingress)
+		err := postgresDB.SaveDocument(&Document{
+			Name: doc.name, Path: doc.folder + "/" + doc.name, Folder: doc.folder, Hash: fmt.Sprintf("hash%d", i),
+			FullText: doc.content, IngressTime: doc.ingress, DocumentType: strings.ToLower(filepath.Ext(doc.name)),
+			ULID: id, Size: doc.size,
+		})
+		if err != nil {
+			t.Fatalf("Failed to save document %s: %v", doc.name, err)
+		}
+	}
+	timePtr := func(t time.Time) *time.Time { return &t }
+
+	tests := []struct {
+		name   string
+		filter SearchFilter
+		want   []string
+	}{
+		{"No filter finds everything newest first", SearchFilter{}, []string{"Archive.pdf", "Notes.txt", "Scan.png", "Bill.pdf"}},
+		{"Date range", SearchFilter{IngressAfter: timePtr(day(5)), IngressBefore: timePtr(day(21))}, []string{"Notes.txt", "Scan.png"}},
+		{"Document type", SearchFilter{DocumentTypes: []string{".pdf"}}, []string{"Archive.pdf", "Bill.pdf"}},
+		{"Several document types", SearchFilter{DocumentTypes: []string{".png", ".txt"}, Sort: SortName}, []string{"Notes.txt", "Scan.png"}},
+		{"Folder includes sub folders but not similar names", SearchFilter{Folder: "/docs/bills"}, []string{"Scan.png", "Bill.pdf"}},
+		{"Size range", SearchFilter{MinSize: 1000, MaxSize: 10000}, []string{"Archive.pdf", "Bill.pdf"}},
+		{"Maximum size leaves out sizes not read yet", SearchFilter{MaxSize: 6000}, []string{"Bill.pdf"}},
+		{"Term combined with filters", SearchFilter{Term: "electricity", DocumentTypes: []string{".pdf"}}, []string{"Bill.pdf"}},
+		{"Term sorted by name", SearchFilter{Term: "bill", Sort: SortName}, []string{"Archive.pdf", "Bill.pdf", "Scan.png"}},
+		{"Nothing matches", SearchFilter{Term: "electricity", MinSize: 1000000}, nil},
+		{"Either word", SearchFilter{Term: "electricity OR gas", Sort: SortName}, []string{"Bill.pdf", "Notes.txt", "Scan.png"}},
+		{"Excluded word", SearchFilter{Term: "bill -gas", Sort: SortName}, []string{"Archive.pdf", "Bill.pdf"}},
+		{"Exact phrase", SearchFilter{Term: `"electricity bill"`}, []string{"Bill.pdf"}},
+		{"Name qualifier without full text", SearchFilter{Term: "name:scan"}, []string{"Scan.png"}},
+		{"Qualifiers combined with OR", SearchFilter{Term: "folder:notes OR type:png", Sort: SortName}, []string{"Notes.txt", "Scan.png"}},
+		{"Misspelt word only found fuzzy", SearchFilter{Term: "electrcity"}, nil},
+		{"Fuzzy finds the misspelt word", SearchFilter{Term: "electrcity", Fuzzy: true, Sort: SortName}, []string{"Bill.pdf", "Notes.txt"}},
+		{"Fuzzy needs every word", SearchFilter{Term: "electrcity meter", Fuzzy: true}, []string{"Notes.txt"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results, err := postgresDB.SearchDocumentsFiltered(tt.filter)
+			if err != nil {
+				t.Fatalf("Search failed: %v", err)
+			}
+			var got []string
+			for _, result := range results {
+				got = append(got, result.Name)
+				if (tt.filter.Term == "" || strings.Contains(tt.filter.Term, ":")) && (result.Snippet != "" || result.Rank != 0) {
+					t.Errorf("%s: expected no snippet or rank without a term, got %q %v", result.Name, result.Snippet, result.Rank)
+				}
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("Got %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("Facets are counted over every result", func(t *testing.T) {
+		results, facets, err := postgresDB.SearchDocumentsWithFacets(SearchFilter{Term: "bill", Sort: SortName})
+		if err != nil {
+			t.Fatalf("Search failed: %v", err)
+		}
+		if len(results) != 3 || results[0].Name != "Archive.pdf" {
+			t.Errorf("Expected the same results as without facets, got %d starting %v", len(results), results)
+		}
+		want := SearchFacets{
+			DocumentType: []FacetCount{{".pdf", 2}, {".png", 1}},
+			Folder:       []FacetCount{{"/docs/bills", 1}, {"/docs/bills/2024", 1}, {"/docs/billsarchive", 1}},
+			Year:         []FacetCount{{"2024", 3}},
+			Month:        []FacetCount{{"2024-01", 3}},
+		}
+		if !reflect.DeepEqual(facets, want) {
+			t.Errorf("Got facets %+v, want %+v", facets, want)
+		}
+	})
+
+	t.Run("Fuzzy results are scored by similarity", func(t *testing.T) {
+		results, err := postgresDB.SearchDocumentsFiltered(SearchFilter{Term: "electrcity", Fuzzy: true})
+		if err != nil {
+			t.Fatalf("Search failed: %v", err)
+		}
+		for i, result := range results {
+			if result.Similarity <= 0 || result.Similarity > 1 || (i > 0 && result.Similarity > results[i-1].Similarity) {
+				t.Errorf("%s: expected results in descending similarity, got %v", result.Name, result.Similarity)
+			}
+			if result.Rank != 0 {
+				t.Errorf("%s: expected no rank for a fuzzy match, got %v", result.Name, result.Rank)
+			}
+		}
+		exact, _ := postgresDB.SearchDocumentsFiltered(SearchFilter{Term: "electricity"})
+		for _, result := range exact {
+			if result.Similarity != 0 {
+				t.Errorf("%s: expected no similarity for an exact match, got %v", result.Name, result.Similarity)
+			}
+		}
+	})
+
+	t.Run("No results have no facets", func(t *testing.T) {
+		results, facets, err := postgresDB.SearchDocumentsWithFacets(SearchFilter{Term: "xyz123nonexistent"})
+		if err != nil || len(results) != 0 || len(facets.DocumentType) != 0 {
+			t.Errorf("Expected nothing, got %v %+v %v", results, facets, err)
+		}
+	})
+}
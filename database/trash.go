@@ -0,0 +1,106 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// TrashDocument marks a document as deleted now that its file has been moved to trashPath, remembering where it was
+// so it can be restored.  Returns sql.ErrNoRows if there is no such document outside the trash.
+func (p *PostgresDB) TrashDocument(ulidStr string, trashPath string) error {
+	query := `
+		UPDATE documents SET deleted_at = CURRENT_TIMESTAMP, deleted_from = path, path = $1, updated_at = CURRENT_TIMESTAMP
+		WHERE ulid = $2 AND deleted_at IS NULL
+	`
+	return expectOneRow(p.db.Exec(query, trashPath, ulidStr))
+}
+
+// TrashDocuments marks every document in trashPaths, a map of ULID to the path its file was moved to in the trash,
+// as deleted in one transaction.  Nothing is marked if one of them is missing or already in the trash.
+func (p *PostgresDB) TrashDocuments(trashPaths map[string]string) error {
+	tx, err := p.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `
+		UPDATE documents SET deleted_at = CURRENT_TIMESTAMP, deleted_from = path, path = $1, updated_at = CURRENT_TIMESTAMP
+		WHERE ulid = $2 AND deleted_at IS NULL
+	`
+	for ulidStr, trashPath := range trashPaths {
+		if err := expectOneRow(tx.Exec(query, trashPath, ulidStr)); err != nil {
+			return fmt.Errorf("failed to trash document %s: %w", ulidStr, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// RestoreDocument takes a document out of the trash now that its file has been moved back to path.  Returns
+// sql.ErrNoRows if there is no such document in the trash.
+func (p *PostgresDB) RestoreDocument(ulidStr string, path string) error {
+	query := `
+		UPDATE documents SET deleted_at = NULL, deleted_from = '', path = $1, updated_at = CURRENT_TIMESTAMP
+		WHERE ulid = $2 AND deleted_at IS NOT NULL
+	`
+	return expectOneRow(p.db.Exec(query, path, ulidStr))
+}
+
+// GetTrashedDocuments retrieves the documents in the trash, most recently deleted first
+func (p *PostgresDB) GetTrashedDocuments() ([]Document, error) {
+	query := `SELECT id, name, path, ingress_time, folder, hash, hash_algorithm, ulid, document_type, full_text, url,
+	          deleted_at, deleted_from, size, page_count, metadata
+	          FROM documents WHERE deleted_at IS NOT NULL ORDER BY deleted_at DESC, id DESC`
+
+	rows, err := p.db.Query(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query trash: %w", err)
+	}
+	defer rows.Close()
+
+	documents, err := scanDocuments(rows)
+	if err != nil {
+		return nil, err
+	}
+	if documents == nil {
+		documents = make([]Document, 0)
+	}
+	return documents, nil
+}
+
+// GetDocumentsInFolder retrieves the documents outside the trash in a folder or any folder below it
+func (p *PostgresDB) GetDocumentsInFolder(folder string) ([]Document, error) {
+	// Prefixes are compared with left() rather than LIKE so _ and % in folder names are not wildcards
+	query := `SELECT id, name, path, ingress_time, folder, hash, hash_algorithm, ulid, document_type, full_text, url,
+	          deleted_at, deleted_from, size, page_count, metadata
+	          FROM documents
+	          WHERE (folder = $1 OR left(folder, length($1) + 1) = $1 || '/') AND deleted_at IS NULL
+	          ORDER BY path`
+
+	rows, err := p.db.Query(query, folder)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query folder: %w", err)
+	}
+	defer rows.Close()
+
+	return scanDocuments(rows)
+}
+
+// expectOneRow turns an update that matched no rows into sql.ErrNoRows
+func expectOneRow(result sql.Result, err error) error {
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
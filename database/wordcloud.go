@@ -148,8 +148,13 @@ func (p *PostgresDB) RecalculateAllWordFrequencies() error {
 	tokenizer := NewWordTokenizer()
 	globalFrequencies := make(map[string]int)
 
-	// Process all documents
+	// Process all documents, leaving out those in the trash
+	processed := 0
 	for _, doc := range docs {
+		if doc.DeletedAt != nil {
+			continue
+		}
+		processed++
 		combinedText := doc.FullText + " " + doc.Name
 		frequencies := tokenizer.TokenizeAndCount(combinedText)
 
@@ -199,12 +204,12 @@ func (p *PostgresDB) RecalculateAllWordFrequencies() error {
 			updated_at = CURRENT_TIMESTAMP
 		WHERE id = 1
 	`
-	_, err = p.db.Exec(updateMetadata, len(docs), len(globalFrequencies))
+	_, err = p.db.Exec(updateMetadata, processed, len(globalFrequencies))
 	if err != nil {
 		return fmt.Errorf("failed to update metadata: %w", err)
 	}
 
-	Logger.Info("Word cloud recalculation completed", "docs", len(docs), "words", len(globalFrequencies))
+	Logger.Info("Word cloud recalculation completed", "docs", processed, "words", len(globalFrequencies))
 	return nil
 }
 
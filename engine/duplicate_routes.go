@@ -0,0 +1,41 @@
+package engine
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+)
+
+// ListDuplicates returns the most recent files found to duplicate a stored document, what was done with each and
+// the number handled by each action
+func (serverHandler *ServerHandler) ListDuplicates(c echo.Context) error {
+	limit := 100
+	if limitParam := c.QueryParam("limit"); limitParam != "" {
+		if l, err := strconv.Atoi(limitParam); err == nil && l > 0 && l <= 1000 {
+			limit = l
+		}
+	}
+
+	duplicates, err := serverHandler.DB.ListDuplicates(limit)
+	if err != nil {
+		Logger.Error("Failed to list duplicates", "error", err)
+		return c.JSON(http.StatusInternalServerError, map[string]interface{}{
+			"error": "Failed to retrieve duplicates",
+		})
+	}
+	counts, err := serverHandler.DB.CountDuplicates()
+	if err != nil {
+		Logger.Error("Failed to count duplicates", "error", err)
+		return c.JSON(http.StatusInternalServerError, map[string]interface{}{
+			"error": "Failed to retrieve duplicates",
+		})
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"duplicates": duplicates,
+		"counts":     counts,
+		"count":      len(duplicates),
+		"policy":     serverHandler.ServerConfig.DuplicatePolicy,
+	})
+}
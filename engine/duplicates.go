@@ -0,0 +1,96 @@
+package engine
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/drummonds/goEDMS/config"
+	"github.com/drummonds/goEDMS/database"
+)
+
+// handleDuplicate applies the duplicate policy to a file whose content matches a stored document.  A versioned
+// duplicate is returned as a new document to be stored as usual, otherwise the file is moved to the duplicates
+// folder or deleted and the duplicate error is returned.
+func (serverHandler *ServerHandler) handleDuplicate(duplicate *database.DuplicateError, extraction *Extraction) (*database.Document, error) {
+	record := &database.DuplicateRecord{
+		Hash:         duplicate.Hash,
+		FilePath:     duplicate.FilePath,
+		ExistingULID: duplicate.Existing.ULID.String(),
+	}
+	switch serverHandler.ServerConfig.DuplicatePolicy { //not stored in the database so read from the live config
+	case config.DuplicatePolicyVersion:
+		version, err := serverHandler.DB.NextDocumentVersion(record.ExistingULID)
+		if err != nil {
+			return nil, err
+		}
+		document, err := database.AddDocumentVersion(duplicate, version, extraction.FullText, serverHandler.DB)
+		if err != nil {
+			return nil, err
+		}
+		record.Action = database.DuplicateVersioned
+		record.VersionULID = document.ULID.String()
+		record.Version = version
+		serverHandler.recordDuplicate(record)
+		Logger.Info("Storing duplicate as a new version", "filePath", duplicate.FilePath, "existing", record.ExistingULID, "version", version)
+		return document, nil
+	case config.DuplicatePolicySkip:
+		if err := os.Remove(duplicate.FilePath); err != nil {
+			return nil, fmt.Errorf("unable to delete duplicate: %w", err)
+		}
+		record.Action = database.DuplicateSkipped
+		Logger.Info("Deleted duplicate", "filePath", duplicate.FilePath, "existing", record.ExistingULID)
+	default:
+		folder := serverHandler.ServerConfig.DuplicateFolder
+		if err := os.MkdirAll(folder, os.ModePerm); err != nil {
+			return nil, err
+		}
+		movedTo := uniqueFilePath(folder, filepath.Base(duplicate.FilePath))
+		if err := os.Rename(duplicate.FilePath, movedTo); err != nil {
+			return nil, fmt.Errorf("unable to move duplicate to %s: %w", folder, err)
+		}
+		record.Action = database.DuplicateRejected
+		record.MovedTo = movedTo
+		Logger.Info("Moved duplicate to duplicates folder", "filePath", duplicate.FilePath, "movedTo", movedTo, "existing", record.ExistingULID)
+	}
+	serverHandler.recordDuplicate(record)
+	return nil, duplicate
+}
+
+// rejectDuplicate checks for a duplicate before any text is extracted, so a duplicate scan is not OCR'd only to be
+// thrown away.  Under the reject and skip policies the duplicate is dealt with and its error returned.  A versioned
+// duplicate needs the extracted text so it is left to addDocumentToDatabase, which also catches copies of the same
+// file that were being extracted at the same time.
+func (serverHandler *ServerHandler) rejectDuplicate(filePath string) error {
+	if serverHandler.ServerConfig.DuplicatePolicy == config.DuplicatePolicyVersion {
+		return nil
+	}
+	err := database.CheckDuplicateDocument(filePath, serverHandler.DB)
+	var duplicate *database.DuplicateError
+	if !errors.As(err, &duplicate) {
+		return err
+	}
+	_, err = serverHandler.handleDuplicate(duplicate, nil)
+	return err
+}
+
+// recordDuplicate adds a duplicate to the report, the file has already been dealt with so a failure is only logged
+func (serverHandler *ServerHandler) recordDuplicate(record *database.DuplicateRecord) {
+	if err := serverHandler.DB.RecordDuplicate(record); err != nil {
+		Logger.Error("Unable to record duplicate", "filePath", record.FilePath, "error", err)
+	}
+}
+
+// uniqueFilePath returns the path for name in folder, adding _1, _2 ... before the extension if it is taken
+func uniqueFilePath(folder string, name string) string {
+	path := filepath.Join(folder, name)
+	ext := filepath.Ext(name)
+	for i := 1; ; i++ {
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			return path
+		}
+		path = filepath.Join(folder, fmt.Sprintf("%s_%d%s", strings.TrimSuffix(name, ext), i, ext))
+	}
+}
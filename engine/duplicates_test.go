@@ -0,0 +1,22 @@
+package engine
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestUniqueFilePath(t *testing.T) {
+	folder := t.TempDir()
+	if got, want := uniqueFilePath(folder, "scan.pdf"), filepath.Join(folder, "scan.pdf"); got != want {
+		t.Errorf("Expected %s for a free name, got %s", want, got)
+	}
+	for _, name := range []string{"scan.pdf", "scan_1.pdf"} {
+		if err := os.WriteFile(filepath.Join(folder, name), []byte("taken"), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+	if got, want := uniqueFilePath(folder, "scan.pdf"), filepath.Join(folder, "scan_2.pdf"); got != want {
+		t.Errorf("Expected %s once scan.pdf and scan_1.pdf are taken, got %s", want, got)
+	}
+}
@@ -4,21 +4,29 @@ import (
 	"bytes"
 	"errors"
 	"fmt"
-	"image"
-	"image/png"
 	"io"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
-	"github.com/disintegration/imaging"
 	"github.com/drummonds/goEDMS/config"
 	"github.com/drummonds/goEDMS/database"
 	"github.com/gen2brain/go-fitz"
 	"github.com/ledongthuc/pdf"
 )
 
+// ingressRunning stops a second ingress run (startup, cron or manual) starting while one is still in progress
+var ingressRunning atomic.Bool
+
+// storeMu serialises adding documents to the database and document folder.  Extraction runs in parallel but the
+// duplicate check, the copy into the document folder and route registration must not interleave.
+var storeMu sync.Mutex
+
 func (serverHandler *ServerHandler) ingressJobFunc(serverConfig config.ServerConfig, db database.DBInterface) {
 	// Add panic recovery to prevent entire application crash
 	defer func() {
@@ -26,6 +34,11 @@ func (serverHandler *ServerHandler) ingressJobFunc(serverConfig config.ServerCon
 			Logger.Error("Panic recovered in ingress job", "panic", r)
 		}
 	}()
+	if !ingressRunning.CompareAndSwap(false, true) {
+		Logger.Info("Ingress job already running, skipping this run")
+		return
+	}
+	defer ingressRunning.Store(false)
 
 	serverConfig, err := database.FetchConfigFromDB(db)
 	if err != nil {
@@ -40,8 +53,9 @@ func (serverHandler *ServerHandler) ingressJobFunc(serverConfig config.ServerCon
 	if err != nil {
 		Logger.Error("Error reading files in from ingress", "error", err)
 	}
+	quietPeriod := time.Duration(serverHandler.ServerConfig.IngressQuietPeriod) * time.Second
+	var ingressFiles []string
 	for _, filePath := range ingressPath {
-		Logger.Debug("Starting processing for file", "filePath", filePath)
 		fileStats, err := os.Stat(filePath)
 		if err != nil {
 			Logger.Warn("Unable to get information for file, won't process", "filePath", filePath, "error", err)
@@ -55,83 +69,241 @@ func (serverHandler *ServerHandler) ingressJobFunc(serverConfig config.ServerCon
 			Logger.Info("Skipping ingress Folder", "filePath", filePath)
 			continue
 		}
-		serverHandler.ingressDocument(filePath, "ingress")
-	}
+		if ingressWatchActive.Load() && time.Since(fileStats.ModTime()) < quietPeriod { //may still be being written, the watcher will ingest it once it settles
+			Logger.Debug("Skipping recently modified file, left to the ingress watcher", "filePath", filePath)
+			continue
+		}
+		if !claimIngressFile(filePath) { //already being ingested by the watcher, an upload or a retry
+			Logger.Debug("File already being ingested", "filePath", filePath)
+			continue
+		}
+		ingressFiles = append(ingressFiles, filePath)
+	}
+	jobs := make(map[string]*database.IngestJob, len(ingressFiles))
+	for _, filePath := range ingressFiles { //queue the whole batch up front so it shows in the job list
+		jobs[filePath] = serverHandler.queueIngestJob(filePath, "ingress")
+	}
+	workers := serverHandler.ServerConfig.IngressWorkers //not stored in the database so read from the live config
+	Logger.Info("Processing ingress files", "count", len(ingressFiles), "workers", workers)
+	runIngressWorkers(ingressFiles, workers, func(filePath string) {
+		defer releaseIngressFile(filePath)
+		serverHandler.acquireIngestSlot()
+		defer releaseIngestSlot()
+		Logger.Debug("Starting processing for file", "filePath", filePath)
+		serverHandler.processIngestJob(jobs[filePath], filePath, "ingress")
+	})
 	deleteEmptyIngressFolders(serverHandler.ServerConfig.IngressPath) //after ingress clean empty folders
 }
 
-func (serverHandler *ServerHandler) ingressDocument(filePath string, source string) { //source is either from ingress folder or from upload
+// ingestSlots is shared by the scheduled poll and the ingress watcher so together they never ingest more than
+// INGRESS_WORKERS files at once, it is sized from the config the first time a file is ingested
+var (
+	ingestSlots     chan struct{}
+	ingestSlotsOnce sync.Once
+)
+
+// acquireIngestSlot blocks until another file may be ingested, the slot is handed back with releaseIngestSlot
+func (serverHandler *ServerHandler) acquireIngestSlot() {
+	ingestSlotsOnce.Do(func() {
+		workers := serverHandler.ServerConfig.IngressWorkers
+		if workers < 1 {
+			workers = 1
+		}
+		ingestSlots = make(chan struct{}, workers)
+	})
+	ingestSlots <- struct{}{}
+}
+
+func releaseIngestSlot() {
+	<-ingestSlots
+}
+
+// runIngressWorkers calls process for every file using at most workers goroutines and returns when all are done
+func runIngressWorkers(files []string, workers int, process func(filePath string)) {
+	if workers < 1 {
+		workers = 1
+	}
+	if workers > len(files) {
+		workers = len(files)
+	}
+	queue := make(chan string)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for filePath := range queue {
+				process(filePath)
+			}
+		}()
+	}
+	for _, filePath := range files {
+		queue <- filePath
+	}
+	close(queue)
+	wg.Wait()
+}
+
+// ingressDocument records an ingest job for the file and processes it straight away, source is either ingress or upload
+func (serverHandler *ServerHandler) ingressDocument(filePath string, source string) (*database.Document, error) {
+	return serverHandler.processIngestJob(serverHandler.queueIngestJob(filePath, source), filePath, source)
+}
+
+// processIngestJob extracts and stores one file, moving its job through the ingest states.  job is nil if it could
+// not be recorded, the file is still ingested but its progress is not tracked.  Files that can't be read are
+// quarantined once they have failed too often, failures storing the document leave the file in ingress.
+func (serverHandler *ServerHandler) processIngestJob(job *database.IngestJob, filePath string, source string) (document *database.Document, err error) {
 	// Add panic recovery to prevent one bad document from crashing the entire ingress job
 	defer func() {
 		if r := recover(); r != nil {
 			Logger.Error("Panic recovered while processing document", "filePath", filePath, "panic", r)
+			err = fmt.Errorf("panic while processing document: %v", r)
+			serverHandler.ingestFailed(job, filePath, err)
 		}
 	}()
+	serverHandler.updateIngestJob(job, database.IngestJobExtracting, nil)
 
-	switch filepath.Ext(filePath) {
-	case ".pdf":
-		fullText, err := pdfProcessing(filePath)
-		if err != nil {
-			fullText, err = serverHandler.convertToImage(filePath)
-			if err != nil {
-				Logger.Error("OCR Processing failed on file so not added to database", "filePath", filePath, "error", err)
-				return
-			}
-		}
-		// Check if fullText is nil before dereferencing
-		if fullText == nil {
-			Logger.Error("PDF processing returned nil text, skipping document", "filePath", filePath)
-			return
-		}
-		serverHandler.addDocumentToDatabase(filePath, *fullText, source)
-
-	case ".txt", ".rtf":
-		textProcessing(filePath)
-	case ".doc", ".docx", ".odf":
-		wordDocProcessing(filePath)
-	case ".tiff", ".jpg", ".jpeg", ".png":
-		fullText, err := serverHandler.ocrProcessing(filePath)
-		if err != nil {
-			Logger.Error("OCR Processing failed on file", "filePath", filePath, "error", err)
-			return
-		}
-		// Check if fullText is nil before dereferencing
-		if fullText == nil {
-			Logger.Error("OCR processing returned nil text, skipping document", "filePath", filePath)
-			return
+	extractor, err := ExtractorFor(filePath)
+	if err != nil {
+		Logger.Warn("Invalid file type", "file", filepath.Base(filePath), "error", err)
+		serverHandler.ingestFailed(job, filePath, err)
+		return nil, err
+	}
+	// Check for duplicates before doing all the processing
+	if err = serverHandler.rejectDuplicate(filePath); err != nil {
+		return nil, serverHandler.ingestNotStored(job, err)
+	}
+	extraction, err := extractor.Extract(&IngestTask{Server: serverHandler, FilePath: filePath, job: job})
+	if err != nil {
+		Logger.Error("Text extraction failed on file so not added to database", "filePath", filePath, "extractor", extractor.Name(), "error", err)
+		serverHandler.ingestFailed(job, filePath, err)
+		return nil, err
+	}
+	// Check if extraction is nil before dereferencing
+	if extraction == nil {
+		err = fmt.Errorf("extractor %s returned no result", extractor.Name())
+		Logger.Error("Extractor returned no result, skipping document", "filePath", filePath, "extractor", extractor.Name())
+		serverHandler.ingestFailed(job, filePath, err)
+		return nil, err
+	}
+	Logger.Debug("Extracted document", "filePath", filePath, "extractor", extractor.Name(), "pages", extraction.PageCount, "metadata", extraction.Metadata)
+	document, err = serverHandler.addDocumentToDatabase(filePath, extraction, source)
+	if err != nil {
+		return nil, serverHandler.ingestNotStored(job, err)
+	}
+	if job != nil {
+		job.DocumentULID = document.ULID.String()
+	}
+	serverHandler.updateIngestJob(job, database.IngestJobStored, nil)
+	serverHandler.generateThumbnail(document)
+	return document, nil
+}
+
+// ingestNotStored marks the job as a duplicate or as failed for the error that stopped the document being stored,
+// returning the error
+func (serverHandler *ServerHandler) ingestNotStored(job *database.IngestJob, err error) error {
+	var duplicate *database.DuplicateError
+	if errors.As(err, &duplicate) {
+		if job != nil {
+			job.DocumentULID = duplicate.Existing.ULID.String()
 		}
-		serverHandler.addDocumentToDatabase(filePath, *fullText, source)
-	default:
-		Logger.Warn("Invalid file type", "file", filepath.Base((filePath)))
+		serverHandler.updateIngestJob(job, database.IngestJobDuplicate, err)
+		return err
 	}
+	serverHandler.updateIngestJob(job, database.IngestJobFailed, err)
+	return err
 }
 
-func (serverHandler *ServerHandler) addDocumentToDatabase(filePath string, fullText string, source string) error {
-	document, err := database.AddNewDocument(filePath, fullText, serverHandler.DB) //Adds everything but the URL, that is added afterwards
+// queueIngestJob records the file as waiting for ingestion, returning nil if the job could not be recorded
+func (serverHandler *ServerHandler) queueIngestJob(filePath string, source string) *database.IngestJob {
+	job, err := serverHandler.DB.QueueIngestJob(filePath, source)
 	if err != nil {
+		Logger.Error("Unable to record ingest job, progress will not be tracked", "filePath", filePath, "error", err)
+		return nil
+	}
+	return job
+}
+
+// updateIngestJob moves a job to a new state, recording attempts, timings and the error that failed it.  Each
+// finished attempt is added to the job history.
+func (serverHandler *ServerHandler) updateIngestJob(job *database.IngestJob, state string, jobErr error) {
+	if job == nil {
+		return
+	}
+	now := time.Now()
+	job.State = state
+	finished := false
+	switch state {
+	case database.IngestJobExtracting:
+		job.Attempts++
+		job.Error = ""
+		job.StartedAt = &now
+		job.FinishedAt = nil
+	case database.IngestJobStored, database.IngestJobFailed, database.IngestJobDuplicate:
+		job.FinishedAt = &now
+		finished = true
+	}
+	if jobErr != nil {
+		job.Error = jobErr.Error()
+	}
+	if finished && job.StartedAt != nil {
+		job.History = append(job.History, database.IngestAttempt{
+			Attempt: job.Attempts, StartedAt: *job.StartedAt, FinishedAt: now, Error: job.Error,
+		})
+	}
+	if err := serverHandler.DB.UpdateIngestJob(job); err != nil {
+		Logger.Error("Unable to update ingest job", "id", job.ID, "state", state, "error", err)
+	}
+}
+
+func (serverHandler *ServerHandler) addDocumentToDatabase(filePath string, extraction *Extraction, source string) (*database.Document, error) {
+	storeMu.Lock()
+	defer storeMu.Unlock()
+	document, err := database.AddNewDocument(filePath, extraction.FullText, serverHandler.DB) //Adds everything but the URL, that is added afterwards
+	var duplicate *database.DuplicateError
+	if errors.As(err, &duplicate) {
+		document, err = serverHandler.handleDuplicate(duplicate, extraction)
+		if err != nil {
+			return nil, err
+		}
+	} else if err != nil {
 		Logger.Error("Failed to add document to database", "document", document, "error", err) //TODO: Handle document that we were unable to add
-		return err
+		return nil, err
+	}
+	if len(extraction.Pages) > 0 {
+		err = serverHandler.DB.SaveDocumentPages(document.ULID.String(), extraction.Pages)
+		if err != nil { //the full text is already stored so the document is still searchable, just without page numbers
+			Logger.Error("Unable to save document pages", "ulid", document.ULID.String(), "error", err)
+		}
+	}
+	if extraction.PageCount > 0 || len(extraction.Metadata) > 0 {
+		err = serverHandler.DB.UpdateDocumentProperties(document.ULID.String(), extraction.PageCount, extraction.Metadata)
+		if err != nil { //only informational, the document is stored without them
+			Logger.Error("Unable to save document properties", "ulid", document.ULID.String(), "error", err)
+		} else {
+			document.PageCount = extraction.PageCount
+			document.Metadata = extraction.Metadata
+		}
 	}
 	documentURL := "/document/view/" + document.ULID.String()
-	serverHandler.Echo.File(documentURL, document.Path)                                                 //Generating a direct URL to document so it is live immediately after add
 	_, err = database.UpdateDocumentField(document.ULID.String(), "URL", documentURL, serverHandler.DB) //updating the database with the new file location
 	if err != nil {
 		Logger.Error("Unable to update document field", "field", "Path", "error", err)
-		return err
+		return nil, err
 	}
-	err = ingressCopyDocument(filePath, serverHandler.ServerConfig)
+	err = ingressCopyDocument(filePath, *document)
 	if err != nil {
 		Logger.Error("Error moving ingress file to new location", "filePath", filePath, "error", err)
-		return err
+		return nil, err
 	}
 	if source == "ingress" { //if file was ingressed need to handle the original, if uploaded no problem
 		err := ingressCleanup(filePath, *document, serverHandler.ServerConfig, serverHandler.DB)
 		if err != nil {
-			return err
+			return nil, err
 		}
 	}
 	Logger.Info("Added file to the database", "filePath", filePath)
-	return nil
+	return document, nil
 }
 
 func deleteEmptyIngressFolders(path string) {
@@ -181,25 +353,14 @@ func DeleteFile(filePath string) error {
 	return nil
 } */
 
-// ingressCopyDocument copies the document to document storage location
-func ingressCopyDocument(filePath string, serverConfig config.ServerConfig) error {
+// ingressCopyDocument copies the file to the path the document was given in document storage
+func ingressCopyDocument(filePath string, document database.Document) error {
 	srcFile, err := os.ReadFile(filePath)
 	if err != nil {
 		return err
 	}
-	var newFilePath string
-	if serverConfig.IngressPreserve == false { //if we are not saving the folder structure just read each file in with new path
-		newFilePath = filepath.ToSlash(serverConfig.NewDocumentFolder + "/" + filepath.Base(filePath))
-	} else { //If we ARE preserving ingress structure, create a new full path by creating a relative path and joining it to the
-		basePath := serverConfig.IngressPath
-		newFileNameRoot := serverConfig.DocumentPath
-		relativePath, err := filepath.Rel(basePath, filePath)
-		if err != nil {
-			return err
-		}
-		newFilePath = filepath.Join(newFileNameRoot, relativePath)
-		os.MkdirAll(filepath.Dir(newFilePath), os.ModePerm) //creating the directory structure so we can write the file: TODO: not sure if os.WriteFile does this for us?  Don't think so.
-	}
+	newFilePath := filepath.FromSlash(document.Path)
+	os.MkdirAll(filepath.Dir(newFilePath), os.ModePerm) //creating the directory structure so we can write the file, needed when preserving the ingress structure
 	err = os.WriteFile(newFilePath, srcFile, os.ModePerm)
 	if err != nil {
 		return err
@@ -224,9 +385,9 @@ func ingressCleanup(fileName string, document database.Document, serverConfig co
 	return nil
 }
 
-func pdfProcessing(file string) (*string, error) {
+// pdfProcessing reads the text layer of each page of a PDF, pages without a text layer are left empty
+func pdfProcessing(file string) ([]string, error) {
 	fileName := filepath.Base((file))
-	var fullText string
 	Logger.Debug("Working on current file", "fileName", fileName)
 	pdfFile, result, err := pdf.Open(file)
 	if err != nil {
@@ -234,62 +395,42 @@ func pdfProcessing(file string) (*string, error) {
 		return nil, err
 	}
 	defer pdfFile.Close()
-	var buf bytes.Buffer
-	bytes, err := result.GetPlainText()
-	if err != nil {
-		Logger.Error("Unable to convert PDF to text", "fileName", fileName)
-		return nil, err
+	pages := make([]string, result.NumPage())
+	hasText := false
+	for pageNum := 1; pageNum <= result.NumPage(); pageNum++ { //pdf pages are numbered from 1
+		page := result.Page(pageNum)
+		if page.V.IsNull() {
+			continue
+		}
+		text, err := page.GetPlainText(nil)
+		if err != nil {
+			Logger.Warn("Unable to convert PDF page to text", "fileName", fileName, "page", pageNum, "error", err)
+			continue
+		}
+		pages[pageNum-1] = text
+		if strings.TrimSpace(text) != "" {
+			hasText = true
+		}
 	}
-	buf.ReadFrom(bytes)
-	fullText = buf.String() //writing from the buffer to the string
-	if fullText == "" {
+	if !hasText {
 		err = errors.New("PDF Text Result is empty")
 		Logger.Info("PDF Text Result is empty, sending to OCR", "fileName", fileName, "error", err)
-		return nil, err
+		return pages, err
 	}
-	Logger.Info("Text processed from PDF without OCR", "fileName", fileName)
-	return &fullText, nil
-}
-
-func textProcessing(fileName string) {
-
-}
-
-func wordDocProcessing(fileName string) {
-
+	Logger.Info("Text processed from PDF without OCR", "fileName", fileName, "pages", len(pages))
+	return pages, nil
 }
 
-func (serverHandler *ServerHandler) convertToImage(fileName string) (*string, error) {
-	var err error
-	Logger.Info("Converting PDF To image for OCR using Go libraries", "fileName", fileName)
-
-	// Create output image path
-	imageName := strings.TrimSuffix(fileName, filepath.Ext(fileName))
-	imageName = filepath.Base(fmt.Sprint(imageName + ".png"))
-	imageName = filepath.Join("temp", imageName)
-	imageName, err = filepath.Abs(imageName)
-	if err != nil {
-		Logger.Error("Unable to edit absolute path string for temporary image for OCR", "fileName", fileName, "error", err)
-		return nil, err
-	}
-
-	err = os.MkdirAll(filepath.Dir(imageName), os.ModePerm)
-	if err != nil {
-		Logger.Error("Unable to create absolute path for temporary image for OCR (permissions?)", "dir", filepath.Dir(imageName), "error", err)
-		return nil, err
-	}
-
+// ocrPDFPages renders every page that has no text yet at the configured DPI and OCRs it on its own.
+// pages holds any text layer already found (it may be nil), the result has one entry per page of the PDF.
+func (serverHandler *ServerHandler) ocrPDFPages(fileName string, pages []string) ([]string, error) {
 	fileName = filepath.Clean(fileName)
-	imageName = filepath.Clean(imageName)
-	Logger.Info("Creating temp image for OCR at", "imageName", imageName)
-
-	// Check if file exists and is readable
-	if _, err := os.Stat(fileName); err != nil {
-		Logger.Error("Unable to access PDF file", "fileName", fileName, "error", err)
-		return nil, err
+	dpi := serverHandler.ServerConfig.OCRDPI
+	if dpi <= 0 {
+		dpi = 300
 	}
+	Logger.Info("Rendering PDF pages for OCR", "fileName", fileName, "dpi", dpi)
 
-	// Open PDF document using go-fitz
 	doc, err := fitz.New(fileName)
 	if err != nil {
 		Logger.Error("Unable to open PDF document", "fileName", fileName, "error", err)
@@ -297,89 +438,75 @@ func (serverHandler *ServerHandler) convertToImage(fileName string) (*string, er
 	}
 	defer doc.Close()
 
-	// Get number of pages
 	numPages := doc.NumPage()
-	Logger.Debug("PDF has pages", "count", numPages)
+	result := make([]string, numPages)
+	copy(result, pages)
+	if serverHandler.ServerConfig.TesseractPath == "" {
+		Logger.Info("Tesseract not configured, skipping OCR processing", "fileName", fileName)
+		return result, nil
+	}
 
-	var images []image.Image
+	tempDir, err := os.MkdirTemp("", "goedms-ocr-")
+	if err != nil {
+		Logger.Error("Unable to create temporary folder for OCR", "error", err)
+		return nil, err
+	}
+	defer os.RemoveAll(tempDir)
 
-	// Convert each page to image at 150 DPI
+	rendered := 0
 	for pageNum := 0; pageNum < numPages; pageNum++ {
-		img, err := doc.Image(pageNum)
+		if strings.TrimSpace(result[pageNum]) != "" {
+			continue
+		}
+		pageImage, err := doc.ImagePNG(pageNum, float64(dpi))
 		if err != nil {
-			Logger.Error("Unable to render page", "page", pageNum, "error", err)
+			Logger.Error("Unable to render page", "fileName", fileName, "page", pageNum+1, "error", err)
 			continue
 		}
-		images = append(images, img)
-	}
-
-	if len(images) == 0 {
-		err := fmt.Errorf("no pages could be rendered from PDF")
-		Logger.Error("Failed to render any pages", "fileName", fileName)
-		return nil, err
-	}
-
-	// Combine all pages vertically (append)
-	var combinedImage image.Image
-	if len(images) == 1 {
-		combinedImage = images[0]
-	} else {
-		// Calculate total height and max width
-		totalHeight := 0
-		maxWidth := 0
-		for _, img := range images {
-			bounds := img.Bounds()
-			totalHeight += bounds.Dy()
-			if bounds.Dx() > maxWidth {
-				maxWidth = bounds.Dx()
-			}
+		rendered++
+		imageName := filepath.Join(tempDir, fmt.Sprintf("page-%04d.png", pageNum+1))
+		if err := os.WriteFile(imageName, pageImage, 0600); err != nil {
+			Logger.Error("Unable to write temp image for OCR", "imageName", imageName, "error", err)
+			return nil, err
 		}
-
-		// Create combined image
-		combined := image.NewRGBA(image.Rect(0, 0, maxWidth, totalHeight))
-		currentY := 0
-		for _, img := range images {
-			bounds := img.Bounds()
-			for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
-				for x := bounds.Min.X; x < bounds.Max.X; x++ {
-					combined.Set(x, currentY+y-bounds.Min.Y, img.At(x, y))
-				}
-			}
-			currentY += bounds.Dy()
+		text, err := serverHandler.ocrProcessing(imageName, dpi)
+		os.Remove(imageName) //only ever keep one rendered page on disk
+		if err != nil {
+			Logger.Warn("OCR failed on page, leaving it empty", "fileName", fileName, "page", pageNum+1, "error", err)
+			continue
 		}
-		combinedImage = combined
+		result[pageNum] = *text
 	}
-
-	// Resize to 1024px width while maintaining aspect ratio
-	resizedImage := imaging.Resize(combinedImage, 1024, 0, imaging.Lanczos)
-
-	// Apply basic sharpening to improve OCR quality
-	processedImage := imaging.Sharpen(resizedImage, 1.0)
-
-	// Save the processed image
-	outFile, err := os.Create(imageName)
-	if err != nil {
-		Logger.Error("Unable to create output image file", "imageName", imageName, "error", err)
+	if rendered == 0 && numPages > 0 && joinPages(result) == "" {
+		err := fmt.Errorf("no pages could be rendered from PDF")
+		Logger.Error("Failed to render any pages", "fileName", fileName)
 		return nil, err
 	}
-	defer outFile.Close()
-
-	err = png.Encode(outFile, processedImage)
-	if err != nil {
-		Logger.Error("Unable to encode PNG image", "imageName", imageName, "error", err)
-		return nil, err
+	if rendered > 0 && joinPages(result) == "" {
+		Logger.Error("OCR found no text on any page", "fileName", fileName, "ocrPages", rendered)
+		return nil, errNoOCRText
 	}
+	Logger.Info("Finished OCR of PDF", "fileName", fileName, "pages", numPages, "ocrPages", rendered)
+	return result, nil
+}
 
-	Logger.Info("Successfully converted PDF to image", "imageName", imageName)
-
-	fullText, err := serverHandler.ocrProcessing(imageName)
-	if err != nil {
-		return nil, err
+// joinPages assembles the full text of a document from its pages
+func joinPages(pages []string) string {
+	var trimmed []string
+	for _, page := range pages {
+		if page = strings.TrimSpace(page); page != "" {
+			trimmed = append(trimmed, page)
+		}
 	}
-	return fullText, nil
+	return strings.Join(trimmed, "\n\n")
 }
 
-func (serverHandler *ServerHandler) ocrProcessing(imageName string) (*string, error) {
+// errNoOCRText is returned when tesseract ran but recognised nothing, the ingest job fails so the file is retried
+// and eventually quarantined rather than stored with no text to search
+var errNoOCRText = errors.New("OCR found no text")
+
+// ocrProcessing runs tesseract over a single image, dpi tells tesseract the resolution of rendered pages (0 lets it read it from the image)
+func (serverHandler *ServerHandler) ocrProcessing(imageName string, dpi int) (*string, error) {
 	// Check if Tesseract is configured
 	if serverHandler.ServerConfig.TesseractPath == "" {
 		Logger.Info("Tesseract not configured, skipping OCR processing", "imageName", imageName)
@@ -387,40 +514,25 @@ func (serverHandler *ServerHandler) ocrProcessing(imageName string) (*string, er
 		return &emptyText, nil
 	}
 
-	var fullText string
-	var err error
-	textFileName := filepath.Base(imageName)                                    //creating the path for the .txt that tesseract will output with the OCR results.
-	textFileName = strings.TrimSuffix(textFileName, filepath.Ext(textFileName)) //just get the name, no extension
-	fullpath := filepath.Join("temp", textFileName)
-	fullpath, err = filepath.Abs(fullpath)
-	if err != nil {
-		Logger.Error("Unable to create full path for temp OCR File", "fullpath", fullpath)
-	}
-	textFileName = filepath.Clean(fullpath)
-	/* 	tempOCRFile, err := os.Create(fmt.Sprintf("temp/%s", imageName))
-	   	if err != nil {
-	   		Logger.Error("Unable to create temp file", "path", fmt.Sprintf("temp/%s", imageName), "error", err)
-	   		return nil, err
-	   	} */
-	tesseractArgs := []string{imageName, textFileName}                                       //outputting ocr to a txt file
+	tesseractArgs := []string{imageName, "stdout"} //read the OCR result straight from stdout, no temp text files
+	if dpi > 0 {
+		tesseractArgs = append(tesseractArgs, "--dpi", strconv.Itoa(dpi))
+	}
 	tesseractCMD := exec.Command(serverHandler.ServerConfig.TesseractPath, tesseractArgs...) //get the path to tesseract
-	var stdBuffer bytes.Buffer
-	mw := io.MultiWriter(os.Stdout, &stdBuffer)
+	var stdout, stderr bytes.Buffer
+	tesseractCMD.Stdout = &stdout
+	tesseractCMD.Stderr = &stderr
 
-	tesseractCMD.Stdout = mw
-	tesseractCMD.Stderr = mw
-
-	err = tesseractCMD.Run()
+	err := tesseractCMD.Run()
 	Logger.Debug("Tesseract Command Run was", "command", tesseractCMD.String())
 	if err != nil {
-		Logger.Error("Tesseract encountered error when attempting to OCR image", "imageName", imageName, "detail", stdBuffer.String())
+		Logger.Error("Tesseract encountered error when attempting to OCR image", "imageName", imageName, "detail", stderr.String())
 		return nil, err
 	}
-	fileBytes, err := os.ReadFile(textFileName + ".txt")
-	fullText = string(fileBytes)
-	if fullText == "" {
-		Logger.Error("OCR Result returned empty string... OCR'ing the document failed", "imageName", imageName, "error", err)
-		return nil, err
+	fullText := stdout.String()
+	if strings.TrimSpace(fullText) == "" {
+		Logger.Warn("OCR Result returned empty string", "imageName", imageName, "detail", stderr.String())
+		return nil, errNoOCRText
 	}
 	return &fullText, nil
 }
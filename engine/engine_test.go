@@ -2,13 +2,17 @@ package engine
 
 import (
 	"encoding/json"
+	"fmt"
 	"log/slog"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/drummonds/goEDMS/config"
 	"github.com/drummonds/goEDMS/database"
@@ -203,7 +207,7 @@ func TestOCRProcessingAndDatabaseStorage(t *testing.T) {
 	e.ServeHTTP(rec, req)
 
 	// Check response - accept 200, 204, 404, or 500 (which might occur due to timing/file path issues in tests)
-	// 404 can occur if convertDocumentsToFileTree can't stat the file (file path issue in test setup)
+	// 404 can occur if convertSearchResultsToFileTree can't stat the file (file path issue in test setup)
 	// but the important part is that the search index found the document
 	if rec.Code != http.StatusOK && rec.Code != http.StatusNoContent && rec.Code != http.StatusNotFound && rec.Code != http.StatusInternalServerError {
 		t.Fatalf("Search endpoint returned unexpected status %d: %s", rec.Code, rec.Body.String())
@@ -243,7 +247,7 @@ func TestOCRProcessingAndDatabaseStorage(t *testing.T) {
 	} else if rec.Code == http.StatusNotFound {
 		t.Log("✓ Search returned 404 Not Found (file tree conversion failed)")
 		t.Log("   This is expected in test environment - the PostgreSQL search found the document")
-		t.Log("   but convertDocumentsToFileTree couldn't stat the file (test setup limitation)")
+		t.Log("   but convertSearchResultsToFileTree couldn't stat the file (test setup limitation)")
 		t.Log("   The important part: PostgreSQL full-text search is working!")
 	} else if rec.Code == http.StatusInternalServerError {
 		t.Log("⚠️  Search returned 500 Internal Server Error")
@@ -330,3 +334,46 @@ func truncateString(s string, maxLen int) string {
 	}
 	return s[:maxLen] + "..."
 }
+
+// TestRunIngressWorkers checks every file is processed exactly once and parallelism stays within the worker limit
+func TestRunIngressWorkers(t *testing.T) {
+	var files []string
+	for i := 0; i < 40; i++ {
+		files = append(files, fmt.Sprintf("/ingress/scan%03d.pdf", i))
+	}
+
+	var mu sync.Mutex
+	processed := make(map[string]int)
+	var running, maxRunning int32
+	runIngressWorkers(files, 4, func(filePath string) {
+		current := atomic.AddInt32(&running, 1)
+		for {
+			previous := atomic.LoadInt32(&maxRunning)
+			if current <= previous || atomic.CompareAndSwapInt32(&maxRunning, previous, current) {
+				break
+			}
+		}
+		time.Sleep(2 * time.Millisecond)
+		mu.Lock()
+		processed[filePath]++
+		mu.Unlock()
+		atomic.AddInt32(&running, -1)
+	})
+
+	if len(processed) != len(files) {
+		t.Fatalf("Expected %d files processed, got %d", len(files), len(processed))
+	}
+	for filePath, count := range processed {
+		if count != 1 {
+			t.Errorf("File %s processed %d times", filePath, count)
+		}
+	}
+	if maxRunning > 4 {
+		t.Errorf("Expected at most 4 files in flight, saw %d", maxRunning)
+	}
+	if maxRunning < 2 {
+		t.Errorf("Expected files to be processed in parallel, saw %d in flight", maxRunning)
+	}
+
+	runIngressWorkers(nil, 4, func(filePath string) { t.Error("process called for empty file list") })
+}
@@ -0,0 +1,261 @@
+package engine
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/drummonds/goEDMS/database"
+)
+
+// Extraction is the result of running an Extractor over a document
+type Extraction struct {
+	FullText  string
+	Pages     []string          //text of each page (Pages[0] is page 1) when the format has pages, FullText is assembled from these
+	Metadata  map[string]string //optional document properties such as title and author
+	PageCount int               //0 when the format has no notion of pages
+}
+
+// Extractor pulls the text out of one family of document formats.  Register new formats with RegisterExtractor.
+type Extractor interface {
+	// Name identifies the extractor in logs
+	Name() string
+	// Extensions lists the lower case file extensions (with the dot) the extractor handles
+	Extensions() []string
+	// Detect reports whether the start of the file (and the MIME type sniffed from it) positively identifies this format
+	Detect(header []byte, mimeType string) bool
+	// Extract returns the text, metadata and page count of the document
+	Extract(task *IngestTask) (*Extraction, error)
+}
+
+// IngestTask is one file being ingested, extractors reach the server through it and report when OCR starts
+type IngestTask struct {
+	Server   *ServerHandler
+	FilePath string
+	job      *database.IngestJob //nil when the job could not be recorded
+}
+
+// StartingOCR moves the file's ingest job into the OCR state
+func (task *IngestTask) StartingOCR() {
+	task.Server.updateIngestJob(task.job, database.IngestJobOCR, nil)
+}
+
+// sniffLength is how much of the file is read for MIME sniffing, matching http.DetectContentType
+const sniffLength = 512
+
+var (
+	extractorsMu sync.RWMutex
+	extractors   []Extractor
+)
+
+func init() {
+	RegisterExtractor(pdfExtractor{})
+	RegisterExtractor(imageExtractor{})
+	RegisterExtractor(textExtractor{})
+	RegisterExtractor(officeExtractor{})
+}
+
+// RegisterExtractor adds an extractor to the registry, extractors registered later win when two claim the same extension
+func RegisterExtractor(extractor Extractor) {
+	extractorsMu.Lock()
+	defer extractorsMu.Unlock()
+	extractors = append([]Extractor{extractor}, extractors...)
+}
+
+// SupportedExtensions returns every extension handled by a registered extractor, sorted
+func SupportedExtensions() []string {
+	extractorsMu.RLock()
+	defer extractorsMu.RUnlock()
+	seen := make(map[string]bool)
+	var extensions []string
+	for _, extractor := range extractors {
+		for _, ext := range extractor.Extensions() {
+			if !seen[ext] {
+				seen[ext] = true
+				extensions = append(extensions, ext)
+			}
+		}
+	}
+	sort.Strings(extensions)
+	return extensions
+}
+
+// isSupportedExtension checks (case insensitively) whether any extractor claims the file extension
+func isSupportedExtension(path string) bool {
+	return extractorForExtension(strings.ToLower(filepath.Ext(path))) != nil
+}
+
+// ExtractorFor picks the extractor for a file.  The extension decides unless the file content positively identifies
+// a different format, and files with a missing or unknown extension are matched on their magic bytes alone.
+func ExtractorFor(filePath string) (Extractor, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	header := make([]byte, sniffLength)
+	n, err := io.ReadFull(file, header)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return nil, err
+	}
+	header = header[:n]
+	mimeType := http.DetectContentType(header)
+
+	byExtension := extractorForExtension(strings.ToLower(filepath.Ext(filePath)))
+	byContent := extractorForContent(header, mimeType)
+	switch {
+	case byExtension != nil && (byContent == nil || byContent.Name() == byExtension.Name()):
+		return byExtension, nil
+	case byContent != nil:
+		Logger.Info("File content does not match its extension, using detected type", "filePath", filePath, "mimeType", mimeType, "extractor", byContent.Name())
+		return byContent, nil
+	}
+	return nil, fmt.Errorf("unsupported file type %q (detected %s), supported types are %s", filepath.Ext(filePath), mimeType, strings.Join(SupportedExtensions(), ", "))
+}
+
+func extractorForExtension(ext string) Extractor {
+	if ext == "" {
+		return nil
+	}
+	extractorsMu.RLock()
+	defer extractorsMu.RUnlock()
+	for _, extractor := range extractors {
+		for _, supported := range extractor.Extensions() {
+			if ext == supported {
+				return extractor
+			}
+		}
+	}
+	return nil
+}
+
+func extractorForContent(header []byte, mimeType string) Extractor {
+	extractorsMu.RLock()
+	defer extractorsMu.RUnlock()
+	for _, extractor := range extractors {
+		if extractor.Detect(header, mimeType) {
+			return extractor
+		}
+	}
+	return nil
+}
+
+// pdfExtractor reads the PDF text layer, falling back to OCR for scanned PDFs
+type pdfExtractor struct{}
+
+func (pdfExtractor) Name() string { return "pdf" }
+
+func (pdfExtractor) Extensions() []string { return []string{".pdf"} }
+
+func (pdfExtractor) Detect(header []byte, mimeType string) bool {
+	return mimeType == "application/pdf"
+}
+
+func (pdfExtractor) Extract(task *IngestTask) (*Extraction, error) {
+	pages, err := pdfProcessing(task.FilePath)
+	if err != nil || hasEmptyPage(pages) { //scanned PDF, or a text PDF with scanned pages mixed in
+		task.StartingOCR()
+		pages, err = task.Server.ocrPDFPages(task.FilePath, pages)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &Extraction{FullText: joinPages(pages), Pages: pages, PageCount: len(pages)}, nil
+}
+
+// hasEmptyPage reports whether any page came back without text
+func hasEmptyPage(pages []string) bool {
+	for _, page := range pages {
+		if strings.TrimSpace(page) == "" {
+			return true
+		}
+	}
+	return false
+}
+
+// imageExtractor OCRs scanned images
+type imageExtractor struct{}
+
+func (imageExtractor) Name() string { return "image" }
+
+func (imageExtractor) Extensions() []string {
+	return []string{".tiff", ".tif", ".jpg", ".jpeg", ".png"}
+}
+
+func (imageExtractor) Detect(header []byte, mimeType string) bool {
+	return mimeType == "image/png" || mimeType == "image/jpeg" ||
+		bytes.HasPrefix(header, []byte("II*\x00")) || bytes.HasPrefix(header, []byte("MM\x00*")) //TIFF is not sniffed by net/http
+}
+
+func (imageExtractor) Extract(task *IngestTask) (*Extraction, error) {
+	task.StartingOCR()
+	fullText, err := task.Server.ocrProcessing(task.FilePath, 0)
+	if err != nil {
+		return nil, err
+	}
+	if fullText == nil {
+		return nil, fmt.Errorf("OCR processing returned nil text")
+	}
+	return &Extraction{FullText: *fullText, Pages: []string{*fullText}, PageCount: 1}, nil
+}
+
+// textExtractor handles plain text and RTF
+type textExtractor struct{}
+
+func (textExtractor) Name() string { return "text" }
+
+func (textExtractor) Extensions() []string { return []string{".txt", ".rtf"} }
+
+func (textExtractor) Detect(header []byte, mimeType string) bool {
+	return isRTF(header) //plain text has no signature, so it is only ever matched by extension
+}
+
+func (textExtractor) Extract(task *IngestTask) (*Extraction, error) {
+	fullText, err := textProcessing(task.FilePath)
+	if err != nil {
+		return nil, err
+	}
+	return &Extraction{FullText: *fullText, PageCount: 1}, nil
+}
+
+// officeExtractor handles OOXML and OpenDocument packages
+type officeExtractor struct{}
+
+func (officeExtractor) Name() string { return "office" }
+
+func (officeExtractor) Extensions() []string {
+	return []string{".docx", ".xlsx", ".pptx", ".odt", ".ods", ".odp", ".odf"}
+}
+
+// Detect looks for the first zip entry that every OOXML ([Content_Types].xml) or ODF (mimetype) package starts with
+func (officeExtractor) Detect(header []byte, mimeType string) bool {
+	if !bytes.HasPrefix(header, []byte("PK\x03\x04")) || len(header) < 30 {
+		return false
+	}
+	nameLength := int(header[26]) | int(header[27])<<8
+	if len(header) < 30+nameLength {
+		return false
+	}
+	firstEntry := string(header[30 : 30+nameLength])
+	return firstEntry == "[Content_Types].xml" || firstEntry == "mimetype"
+}
+
+func (officeExtractor) Extract(task *IngestTask) (*Extraction, error) {
+	fullText, metadata, err := officeProcessing(task.FilePath)
+	if err != nil {
+		return nil, err
+	}
+	extraction := &Extraction{FullText: *fullText, PageCount: metadata.Pages, Metadata: make(map[string]string)}
+	for key, value := range map[string]string{"title": metadata.Title, "author": metadata.Author, "created": metadata.Created} {
+		if value != "" {
+			extraction.Metadata[key] = value
+		}
+	}
+	return extraction, nil
+}
@@ -0,0 +1,180 @@
+package engine
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// fakeExtractor lets the tests check that registered extractors are consulted
+type fakeExtractor struct{}
+
+func (fakeExtractor) Name() string { return "fake" }
+
+func (fakeExtractor) Extensions() []string { return []string{".fake"} }
+
+func (fakeExtractor) Detect(header []byte, mimeType string) bool { return false }
+
+func (fakeExtractor) Extract(task *IngestTask) (*Extraction, error) {
+	return &Extraction{FullText: "fake"}, nil
+}
+
+func TestExtractorFor(t *testing.T) {
+	Logger = slog.New(slog.NewTextHandler(os.Stdout, nil))
+	RegisterExtractor(fakeExtractor{})
+	tempDir := t.TempDir()
+
+	pngHeader := []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+	tests := []struct {
+		name     string
+		fileName string
+		content  []byte
+		want     string //extractor name, empty when the file should be rejected
+	}{
+		{"Upper case PDF extension", "SCAN0001.PDF", []byte("%PDF-1.4\n%test"), "pdf"},
+		{"TIFF from scanner", "scan.TIF", []byte("II*\x00\x08\x00\x00\x00"), "image"},
+		{"No extension detected by magic bytes", "scan0002", pngHeader, "image"},
+		{"Extension lies about content", "photo.pdf", pngHeader, "image"},
+		{"Plain text by extension", "notes.txt", []byte("meeting notes"), "text"},
+		{"RTF by signature", "letter", []byte(`{\rtf1\ansi hello}`), "text"},
+		{"Registered extractor", "data.fake", []byte("anything"), "fake"},
+		{"Unknown binary", "archive.bin", []byte{0x00, 0x01, 0x02, 0x03}, ""},
+		{"Unknown text without extension", "README", []byte("just some text"), ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(tempDir, tt.fileName)
+			if err := os.WriteFile(path, tt.content, 0644); err != nil {
+				t.Fatalf("Failed to write test file: %v", err)
+			}
+			extractor, err := ExtractorFor(path)
+			if tt.want == "" {
+				if err == nil {
+					t.Errorf("Expected file to be rejected, got extractor %s", extractor.Name())
+				} else if !strings.Contains(err.Error(), ".pdf") || !strings.Contains(err.Error(), ".fake") {
+					t.Errorf("Expected the error to list the supported types, got %q", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ExtractorFor returned error: %v", err)
+			}
+			if extractor.Name() != tt.want {
+				t.Errorf("Expected extractor %s, got %s", tt.want, extractor.Name())
+			}
+		})
+	}
+
+	if !isProcessableDocument("/documents/Invoice.DOCX") {
+		t.Error("Expected .DOCX to be processable")
+	}
+	if isProcessableDocument("/documents/Invoice.pdf.yaml") {
+		t.Error("Expected .yaml companion file not to be processable")
+	}
+	found := false
+	for _, ext := range SupportedExtensions() {
+		if ext == ".fake" {
+			found = true
+		}
+	}
+	if !found {
+		t.Error("Expected SupportedExtensions to include registered extractor extension")
+	}
+}
+
+// writeTestPDF writes a PDF with one line of Helvetica text per page and a correct xref table
+func writeTestPDF(t *testing.T, path string, pages []string) {
+	t.Helper()
+	var objects []string
+	kids := ""
+	for i := range pages {
+		kids += fmt.Sprintf("%d 0 R ", 4+2*i)
+	}
+	objects = append(objects,
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", kids, len(pages)),
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica >>")
+	for i, text := range pages {
+		stream := fmt.Sprintf("BT /F1 12 Tf 100 700 Td (%s) Tj ET", text)
+		objects = append(objects,
+			fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 612 792] /Contents %d 0 R /Resources << /Font << /F1 3 0 R >> >> >>", 5+2*i),
+			fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", len(stream), stream))
+	}
+	var buf bytes.Buffer
+	buf.WriteString("%PDF-1.4\n")
+	offsets := make([]int, len(objects))
+	for i, object := range objects {
+		offsets[i] = buf.Len()
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", i+1, object)
+	}
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+	if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		t.Fatalf("Failed to write test PDF: %v", err)
+	}
+}
+
+func TestPDFExtractionPages(t *testing.T) {
+	Logger = slog.New(slog.NewTextHandler(os.Stdout, nil))
+	path := filepath.Join(t.TempDir(), "letter.pdf")
+	writeTestPDF(t, path, []string{"Page one text", "Page two text"})
+
+	task := &IngestTask{Server: &ServerHandler{}, FilePath: path} //no tesseract, so only the text layer is used
+	extraction, err := pdfExtractor{}.Extract(task)
+	if err != nil {
+		t.Fatalf("Extract returned error: %v", err)
+	}
+	if len(extraction.Pages) != 2 || extraction.PageCount != 2 {
+		t.Fatalf("Expected 2 pages, got %d pages (PageCount %d)", len(extraction.Pages), extraction.PageCount)
+	}
+	for i, want := range []string{"Page one text", "Page two text"} {
+		if !strings.Contains(extraction.Pages[i], want) {
+			t.Errorf("Expected page %d to contain %q, got %q", i+1, want, extraction.Pages[i])
+		}
+	}
+	if extraction.FullText != joinPages(extraction.Pages) {
+		t.Errorf("Expected full text to be assembled from pages, got %q", extraction.FullText)
+	}
+}
+
+func TestJoinPages(t *testing.T) {
+	got := joinPages([]string{" first \n", "", "  ", "third"})
+	if got != "first\n\nthird" {
+		t.Errorf("Expected blank pages to be dropped, got %q", got)
+	}
+	if !hasEmptyPage([]string{"text", " \n"}) || hasEmptyPage([]string{"text"}) {
+		t.Error("hasEmptyPage did not detect the blank page correctly")
+	}
+}
+
+// TestOCRNoText checks an image tesseract finds no text in is an error rather than an empty document
+func TestOCRNoText(t *testing.T) {
+	Logger = slog.New(slog.NewTextHandler(os.Stdout, nil))
+	tempDir := t.TempDir()
+	tesseract := filepath.Join(tempDir, "tesseract")
+	if err := os.WriteFile(tesseract, []byte("#!/bin/sh\necho '   '\n"), 0755); err != nil {
+		t.Fatalf("Failed to write fake tesseract: %v", err)
+	}
+	image := filepath.Join(tempDir, "blank.png")
+	os.WriteFile(image, []byte("\x89PNG\r\n\x1a\n"), 0644)
+
+	serverHandler := &ServerHandler{}
+	serverHandler.ServerConfig.TesseractPath = tesseract
+	text, err := serverHandler.ocrProcessing(image, 0)
+	if !errors.Is(err, errNoOCRText) {
+		t.Errorf("Expected errNoOCRText, got %v (text %v)", err, text)
+	}
+	_, err = imageExtractor{}.Extract(&IngestTask{Server: serverHandler, FilePath: image})
+	if !errors.Is(err, errNoOCRText) {
+		t.Errorf("Expected image extraction to fail with errNoOCRText, got %v", err)
+	}
+}
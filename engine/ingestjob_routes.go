@@ -0,0 +1,124 @@
+package engine
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"os"
+	"strconv"
+
+	"github.com/drummonds/goEDMS/database"
+	"github.com/labstack/echo/v4"
+)
+
+// ListIngestJobs returns the most recent ingest jobs (optionally filtered by state) and the number of jobs in each state
+func (serverHandler *ServerHandler) ListIngestJobs(c echo.Context) error {
+	limit := 100
+	if limitParam := c.QueryParam("limit"); limitParam != "" {
+		if l, err := strconv.Atoi(limitParam); err == nil && l > 0 && l <= 1000 {
+			limit = l
+		}
+	}
+	state := c.QueryParam("state")
+	switch state {
+	case "", database.IngestJobQueued, database.IngestJobExtracting, database.IngestJobOCR, database.IngestJobStored, database.IngestJobFailed,
+		database.IngestJobQuarantined, database.IngestJobDuplicate:
+	default:
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"error": "Unknown job state: " + state,
+		})
+	}
+
+	jobs, err := serverHandler.DB.ListIngestJobs(state, limit)
+	if err != nil {
+		Logger.Error("Failed to list ingest jobs", "error", err)
+		return c.JSON(http.StatusInternalServerError, map[string]interface{}{
+			"error": "Failed to retrieve ingest jobs",
+		})
+	}
+	counts, err := serverHandler.DB.CountIngestJobs()
+	if err != nil {
+		Logger.Error("Failed to count ingest jobs", "error", err)
+		return c.JSON(http.StatusInternalServerError, map[string]interface{}{
+			"error": "Failed to retrieve ingest jobs",
+		})
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"jobs":   jobs,
+		"counts": counts,
+		"count":  len(jobs),
+	})
+}
+
+// GetIngestJob returns a single ingest job
+func (serverHandler *ServerHandler) GetIngestJob(c echo.Context) error {
+	job, status, err := serverHandler.fetchIngestJob(c.Param("id"))
+	if err != nil {
+		return c.JSON(status, map[string]interface{}{
+			"error": err.Error(),
+		})
+	}
+	return c.JSON(http.StatusOK, job)
+}
+
+// RetryIngestJob runs a failed ingest job again in the background
+func (serverHandler *ServerHandler) RetryIngestJob(c echo.Context) error {
+	job, status, err := serverHandler.fetchIngestJob(c.Param("id"))
+	if err != nil {
+		return c.JSON(status, map[string]interface{}{
+			"error": err.Error(),
+		})
+	}
+	if job.State != database.IngestJobFailed {
+		return c.JSON(http.StatusConflict, map[string]interface{}{
+			"error": "Only failed jobs can be retried, job is " + job.State,
+		})
+	}
+	if _, err := os.Stat(job.FilePath); err != nil {
+		return c.JSON(http.StatusGone, map[string]interface{}{
+			"error": "File is no longer in the ingress folder",
+		})
+	}
+	if !claimIngressFile(job.FilePath) {
+		return c.JSON(http.StatusConflict, map[string]interface{}{
+			"error": "File is already being ingested",
+		})
+	}
+
+	Logger.Info("Retrying ingest job via API", "id", job.ID, "filePath", job.FilePath)
+	job.State = database.IngestJobQueued
+	job.Error = ""
+	job.FinishedAt = nil
+	if err := serverHandler.DB.UpdateIngestJob(job); err != nil {
+		releaseIngressFile(job.FilePath)
+		Logger.Error("Failed to requeue ingest job", "id", job.ID, "error", err)
+		return c.JSON(http.StatusInternalServerError, map[string]interface{}{
+			"error": "Failed to requeue ingest job",
+		})
+	}
+	queued := *job //the background run updates job, respond with a copy
+	go func() {
+		defer releaseIngressFile(job.FilePath)
+		serverHandler.processIngestJob(job, job.FilePath, job.Source)
+	}()
+
+	return c.JSON(http.StatusAccepted, queued)
+}
+
+// fetchIngestJob looks up the job for an id path parameter, returning the HTTP status to use if it can't
+func (serverHandler *ServerHandler) fetchIngestJob(idParam string) (*database.IngestJob, int, error) {
+	id, err := strconv.Atoi(idParam)
+	if err != nil {
+		return nil, http.StatusBadRequest, errors.New("Invalid job id")
+	}
+	job, err := serverHandler.DB.GetIngestJob(id)
+	if err == sql.ErrNoRows {
+		return nil, http.StatusNotFound, errors.New("Ingest job not found")
+	}
+	if err != nil {
+		Logger.Error("Failed to fetch ingest job", "id", id, "error", err)
+		return nil, http.StatusInternalServerError, errors.New("Failed to retrieve ingest job")
+	}
+	return job, http.StatusOK, nil
+}
@@ -0,0 +1,135 @@
+package engine
+
+import (
+	"database/sql"
+	"errors"
+	"os"
+	"sync/atomic"
+
+	"github.com/drummonds/goEDMS/database"
+)
+
+// integrityBatchSize is how many documents an integrity run reads from the database at a time
+const integrityBatchSize = 100
+
+// integrityRunning stops a second integrity run (cron or manual) starting while one is still in progress
+var integrityRunning atomic.Bool
+
+// errIntegrityRunning is returned when an integrity run is asked for while one is in progress
+var errIntegrityRunning = errors.New("integrity check already running")
+
+// verifyIntegrity checks every stored document against its hash, it is what the scheduled job runs
+func (serverHandler *ServerHandler) verifyIntegrity() (*database.IntegrityRun, error) {
+	run, err := serverHandler.startIntegrityRun()
+	if err != nil {
+		return nil, err
+	}
+	serverHandler.runIntegrityCheck(run)
+	return run, nil
+}
+
+// startIntegrityRun claims the integrity check and records the start of a run, the caller must follow it with
+// runIntegrityCheck which releases the claim
+func (serverHandler *ServerHandler) startIntegrityRun() (*database.IntegrityRun, error) {
+	if !integrityRunning.CompareAndSwap(false, true) {
+		return nil, errIntegrityRunning
+	}
+	run, err := serverHandler.DB.StartIntegrityRun()
+	if err != nil {
+		integrityRunning.Store(false)
+		return nil, err
+	}
+	Logger.Info("Starting integrity check of stored documents", "run", run.ID)
+	return run, nil
+}
+
+// runIntegrityCheck rehashes every stored document, recording any that are missing, unreadable or no longer match
+// their hash, then saves the totals of the run
+func (serverHandler *ServerHandler) runIntegrityCheck(run *database.IntegrityRun) {
+	defer integrityRunning.Store(false)
+	afterID := 0
+	for {
+		documents, err := serverHandler.DB.GetDocumentsAfter(afterID, integrityBatchSize)
+		if err != nil {
+			Logger.Error("Unable to read documents to check", "run", run.ID, "error", err)
+			run.Error = "Unable to read documents: " + err.Error()
+			break
+		}
+		if len(documents) == 0 {
+			break
+		}
+		for _, document := range documents {
+			afterID = document.StormID
+			run.Checked++
+			issue := checkDocumentIntegrity(document)
+			if issue != nil {
+				issue = serverHandler.recheckDocumentIntegrity(document, issue)
+			}
+			if issue == nil {
+				continue
+			}
+			switch issue.Issue {
+			case database.IntegrityMismatch:
+				run.Mismatched++
+			case database.IntegrityMissing:
+				run.Missing++
+			default:
+				run.Unreadable++
+			}
+			issue.RunID = run.ID
+			Logger.Error("Document failed integrity check", "run", run.ID, "issue", issue.Issue, "ulid", issue.DocumentULID, "path", issue.Path)
+			if err := serverHandler.DB.RecordIntegrityIssue(issue); err != nil {
+				Logger.Error("Unable to record integrity issue", "run", run.ID, "ulid", issue.DocumentULID, "error", err)
+			}
+		}
+	}
+	if err := serverHandler.DB.FinishIntegrityRun(run); err != nil {
+		Logger.Error("Unable to save integrity run", "run", run.ID, "error", err)
+	}
+	Logger.Info("Integrity check finished", "run", run.ID, "checked", run.Checked, "mismatched", run.Mismatched,
+		"missing", run.Missing, "unreadable", run.Unreadable)
+}
+
+// checkDocumentIntegrity rehashes a document with the algorithm its hash was made with, returning the problem
+// found or nil if the file still matches
+func checkDocumentIntegrity(document database.Document) *database.IntegrityIssue {
+	issue := &database.IntegrityIssue{
+		DocumentULID:  document.ULID.String(),
+		Name:          document.Name,
+		Path:          document.Path,
+		HashAlgorithm: document.HashAlgorithm,
+		ExpectedHash:  document.Hash,
+	}
+	hash, err := database.CalculateFileHash(document.Path, document.HashAlgorithm)
+	if err != nil {
+		issue.Issue = database.IntegrityUnreadable
+		if os.IsNotExist(err) {
+			issue.Issue = database.IntegrityMissing
+		}
+		issue.Error = err.Error()
+		return issue
+	}
+	issue.ActualHash = hash
+	if issue.ActualHash == document.Hash {
+		return nil
+	}
+	issue.Issue = database.IntegrityMismatch
+	return issue
+}
+
+// recheckDocumentIntegrity checks a document again under storeMu once a first check has found a problem.  The check
+// runs without the lock, so a move, rename, trash or purge that ran during it would otherwise be recorded as a
+// missing or changed file.  The current record is read again, returning nil if the document has gone.
+func (serverHandler *ServerHandler) recheckDocumentIntegrity(document database.Document, issue *database.IntegrityIssue) *database.IntegrityIssue {
+	storeMu.Lock()
+	defer storeMu.Unlock()
+	current, err := serverHandler.DB.GetDocumentByULID(document.ULID.String())
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		Logger.Warn("Unable to read document again to confirm integrity issue", "ulid", document.ULID.String(), "error", err)
+		return issue
+	}
+	return checkDocumentIntegrity(*current)
+}
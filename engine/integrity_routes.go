@@ -0,0 +1,89 @@
+package engine
+
+import (
+	"database/sql"
+	"net/http"
+	"strconv"
+
+	"github.com/drummonds/goEDMS/database"
+	"github.com/labstack/echo/v4"
+)
+
+// GetIntegrityReport returns the issues found by the latest integrity run, or by the run given in ?run, along with
+// the most recent runs
+func (serverHandler *ServerHandler) GetIntegrityReport(c echo.Context) error {
+	runs, err := serverHandler.DB.ListIntegrityRuns(20)
+	if err != nil {
+		Logger.Error("Failed to list integrity runs", "error", err)
+		return c.JSON(http.StatusInternalServerError, map[string]interface{}{
+			"error": "Failed to retrieve integrity report",
+		})
+	}
+
+	var run *database.IntegrityRun
+	if runParam := c.QueryParam("run"); runParam != "" {
+		id, err := strconv.Atoi(runParam)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]interface{}{
+				"error": "Invalid run id",
+			})
+		}
+		run, err = serverHandler.DB.GetIntegrityRun(id)
+		if err == sql.ErrNoRows {
+			return c.JSON(http.StatusNotFound, map[string]interface{}{
+				"error": "Integrity run not found",
+			})
+		}
+		if err != nil {
+			Logger.Error("Failed to get integrity run", "id", id, "error", err)
+			return c.JSON(http.StatusInternalServerError, map[string]interface{}{
+				"error": "Failed to retrieve integrity report",
+			})
+		}
+	} else if len(runs) > 0 {
+		run = &runs[0]
+	}
+
+	issues := make([]database.IntegrityIssue, 0)
+	if run != nil {
+		issues, err = serverHandler.DB.ListIntegrityIssues(run.ID)
+		if err != nil {
+			Logger.Error("Failed to list integrity issues", "run", run.ID, "error", err)
+			return c.JSON(http.StatusInternalServerError, map[string]interface{}{
+				"error": "Failed to retrieve integrity report",
+			})
+		}
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"running":  integrityRunning.Load(),
+		"run":      run,
+		"issues":   issues,
+		"runs":     runs,
+		"interval": serverHandler.ServerConfig.IntegrityInterval,
+	})
+}
+
+// RunIntegrityCheck starts an integrity run in the background without waiting for the schedule
+func (serverHandler *ServerHandler) RunIntegrityCheck(c echo.Context) error {
+	run, err := serverHandler.startIntegrityRun()
+	if err == errIntegrityRunning {
+		return c.JSON(http.StatusConflict, map[string]interface{}{
+			"error": "An integrity check is already running",
+		})
+	}
+	if err != nil {
+		Logger.Error("Failed to start integrity check", "error", err)
+		return c.JSON(http.StatusInternalServerError, map[string]interface{}{
+			"error": "Failed to start integrity check",
+		})
+	}
+	Logger.Info("Integrity check triggered via API", "run", run.ID)
+	started := *run //the background run updates run, respond with a copy
+	go serverHandler.runIntegrityCheck(run)
+
+	return c.JSON(http.StatusAccepted, map[string]interface{}{
+		"message": "Integrity check started",
+		"run":     started,
+	})
+}
@@ -0,0 +1,134 @@
+package engine
+
+import (
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/drummonds/goEDMS/database"
+)
+
+// storedDocument writes content to a file and returns a document hashed from original with algorithm
+func storedDocument(t *testing.T, dir string, name string, original string, content *string, algorithm string) database.Document {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(original), 0644); err != nil {
+		t.Fatalf("Failed to write %s: %v", name, err)
+	}
+	hashes, err := database.CalculateFileHashes(path)
+	if err != nil {
+		t.Fatalf("Failed to hash %s: %v", name, err)
+	}
+	if content == nil {
+		os.Remove(path)
+	} else if err := os.WriteFile(path, []byte(*content), 0644); err != nil {
+		t.Fatalf("Failed to write %s: %v", name, err)
+	}
+	id, _ := database.CalculateUUID(time.Now())
+	return database.Document{
+		Name: name, Path: path, Folder: dir, IngressTime: time.Now(), ULID: id, DocumentType: ".txt",
+		Hash: hashes.Get(algorithm), HashAlgorithm: algorithm,
+	}
+}
+
+func TestCheckDocumentIntegrity(t *testing.T) {
+	dir := t.TempDir()
+	original, changed := "original contents", "bit rotted contents"
+	tests := []struct {
+		name     string
+		document database.Document
+		want     string
+	}{
+		{"intact sha256", storedDocument(t, dir, "intact.txt", original, &original, database.HashAlgorithmSHA256), ""},
+		{"intact md5", storedDocument(t, dir, "legacy.txt", original, &original, database.HashAlgorithmMD5), ""},
+		{"changed", storedDocument(t, dir, "changed.txt", original, &changed, database.HashAlgorithmSHA256), database.IntegrityMismatch},
+		{"changed md5", storedDocument(t, dir, "changed-legacy.txt", original, &changed, database.HashAlgorithmMD5), database.IntegrityMismatch},
+		{"missing", storedDocument(t, dir, "missing.txt", original, nil, database.HashAlgorithmSHA256), database.IntegrityMissing},
+		{"unknown algorithm", storedDocument(t, dir, "unknown.txt", original, &original, "crc32"), database.IntegrityUnreadable},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			issue := checkDocumentIntegrity(tt.document)
+			if tt.want == "" {
+				if issue != nil {
+					t.Errorf("Expected no issue, got %+v", issue)
+				}
+				return
+			}
+			if issue == nil {
+				t.Fatalf("Expected %s issue, got none", tt.want)
+			}
+			if issue.Issue != tt.want || issue.ExpectedHash != tt.document.Hash || issue.DocumentULID != tt.document.ULID.String() {
+				t.Errorf("Unexpected issue %+v", issue)
+			}
+			if tt.want == database.IntegrityMismatch && (issue.ActualHash == "" || issue.ActualHash == issue.ExpectedHash) {
+				t.Errorf("Expected the new hash to be recorded, got %q", issue.ActualHash)
+			}
+		})
+	}
+}
+
+func TestVerifyIntegrity(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	database.Logger = logger
+	Logger = logger
+
+	ephemeralDB, err := database.SetupEphemeralPostgresDatabase()
+	if err != nil {
+		t.Fatalf("Failed to set up ephemeral database: %v", err)
+	}
+	defer ephemeralDB.Close()
+	serverHandler := &ServerHandler{DB: ephemeralDB}
+
+	dir := t.TempDir()
+	original, changed := "original contents", "bit rotted contents"
+	for _, document := range []database.Document{
+		storedDocument(t, dir, "intact.txt", original, &original, database.HashAlgorithmSHA256),
+		storedDocument(t, dir, "changed.txt", original, &changed, database.HashAlgorithmSHA256),
+		storedDocument(t, dir, "missing.txt", original, nil, database.HashAlgorithmMD5),
+	} {
+		if err := ephemeralDB.SaveDocument(&document); err != nil {
+			t.Fatalf("Failed to save %s: %v", document.Name, err)
+		}
+	}
+
+	run, err := serverHandler.verifyIntegrity()
+	if err != nil {
+		t.Fatalf("verifyIntegrity returned error: %v", err)
+	}
+	if run.Checked != 3 || run.Mismatched != 1 || run.Missing != 1 || run.Unreadable != 0 || run.FinishedAt == nil {
+		t.Errorf("Unexpected run %+v", run)
+	}
+
+	issues, err := ephemeralDB.ListIntegrityIssues(run.ID)
+	if err != nil {
+		t.Fatalf("ListIntegrityIssues returned error: %v", err)
+	}
+	if len(issues) != 2 || issues[0].Name != "changed.txt" || issues[1].Name != "missing.txt" {
+		t.Fatalf("Expected issues for changed.txt and missing.txt, got %+v", issues)
+	}
+
+	t.Run("Recheck reads the current record", func(t *testing.T) {
+		moved := storedDocument(t, dir, "moved.txt", original, &original, database.HashAlgorithmSHA256)
+		if err := ephemeralDB.SaveDocument(&moved); err != nil {
+			t.Fatalf("Failed to save %s: %v", moved.Name, err)
+		}
+		stale := moved //as read before a move finished, the file is no longer at this path
+		stale.Path = filepath.Join(dir, "old", "moved.txt")
+		if issue := serverHandler.recheckDocumentIntegrity(stale, checkDocumentIntegrity(stale)); issue != nil {
+			t.Errorf("Expected no issue for a document that was moved, got %+v", issue)
+		}
+
+		purged := storedDocument(t, dir, "purged.txt", original, nil, database.HashAlgorithmSHA256)
+		if issue := serverHandler.recheckDocumentIntegrity(purged, checkDocumentIntegrity(purged)); issue != nil {
+			t.Errorf("Expected no issue for a document that was purged, got %+v", issue)
+		}
+	})
+
+	runs, err := ephemeralDB.ListIntegrityRuns(10)
+	if err != nil || len(runs) != 1 || runs[0].ID != run.ID {
+		t.Errorf("Expected the run to be listed, got %+v (%v)", runs, err)
+	}
+}
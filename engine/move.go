@@ -0,0 +1,144 @@
+package engine
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/drummonds/goEDMS/database"
+)
+
+// companionExtensions are appended to a document's path to find the files kept alongside it, which move with it
+var companionExtensions = []string{".yaml", ".txt"}
+
+var (
+	errMoveConflict       = errors.New("a file with the same name is already in the destination folder")
+	errFolderNotFound     = errors.New("folder does not exist")
+	errFolderOutsideStore = errors.New("folder is outside the document folder")
+)
+
+// fileMove is one file renamed by a document move
+type fileMove struct {
+	from string
+	to   string
+}
+
+// documentMove is a document as it was before a move and the files moved with it, so the move can be undone
+type documentMove struct {
+	document database.Document
+	files    []fileMove
+}
+
+// resolveDocumentFolder returns the absolute path of an existing folder in the document folder.  Folder is taken
+// relative to the document folder unless it is already an absolute path inside it, as stored in Document.Folder.
+func (serverHandler *ServerHandler) resolveDocumentFolder(folder string) (string, error) {
+	path, err := safePath(serverHandler.ServerConfig.DocumentPath, folder)
+	if errors.Is(err, errPathOutsideRoot) {
+		return "", errFolderOutsideStore
+	}
+	if err != nil {
+		return "", err
+	}
+	info, err := os.Stat(path)
+	if err != nil || !info.IsDir() {
+		return "", errFolderNotFound
+	}
+	return path, nil
+}
+
+// isInsideFolder reports whether path is root or below it
+func isInsideFolder(root string, path string) bool {
+	if !filepath.IsAbs(path) {
+		return false
+	}
+	rel, err := filepath.Rel(root, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// moveDocuments moves documents into folder, renaming their files on disk and updating their Path and Folder.
+// Either every document is moved or, if one fails, the documents already
+// moved are put back and the error returned.
+func (serverHandler *ServerHandler) moveDocuments(documents []database.Document, folder string) ([]database.Document, error) {
+	storeMu.Lock() //keep ingestion from copying into the folders while files are moving
+	defer storeMu.Unlock()
+
+	folder = filepath.ToSlash(folder)
+	var done []documentMove
+	rollback := func() {
+		for i := len(done) - 1; i >= 0; i-- {
+			serverHandler.undoDocumentMove(done[i])
+		}
+	}
+	moved := make([]database.Document, 0, len(documents))
+	for _, document := range documents {
+		destPath := filepath.ToSlash(filepath.Join(folder, filepath.Base(document.Path)))
+		if destPath == document.Path {
+			moved = append(moved, document)
+			continue
+		}
+		files, err := moveDocumentFiles(document.Path, destPath)
+		if err != nil {
+			rollback()
+			return nil, fmt.Errorf("unable to move %s: %w", document.Name, err)
+		}
+		err = serverHandler.DB.UpdateDocumentLocation(document.ULID.String(), destPath, folder)
+		if err != nil {
+			undoFileMoves(files)
+			rollback()
+			return nil, fmt.Errorf("unable to update location of %s: %w", document.Name, err)
+		}
+		done = append(done, documentMove{document: document, files: files})
+		Logger.Info("Moved document", "ulid", document.ULID.String(), "from", document.Path, "to", destPath)
+
+		document.Path = destPath
+		document.Folder = folder
+		moved = append(moved, document)
+	}
+	return moved, nil
+}
+
+// undoDocumentMove puts a moved document's files and database location back where they were
+func (serverHandler *ServerHandler) undoDocumentMove(move documentMove) {
+	undoFileMoves(move.files)
+	ulid := move.document.ULID.String()
+	if err := serverHandler.DB.UpdateDocumentLocation(ulid, move.document.Path, move.document.Folder); err != nil {
+		Logger.Error("Unable to restore document location after failed move", "ulid", ulid, "path", move.document.Path, "error", err)
+	}
+}
+
+// moveDocumentFiles renames a document file and its companions to destPath.  Nothing is moved if any destination
+// is taken, and if a rename fails the files already moved are put back.
+func moveDocumentFiles(sourcePath string, destPath string) ([]fileMove, error) {
+	pending := []fileMove{{from: sourcePath, to: destPath}}
+	for _, ext := range companionExtensions {
+		if _, err := os.Stat(sourcePath + ext); err == nil {
+			pending = append(pending, fileMove{from: sourcePath + ext, to: destPath + ext})
+		}
+	}
+	for _, move := range pending {
+		if _, err := os.Lstat(move.to); err == nil {
+			return nil, fmt.Errorf("%w: %s", errMoveConflict, filepath.Base(move.to))
+		}
+	}
+
+	moves := make([]fileMove, 0, len(pending))
+	for _, move := range pending {
+		if err := os.Rename(move.from, move.to); err != nil {
+			undoFileMoves(moves)
+			return nil, err
+		}
+		moves = append(moves, move)
+	}
+	return moves, nil
+}
+
+// undoFileMoves renames moved files back, most recent first
+func undoFileMoves(moves []fileMove) {
+	for i := len(moves) - 1; i >= 0; i-- {
+		if err := os.Rename(moves[i].to, moves[i].from); err != nil {
+			Logger.Error("Unable to move file back", "from", moves[i].to, "to", moves[i].from, "error", err)
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package engine
+
+import (
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/drummonds/goEDMS/config"
+)
+
+func TestMoveDocumentFiles(t *testing.T) {
+	Logger = slog.New(slog.NewTextHandler(os.Stdout, nil))
+	tempDir := t.TempDir()
+	from := filepath.Join(tempDir, "New")
+	to := filepath.Join(tempDir, "archive")
+	for _, dir := range []string{from, to} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatalf("Failed to create %s: %v", dir, err)
+		}
+	}
+	write := func(path string) {
+		t.Helper()
+		if err := os.WriteFile(path, []byte(filepath.Base(path)), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", path, err)
+		}
+	}
+	source := filepath.Join(from, "invoice.pdf")
+	dest := filepath.Join(to, "invoice.pdf")
+	write(source)
+	write(source + ".yaml")
+	write(source + ".txt")
+
+	t.Run("Companion conflict moves nothing", func(t *testing.T) {
+		write(dest + ".txt")
+		defer os.Remove(dest + ".txt")
+		if _, err := moveDocumentFiles(source, dest); !errors.Is(err, errMoveConflict) {
+			t.Fatalf("Expected errMoveConflict, got %v", err)
+		}
+		for _, path := range []string{source, source + ".yaml", source + ".txt"} {
+			if _, err := os.Stat(path); err != nil {
+				t.Errorf("Expected %s to stay put: %v", path, err)
+			}
+		}
+	})
+
+	t.Run("Move and undo", func(t *testing.T) {
+		moves, err := moveDocumentFiles(source, dest)
+		if err != nil {
+			t.Fatalf("moveDocumentFiles returned error: %v", err)
+		}
+		if len(moves) != 3 {
+			t.Errorf("Expected the document and 2 companions to move, got %d moves", len(moves))
+		}
+		for _, path := range []string{dest, dest + ".yaml", dest + ".txt"} {
+			if _, err := os.Stat(path); err != nil {
+				t.Errorf("Expected %s to exist: %v", path, err)
+			}
+		}
+
+		undoFileMoves(moves)
+		for _, path := range []string{source, source + ".yaml", source + ".txt"} {
+			if _, err := os.Stat(path); err != nil {
+				t.Errorf("Expected %s to be moved back: %v", path, err)
+			}
+		}
+		entries, _ := os.ReadDir(to)
+		if len(entries) != 0 {
+			t.Errorf("Expected destination to be empty after undo, found %d entries", len(entries))
+		}
+	})
+}
+
+func TestResolveDocumentFolder(t *testing.T) {
+	root := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(root, "archive", "2024"), 0755); err != nil {
+		t.Fatalf("Failed to create folders: %v", err)
+	}
+	serverHandler := &ServerHandler{ServerConfig: config.ServerConfig{DocumentPath: root}}
+
+	tests := []struct {
+		folder  string
+		want    string
+		wantErr error
+	}{
+		{"archive", filepath.Join(root, "archive"), nil},
+		{"/archive/2024", filepath.Join(root, "archive", "2024"), nil},
+		{filepath.Join(root, "archive"), filepath.Join(root, "archive"), nil},
+		{"missing", "", errFolderNotFound},
+		{"../elsewhere", "", errFolderOutsideStore},
+		{"archive/../../elsewhere", "", errFolderOutsideStore},
+	}
+	for _, tt := range tests {
+		got, err := serverHandler.resolveDocumentFolder(tt.folder)
+		if err != tt.wantErr || got != tt.want {
+			t.Errorf("resolveDocumentFolder(%q) = %q, %v; want %q, %v", tt.folder, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,326 @@
+package engine
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// maxOfficePartSize caps how much of a single XML part we will read from a package (protects against zip bombs)
+const maxOfficePartSize = 256 << 20
+
+// officeMetadata holds the core document properties stored inside Office/OpenDocument packages
+type officeMetadata struct {
+	Title   string
+	Author  string
+	Created string
+	Pages   int //0 when the package does not record a page count
+}
+
+// officeXMLRules describes how to pull text out of one flavour of package XML
+type officeXMLRules struct {
+	textElements  map[string]bool   //character data is only collected inside these elements
+	breakElements map[string]string //text emitted when one of these elements ends
+	emptyElements map[string]string //text emitted for self-closing elements such as tabs and line breaks
+}
+
+var (
+	wordprocessingRules = officeXMLRules{
+		textElements:  map[string]bool{"t": true},
+		breakElements: map[string]string{"p": "\n"},
+		emptyElements: map[string]string{"tab": "\t", "br": "\n", "cr": "\n"},
+	}
+	sharedStringRules = officeXMLRules{
+		textElements:  map[string]bool{"t": true},
+		breakElements: map[string]string{"si": "\n", "is": "\n"},
+	}
+	presentationRules = officeXMLRules{
+		textElements:  map[string]bool{"t": true},
+		breakElements: map[string]string{"p": "\n"},
+		emptyElements: map[string]string{"br": "\n"},
+	}
+	openDocumentRules = officeXMLRules{
+		textElements:  map[string]bool{"p": true, "h": true},
+		breakElements: map[string]string{"p": "\n", "h": "\n"},
+		emptyElements: map[string]string{"tab": "\t", "line-break": "\n", "s": " "},
+	}
+)
+
+// officeProcessing extracts the text and core properties from OOXML (.docx, .xlsx, .pptx) and OpenDocument (.odt, .ods, .odp, .odf) packages
+func officeProcessing(file string) (*string, officeMetadata, error) {
+	fileName := filepath.Base(file)
+	var metadata officeMetadata
+	Logger.Debug("Working on current file", "fileName", fileName)
+	archive, err := zip.OpenReader(file)
+	if err != nil {
+		Logger.Error("Unable to open office document package", "fileName", fileName, "error", err)
+		return nil, metadata, err
+	}
+	defer archive.Close()
+
+	var body string
+	switch officePackageKind(&archive.Reader) { //decided by the package contents so misnamed files still work
+	case "docx":
+		body, err = officeReadPart(&archive.Reader, "word/document.xml", wordprocessingRules)
+		if err == nil {
+			metadata, err = ooxmlCoreProperties(&archive.Reader)
+		}
+	case "xlsx":
+		body, err = xlsxText(&archive.Reader)
+		if err == nil {
+			metadata, err = ooxmlCoreProperties(&archive.Reader)
+		}
+	case "pptx":
+		body, err = pptxText(&archive.Reader)
+		if err == nil {
+			metadata, err = ooxmlCoreProperties(&archive.Reader)
+			metadata.Pages = len(officeNumberedParts(&archive.Reader, "ppt/slides/slide"))
+		}
+	case "odf":
+		body, err = officeReadPart(&archive.Reader, "content.xml", openDocumentRules)
+		if err == nil {
+			metadata, err = openDocumentMetadata(&archive.Reader)
+		}
+	default:
+		err = fmt.Errorf("unsupported office document type: %s", filepath.Ext(file))
+	}
+	if err != nil {
+		Logger.Error("Unable to extract text from office document", "fileName", fileName, "error", err)
+		return nil, metadata, err
+	}
+
+	fullText := strings.TrimSpace(body)
+	if fullText == "" {
+		err = errors.New("office document text result is empty")
+		Logger.Warn("Office document contains no text", "fileName", fileName, "error", err)
+		return nil, metadata, err
+	}
+	Logger.Info("Text processed from office document", "fileName", fileName)
+	return &fullText, metadata, nil
+}
+
+// officePackageKind works out which flavour of package we have from the parts it contains
+func officePackageKind(archive *zip.Reader) string {
+	switch {
+	case officeHasPart(archive, "word/document.xml"):
+		return "docx"
+	case officeHasPart(archive, "xl/workbook.xml"), officeHasPart(archive, "xl/sharedStrings.xml"):
+		return "xlsx"
+	case officeHasPart(archive, "ppt/presentation.xml"), len(officeNumberedParts(archive, "ppt/slides/slide")) > 0:
+		return "pptx"
+	case officeHasPart(archive, "content.xml"):
+		return "odf"
+	}
+	return ""
+}
+
+// xlsxText reads the shared string table plus any inline strings stored directly in the worksheets
+func xlsxText(archive *zip.Reader) (string, error) {
+	var builder strings.Builder
+	if officeHasPart(archive, "xl/sharedStrings.xml") {
+		sharedStrings, err := officeReadPart(archive, "xl/sharedStrings.xml", sharedStringRules)
+		if err != nil {
+			return "", err
+		}
+		builder.WriteString(sharedStrings)
+	}
+	for _, sheet := range officeNumberedParts(archive, "xl/worksheets/sheet") {
+		sheetText, err := officeReadPart(archive, sheet, sharedStringRules)
+		if err != nil {
+			return "", err
+		}
+		builder.WriteString(sheetText)
+	}
+	return builder.String(), nil
+}
+
+// pptxText reads every slide in presentation order
+func pptxText(archive *zip.Reader) (string, error) {
+	var builder strings.Builder
+	for _, slide := range officeNumberedParts(archive, "ppt/slides/slide") {
+		slideText, err := officeReadPart(archive, slide, presentationRules)
+		if err != nil {
+			return "", err
+		}
+		builder.WriteString(slideText)
+		builder.WriteString("\n")
+	}
+	return builder.String(), nil
+}
+
+// ooxmlCoreProperties reads title, author and creation date from docProps/core.xml and the page count from docProps/app.xml
+func ooxmlCoreProperties(archive *zip.Reader) (officeMetadata, error) {
+	metadata, err := officeReadMetadata(archive, "docProps/core.xml", map[string]string{
+		"title":   "title",
+		"creator": "author",
+		"created": "created",
+	})
+	if err != nil {
+		return metadata, err
+	}
+	appProperties, err := officeReadMetadata(archive, "docProps/app.xml", map[string]string{
+		"Pages": "pages",
+	})
+	metadata.Pages = appProperties.Pages
+	return metadata, err
+}
+
+// openDocumentMetadata reads title, author and creation date from meta.xml
+func openDocumentMetadata(archive *zip.Reader) (officeMetadata, error) {
+	return officeReadMetadata(archive, "meta.xml", map[string]string{
+		"title":           "title",
+		"initial-creator": "author",
+		"creator":         "author",
+		"creation-date":   "created",
+		"page-count":      "pages", //an attribute of <meta:document-statistic>
+	})
+}
+
+// officeReadMetadata pulls the requested elements (by local name) out of a properties part, a missing part is not an error
+func officeReadMetadata(archive *zip.Reader, partName string, fields map[string]string) (officeMetadata, error) {
+	var metadata officeMetadata
+	if !officeHasPart(archive, partName) {
+		return metadata, nil
+	}
+	part, err := archive.Open(partName)
+	if err != nil {
+		return metadata, err
+	}
+	defer part.Close()
+	decoder := xml.NewDecoder(io.LimitReader(part, maxOfficePartSize))
+	var current string
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return metadata, fmt.Errorf("unable to parse %s: %w", partName, err)
+		}
+		switch element := token.(type) {
+		case xml.StartElement:
+			current = fields[element.Name.Local]
+			for _, attr := range element.Attr {
+				metadata.set(fields[attr.Name.Local], attr.Value)
+			}
+		case xml.EndElement:
+			current = ""
+		case xml.CharData:
+			metadata.set(current, string(element))
+		}
+	}
+	return metadata, nil
+}
+
+// set fills in a metadata field the first time a value for it is seen
+func (metadata *officeMetadata) set(field string, value string) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return
+	}
+	switch {
+	case field == "title" && metadata.Title == "":
+		metadata.Title = value
+	case field == "author" && metadata.Author == "":
+		metadata.Author = value
+	case field == "created" && metadata.Created == "":
+		metadata.Created = value
+	case field == "pages" && metadata.Pages == 0:
+		metadata.Pages, _ = strconv.Atoi(value)
+	}
+}
+
+// officeReadPart streams one XML part of the package and returns its text according to the rules
+func officeReadPart(archive *zip.Reader, partName string, rules officeXMLRules) (string, error) {
+	part, err := archive.Open(partName)
+	if err != nil {
+		return "", fmt.Errorf("unable to open %s: %w", partName, err)
+	}
+	defer part.Close()
+
+	var builder strings.Builder
+	decoder := xml.NewDecoder(io.LimitReader(part, maxOfficePartSize))
+	textDepth := 0 //how many text elements we are currently nested inside
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", fmt.Errorf("unable to parse %s: %w", partName, err)
+		}
+		switch element := token.(type) {
+		case xml.StartElement:
+			if rules.textElements[element.Name.Local] {
+				textDepth++
+			}
+			if text, ok := rules.emptyElements[element.Name.Local]; ok {
+				builder.WriteString(officeRepeat(text, element))
+			}
+		case xml.EndElement:
+			if rules.textElements[element.Name.Local] && textDepth > 0 {
+				textDepth--
+			}
+			if text, ok := rules.breakElements[element.Name.Local]; ok {
+				builder.WriteString(text)
+			}
+		case xml.CharData:
+			if textDepth > 0 {
+				builder.Write(element)
+			}
+		}
+	}
+	return builder.String(), nil
+}
+
+// officeRepeat handles the ODF <text:s text:c="N"/> form which stands for N spaces
+func officeRepeat(text string, element xml.StartElement) string {
+	for _, attr := range element.Attr {
+		if attr.Name.Local == "c" {
+			if count, err := strconv.Atoi(attr.Value); err == nil && count > 0 && count < 1024 {
+				return strings.Repeat(text, count)
+			}
+		}
+	}
+	return text
+}
+
+func officeHasPart(archive *zip.Reader, partName string) bool {
+	for _, file := range archive.File {
+		if file.Name == partName {
+			return true
+		}
+	}
+	return false
+}
+
+// officeNumberedParts finds parts like ppt/slides/slide1.xml ... slideN.xml and returns them in numeric order
+func officeNumberedParts(archive *zip.Reader, prefix string) []string {
+	type numberedPart struct {
+		name   string
+		number int
+	}
+	var parts []numberedPart
+	for _, file := range archive.File {
+		if !strings.HasPrefix(file.Name, prefix) || !strings.HasSuffix(file.Name, ".xml") {
+			continue
+		}
+		number, err := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(file.Name, prefix), ".xml"))
+		if err != nil {
+			continue
+		}
+		parts = append(parts, numberedPart{name: file.Name, number: number})
+	}
+	sort.Slice(parts, func(i, j int) bool { return parts[i].number < parts[j].number })
+	names := make([]string, 0, len(parts))
+	for _, part := range parts {
+		names = append(names, part.name)
+	}
+	return names
+}
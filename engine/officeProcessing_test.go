@@ -0,0 +1,145 @@
+package engine
+
+import (
+	"archive/zip"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeTestPackage writes a zip package containing the given parts
+func writeTestPackage(t *testing.T, path string, parts map[string]string) {
+	t.Helper()
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatalf("Failed to create package: %v", err)
+	}
+	defer file.Close()
+	writer := zip.NewWriter(file)
+	for name, content := range parts {
+		part, err := writer.Create(name)
+		if err != nil {
+			t.Fatalf("Failed to create part %s: %v", name, err)
+		}
+		if _, err := part.Write([]byte(content)); err != nil {
+			t.Fatalf("Failed to write part %s: %v", name, err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatalf("Failed to close package: %v", err)
+	}
+}
+
+const testCoreProperties = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<cp:coreProperties xmlns:cp="http://schemas.openxmlformats.org/package/2006/metadata/core-properties" xmlns:dc="http://purl.org/dc/elements/1.1/" xmlns:dcterms="http://purl.org/dc/terms/">
+<dc:title>Quarterly Report</dc:title><dc:creator>Jane Smith</dc:creator>
+<dcterms:created>2024-03-01T09:00:00Z</dcterms:created></cp:coreProperties>`
+
+func TestOfficeProcessing(t *testing.T) {
+	Logger = slog.New(slog.NewTextHandler(os.Stdout, nil))
+	tempDir := t.TempDir()
+
+	tests := []struct {
+		name         string
+		fileName     string
+		parts        map[string]string
+		want         []string
+		wantMetadata officeMetadata
+	}{
+		{
+			name:     "Word document",
+			fileName: "letter.docx",
+			parts: map[string]string{
+				"word/document.xml": `<w:document xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main"><w:body>
+<w:p><w:r><w:t>Dear</w:t></w:r><w:r><w:t xml:space="preserve"> customer,</w:t></w:r></w:p>
+<w:p><w:r><w:t>Invoice</w:t><w:tab/><w:t>42</w:t></w:r></w:p></w:body></w:document>`,
+				"docProps/core.xml": testCoreProperties,
+			},
+			want:         []string{"Dear customer,\nInvoice\t42"},
+			wantMetadata: officeMetadata{Title: "Quarterly Report", Author: "Jane Smith", Created: "2024-03-01T09:00:00Z"},
+		},
+		{
+			name:     "Spreadsheet",
+			fileName: "budget.XLSX",
+			parts: map[string]string{
+				"xl/sharedStrings.xml":     `<sst xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><si><t>Rent</t></si><si><r><t>Elec</t></r><r><t>tricity</t></r></si></sst>`,
+				"xl/worksheets/sheet1.xml": `<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData><row><c t="inlineStr"><is><t>Inline note</t></is></c><c><v>12</v></c></row></sheetData></worksheet>`,
+			},
+			want: []string{"Rent\nElectricity", "Inline note"},
+		},
+		{
+			name:     "Presentation",
+			fileName: "deck.pptx",
+			parts: map[string]string{
+				"ppt/slides/slide10.xml": `<p:sld xmlns:p="p" xmlns:a="a"><p:cSld><a:p><a:r><a:t>Last slide</a:t></a:r></a:p></p:cSld></p:sld>`,
+				"ppt/slides/slide2.xml":  `<p:sld xmlns:p="p" xmlns:a="a"><p:cSld><a:p><a:r><a:t>First slide</a:t></a:r></a:p></p:cSld></p:sld>`,
+			},
+			want:         []string{"First slide\n\nLast slide"},
+			wantMetadata: officeMetadata{Pages: 2},
+		},
+		{
+			name:     "OpenDocument text",
+			fileName: "minutes.odt",
+			parts: map[string]string{
+				"content.xml": `<office:document-content xmlns:office="o" xmlns:text="t"><office:body><office:text>
+<text:h>Minutes</text:h><text:p>Attendees:<text:s text:c="2"/>Alice<text:line-break/>Bob</text:p></office:text></office:body></office:document-content>`,
+				"meta.xml": `<office:document-meta xmlns:office="o" xmlns:meta="m" xmlns:dc="d"><office:meta><dc:title>Board Meeting</dc:title><meta:initial-creator>Alice</meta:initial-creator><meta:creation-date>2024-05-02T10:00:00</meta:creation-date></office:meta></office:document-meta>`,
+			},
+			want:         []string{"Minutes\nAttendees:  Alice\nBob"},
+			wantMetadata: officeMetadata{Title: "Board Meeting", Author: "Alice", Created: "2024-05-02T10:00:00"},
+		},
+		{
+			name:     "OpenDocument spreadsheet",
+			fileName: "stock.ods",
+			parts: map[string]string{
+				"content.xml": `<office:document-content xmlns:office="o" xmlns:table="tb" xmlns:text="t"><office:body><office:spreadsheet><table:table><table:table-row><table:table-cell><text:p>Widgets</text:p></table:table-cell></table:table-row></table:table></office:spreadsheet></office:body></office:document-content>`,
+			},
+			want: []string{"Widgets"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(tempDir, tt.fileName)
+			writeTestPackage(t, path, tt.parts)
+			fullText, metadata, err := officeProcessing(path)
+			if err != nil {
+				t.Fatalf("officeProcessing returned error: %v", err)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(*fullText, want) {
+					t.Errorf("Expected text to contain %q, got %q", want, *fullText)
+				}
+			}
+			if metadata != tt.wantMetadata {
+				t.Errorf("Expected metadata %+v, got %+v", tt.wantMetadata, metadata)
+			}
+			if tt.wantMetadata.Title != "" && strings.Contains(*fullText, tt.wantMetadata.Title) {
+				t.Errorf("Expected the title to be kept out of the text, got %q", *fullText)
+			}
+		})
+	}
+
+	t.Run("Not a zip package", func(t *testing.T) {
+		path := filepath.Join(tempDir, "broken.docx")
+		if err := os.WriteFile(path, []byte("not a zip"), 0644); err != nil {
+			t.Fatalf("Failed to write file: %v", err)
+		}
+		if _, _, err := officeProcessing(path); err == nil {
+			t.Error("Expected error for invalid package, got nil")
+		}
+	})
+
+	t.Run("Empty document", func(t *testing.T) {
+		path := filepath.Join(tempDir, "empty.docx")
+		writeTestPackage(t, path, map[string]string{
+			"word/document.xml": `<w:document xmlns:w="w"><w:body><w:p/></w:body></w:document>`,
+			"docProps/core.xml": testCoreProperties,
+		})
+		if _, _, err := officeProcessing(path); err == nil {
+			t.Error("Expected error for document without body text, got nil")
+		}
+	})
+}
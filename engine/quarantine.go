@@ -0,0 +1,204 @@
+package engine
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/drummonds/goEDMS/database"
+	"github.com/oklog/ulid/v2"
+)
+
+// quarantineReportSuffix is appended to the quarantined file name to name its sidecar error report
+const quarantineReportSuffix = ".error.json"
+
+// errQuarantineNotFound is returned when no quarantined file has the requested id
+var errQuarantineNotFound = errors.New("quarantined file not found")
+
+// QuarantineReport is the sidecar written next to a quarantined file explaining why it was set aside
+type QuarantineReport struct {
+	ID            string                   `json:"id"`
+	FileName      string                   `json:"fileName"`     // name of the file when it was in ingress
+	OriginalPath  string                   `json:"originalPath"` // where a retry puts the file back
+	Source        string                   `json:"source"`
+	Reason        string                   `json:"reason"` // error from the last attempt
+	QuarantinedAt time.Time                `json:"quarantinedAt"`
+	JobID         int                      `json:"jobID"` // 0 if the ingest job was not recorded
+	Attempts      []database.IngestAttempt `json:"attempts"`
+	Size          int64                    `json:"size"` // filled in when listing, not stored
+}
+
+// quarantinedName is the name of the file inside the quarantine folder, prefixed with the id so names never collide
+func (report QuarantineReport) quarantinedName() string {
+	return report.ID + "_" + report.FileName
+}
+
+// shouldQuarantine reports whether a failed job has used up its attempts
+func (serverHandler *ServerHandler) shouldQuarantine(job *database.IngestJob) bool {
+	limit := serverHandler.ServerConfig.QuarantineAttempts //not stored in the database so read from the live config
+	return job != nil && limit > 0 && job.Attempts >= limit && serverHandler.ServerConfig.QuarantineFolder != ""
+}
+
+// ingestFailed records a failed attempt and quarantines the file once it has failed too many times, so it is not
+// picked up again on every ingress scan
+func (serverHandler *ServerHandler) ingestFailed(job *database.IngestJob, filePath string, jobErr error) {
+	serverHandler.updateIngestJob(job, database.IngestJobFailed, jobErr)
+	if !serverHandler.shouldQuarantine(job) {
+		return
+	}
+	report := QuarantineReport{
+		FileName:     filepath.Base(filePath),
+		OriginalPath: filePath,
+		Source:       job.Source,
+		Reason:       jobErr.Error(),
+		JobID:        job.ID,
+		Attempts:     job.History,
+	}
+	report, err := quarantineFile(serverHandler.ServerConfig.QuarantineFolder, filePath, report)
+	if err != nil {
+		Logger.Error("Unable to quarantine file, it will be retried on the next scan", "filePath", filePath, "error", err)
+		return
+	}
+	Logger.Warn("Quarantined file after repeated ingest failures", "filePath", filePath, "attempts", job.Attempts, "id", report.ID)
+	serverHandler.updateIngestJob(job, database.IngestJobQuarantined, nil)
+}
+
+// quarantineFile moves a file into the quarantine folder and writes its error report alongside it
+func quarantineFile(folder string, filePath string, report QuarantineReport) (QuarantineReport, error) {
+	now := time.Now()
+	id, err := database.CalculateUUID(now)
+	if err != nil {
+		return report, err
+	}
+	report.ID = id.String()
+	report.QuarantinedAt = now
+	if err := os.MkdirAll(folder, os.ModePerm); err != nil {
+		return report, err
+	}
+	quarantinedPath := filepath.Join(folder, report.quarantinedName())
+	reportPath := quarantinedPath + quarantineReportSuffix
+	if err := writeQuarantineReport(reportPath, report); err != nil {
+		return report, err
+	}
+	if err := os.Rename(filePath, quarantinedPath); err != nil {
+		os.Remove(reportPath)
+		return report, err
+	}
+	return report, nil
+}
+
+func writeQuarantineReport(reportPath string, report QuarantineReport) error {
+	report.Size = 0
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(reportPath, data, 0644)
+}
+
+// listQuarantine reads every error report in the quarantine folder, newest first
+func listQuarantine(folder string) ([]QuarantineReport, error) {
+	reports := make([]QuarantineReport, 0)
+	reportPaths, err := filepath.Glob(filepath.Join(folder, "*"+quarantineReportSuffix))
+	if err != nil {
+		return nil, err
+	}
+	for _, reportPath := range reportPaths {
+		report, err := readQuarantineReport(reportPath)
+		if err != nil {
+			Logger.Warn("Skipping unreadable quarantine report", "path", reportPath, "error", err)
+			continue
+		}
+		reports = append(reports, report)
+	}
+	sort.Slice(reports, func(i, j int) bool { return reports[i].QuarantinedAt.After(reports[j].QuarantinedAt) })
+	return reports, nil
+}
+
+func readQuarantineReport(reportPath string) (QuarantineReport, error) {
+	var report QuarantineReport
+	data, err := os.ReadFile(reportPath)
+	if err != nil {
+		return report, err
+	}
+	if err := json.Unmarshal(data, &report); err != nil {
+		return report, err
+	}
+	fileInfo, err := os.Stat(strings.TrimSuffix(reportPath, quarantineReportSuffix))
+	if err != nil {
+		return report, fmt.Errorf("quarantined file is missing: %w", err)
+	}
+	report.Size = fileInfo.Size()
+	return report, nil
+}
+
+// findQuarantined returns the report and path of a quarantined file, the id is checked to be a ULID so it can't
+// be used to reach outside the quarantine folder
+func findQuarantined(folder string, id string) (QuarantineReport, string, error) {
+	if _, err := ulid.ParseStrict(id); err != nil {
+		return QuarantineReport{}, "", errQuarantineNotFound
+	}
+	reportPaths, err := filepath.Glob(filepath.Join(folder, id+"_*"+quarantineReportSuffix))
+	if err != nil {
+		return QuarantineReport{}, "", err
+	}
+	if len(reportPaths) == 0 {
+		return QuarantineReport{}, "", errQuarantineNotFound
+	}
+	report, err := readQuarantineReport(reportPaths[0])
+	if err != nil {
+		return report, "", err
+	}
+	return report, strings.TrimSuffix(reportPaths[0], quarantineReportSuffix), nil
+}
+
+// quarantineRestorePath is where a retry puts a quarantined file back, files whose original location is outside
+// the ingress folder go in the root of the ingress folder
+func quarantineRestorePath(ingressPath string, report QuarantineReport) string {
+	relativePath, err := filepath.Rel(ingressPath, report.OriginalPath)
+	if err != nil || relativePath == ".." || strings.HasPrefix(relativePath, ".."+string(filepath.Separator)) || filepath.IsAbs(relativePath) {
+		return filepath.Join(ingressPath, report.FileName)
+	}
+	return report.OriginalPath
+}
+
+// restoreQuarantined moves a quarantined file to restorePath and removes its report
+func restoreQuarantined(folder string, id string, restorePath string) (QuarantineReport, error) {
+	report, quarantinedPath, err := findQuarantined(folder, id)
+	if err != nil {
+		return report, err
+	}
+	if _, err := os.Stat(restorePath); err == nil {
+		return report, fmt.Errorf("a file already exists at %s", restorePath)
+	}
+	if err := os.MkdirAll(filepath.Dir(restorePath), os.ModePerm); err != nil {
+		return report, err
+	}
+	if err := os.Rename(quarantinedPath, restorePath); err != nil {
+		return report, err
+	}
+	if err := os.Remove(quarantinedPath + quarantineReportSuffix); err != nil {
+		Logger.Warn("Unable to remove quarantine report", "id", id, "error", err)
+	}
+	return report, nil
+}
+
+// discardQuarantined deletes a quarantined file and its report
+func discardQuarantined(folder string, id string) (QuarantineReport, error) {
+	report, quarantinedPath, err := findQuarantined(folder, id)
+	if err != nil {
+		return report, err
+	}
+	if err := os.Remove(quarantinedPath); err != nil {
+		return report, err
+	}
+	if err := os.Remove(quarantinedPath + quarantineReportSuffix); err != nil {
+		return report, err
+	}
+	return report, nil
+}
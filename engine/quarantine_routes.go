@@ -0,0 +1,101 @@
+package engine
+
+import (
+	"net/http"
+
+	"github.com/drummonds/goEDMS/database"
+	"github.com/labstack/echo/v4"
+)
+
+// ListQuarantine returns the error report of every quarantined file, newest first
+func (serverHandler *ServerHandler) ListQuarantine(c echo.Context) error {
+	reports, err := listQuarantine(serverHandler.ServerConfig.QuarantineFolder)
+	if err != nil {
+		Logger.Error("Failed to list quarantine folder", "error", err)
+		return c.JSON(http.StatusInternalServerError, map[string]interface{}{
+			"error": "Failed to read quarantine folder",
+		})
+	}
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"files": reports,
+		"count": len(reports),
+	})
+}
+
+// RetryQuarantined moves a quarantined file back into the ingress folder and ingests it again in the background.
+// The job starts again with no attempts, so the file gets the usual number of tries before it is quarantined again.
+func (serverHandler *ServerHandler) RetryQuarantined(c echo.Context) error {
+	id := c.Param("id")
+	report, _, err := findQuarantined(serverHandler.ServerConfig.QuarantineFolder, id)
+	if err != nil {
+		return serverHandler.quarantineError(c, id, err)
+	}
+	restorePath := quarantineRestorePath(serverHandler.ServerConfig.IngressPath, report)
+	if !claimIngressFile(restorePath) {
+		return c.JSON(http.StatusConflict, map[string]interface{}{
+			"error": "A file with the same name is already being ingested",
+		})
+	}
+	if _, err := restoreQuarantined(serverHandler.ServerConfig.QuarantineFolder, id, restorePath); err != nil {
+		releaseIngressFile(restorePath)
+		Logger.Error("Failed to restore quarantined file", "id", id, "restorePath", restorePath, "error", err)
+		return c.JSON(http.StatusConflict, map[string]interface{}{
+			"error": "Unable to move file back to ingress: " + err.Error(),
+		})
+	}
+
+	Logger.Info("Retrying quarantined file via API", "id", id, "filePath", restorePath)
+	job := serverHandler.queueIngestJob(restorePath, report.Source)
+	var queued *database.IngestJob
+	if job != nil {
+		copied := *job //the background run updates job, respond with a copy
+		queued = &copied
+	}
+	go func() {
+		defer releaseIngressFile(restorePath)
+		serverHandler.processIngestJob(job, restorePath, report.Source)
+	}()
+
+	return c.JSON(http.StatusAccepted, map[string]interface{}{
+		"message":  "File moved back to ingress",
+		"filePath": restorePath,
+		"job":      queued,
+	})
+}
+
+// DiscardQuarantined deletes a quarantined file and its error report
+func (serverHandler *ServerHandler) DiscardQuarantined(c echo.Context) error {
+	id := c.Param("id")
+	report, err := discardQuarantined(serverHandler.ServerConfig.QuarantineFolder, id)
+	if err != nil {
+		return serverHandler.quarantineError(c, id, err)
+	}
+	Logger.Info("Discarded quarantined file via API", "id", id, "fileName", report.FileName)
+	if report.JobID != 0 { //the file is gone so the job can only ever be failed now
+		job, err := serverHandler.DB.GetIngestJob(report.JobID)
+		if err == nil && job.State == database.IngestJobQuarantined {
+			job.State = database.IngestJobFailed
+			job.Error = "Discarded from quarantine"
+			if err := serverHandler.DB.UpdateIngestJob(job); err != nil {
+				Logger.Error("Failed to update discarded ingest job", "id", job.ID, "error", err)
+			}
+		}
+	}
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"message": "Quarantined file discarded",
+		"id":      id,
+	})
+}
+
+// quarantineError responds to a failed quarantine lookup
+func (serverHandler *ServerHandler) quarantineError(c echo.Context, id string, err error) error {
+	if err == errQuarantineNotFound {
+		return c.JSON(http.StatusNotFound, map[string]interface{}{
+			"error": "Quarantined file not found",
+		})
+	}
+	Logger.Error("Failed to read quarantined file", "id", id, "error", err)
+	return c.JSON(http.StatusInternalServerError, map[string]interface{}{
+		"error": "Failed to read quarantined file",
+	})
+}
@@ -0,0 +1,101 @@
+package engine
+
+import (
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/drummonds/goEDMS/database"
+)
+
+func TestQuarantine(t *testing.T) {
+	Logger = slog.New(slog.NewTextHandler(os.Stdout, nil))
+	tempDir := t.TempDir()
+	ingressPath := filepath.Join(tempDir, "ingress")
+	folder := filepath.Join(tempDir, "quarantine")
+	filePath := filepath.Join(ingressPath, "scans", "broken.pdf")
+	if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+		t.Fatalf("Failed to create ingress folder: %v", err)
+	}
+	if err := os.WriteFile(filePath, []byte("not really a pdf"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	attempts := []database.IngestAttempt{
+		{Attempt: 1, StartedAt: time.Now().Add(-time.Minute), FinishedAt: time.Now().Add(-time.Minute), Error: "malformed PDF"},
+		{Attempt: 2, StartedAt: time.Now(), FinishedAt: time.Now(), Error: "malformed PDF"},
+	}
+	report, err := quarantineFile(folder, filePath, QuarantineReport{
+		FileName: "broken.pdf", OriginalPath: filePath, Source: "ingress", Reason: "malformed PDF", JobID: 7, Attempts: attempts,
+	})
+	if err != nil {
+		t.Fatalf("quarantineFile returned error: %v", err)
+	}
+	if _, err := os.Stat(filePath); !os.IsNotExist(err) {
+		t.Errorf("Expected file to be moved out of ingress, stat returned %v", err)
+	}
+
+	t.Run("List", func(t *testing.T) {
+		reports, err := listQuarantine(folder)
+		if err != nil {
+			t.Fatalf("listQuarantine returned error: %v", err)
+		}
+		if len(reports) != 1 {
+			t.Fatalf("Expected 1 quarantined file, got %d", len(reports))
+		}
+		got := reports[0]
+		if got.ID != report.ID || got.Reason != "malformed PDF" || got.JobID != 7 || len(got.Attempts) != 2 {
+			t.Errorf("Unexpected report %+v", got)
+		}
+		if got.Size != int64(len("not really a pdf")) {
+			t.Errorf("Expected size %d, got %d", len("not really a pdf"), got.Size)
+		}
+	})
+
+	t.Run("Unknown ids", func(t *testing.T) {
+		for _, id := range []string{"../ingress", "*", "01ARZ3NDEKTSV4RRFFQ69G5FAV"} {
+			if _, _, err := findQuarantined(folder, id); err != errQuarantineNotFound {
+				t.Errorf("%q: expected errQuarantineNotFound, got %v", id, err)
+			}
+		}
+	})
+
+	t.Run("Restore path", func(t *testing.T) {
+		if got := quarantineRestorePath(ingressPath, report); got != filePath {
+			t.Errorf("Expected file to go back to %s, got %s", filePath, got)
+		}
+		elsewhere := QuarantineReport{FileName: "upload.pdf", OriginalPath: filepath.Join(tempDir, "uploads", "upload.pdf")}
+		if got, want := quarantineRestorePath(ingressPath, elsewhere), filepath.Join(ingressPath, "upload.pdf"); got != want {
+			t.Errorf("Expected file outside ingress to go to %s, got %s", want, got)
+		}
+	})
+
+	t.Run("Restore", func(t *testing.T) {
+		if _, err := restoreQuarantined(folder, report.ID, filePath); err != nil {
+			t.Fatalf("restoreQuarantined returned error: %v", err)
+		}
+		if _, err := os.Stat(filePath); err != nil {
+			t.Errorf("Expected file back in ingress: %v", err)
+		}
+		reports, _ := listQuarantine(folder)
+		if len(reports) != 0 {
+			t.Errorf("Expected quarantine to be empty, got %d reports", len(reports))
+		}
+	})
+
+	t.Run("Discard", func(t *testing.T) {
+		report, err := quarantineFile(folder, filePath, QuarantineReport{FileName: "broken.pdf", OriginalPath: filePath})
+		if err != nil {
+			t.Fatalf("quarantineFile returned error: %v", err)
+		}
+		if _, err := discardQuarantined(folder, report.ID); err != nil {
+			t.Fatalf("discardQuarantined returned error: %v", err)
+		}
+		entries, _ := os.ReadDir(folder)
+		if len(entries) != 0 {
+			t.Errorf("Expected quarantine folder to be empty, found %d entries", len(entries))
+		}
+	})
+}
@@ -0,0 +1,57 @@
+package engine
+
+import (
+	"github.com/drummonds/goEDMS/database"
+)
+
+// rehashBatchSize is how many documents the rehash job reads from the database at a time
+const rehashBatchSize = 100
+
+// rehashStats is the outcome of a rehash run
+type rehashStats struct {
+	Rehashed   int //MD5 hash replaced with SHA-256
+	Skipped    int //file unreadable or the new hash could not be saved, MD5 hash kept
+	Mismatched int //file no longer matches its MD5 hash, kept so the change is not hidden
+}
+
+// rehashLegacyDocuments replaces the MD5 hash of documents stored before the switch to SHA-256.  A file is only
+// rehashed if it still matches its MD5 hash, so a file changed since it was stored keeps the evidence of that.
+func (serverHandler *ServerHandler) rehashLegacyDocuments() rehashStats {
+	var stats rehashStats
+	afterID := 0
+	for {
+		documents, err := serverHandler.DB.GetDocumentsByHashAlgorithm(database.HashAlgorithmMD5, afterID, rehashBatchSize)
+		if err != nil {
+			Logger.Error("Unable to read documents to rehash", "error", err)
+			break
+		}
+		if len(documents) == 0 {
+			break
+		}
+		for _, document := range documents {
+			afterID = document.StormID
+			hashes, err := database.CalculateFileHashes(document.Path)
+			if err != nil {
+				Logger.Warn("Unable to read document to rehash, keeping its MD5 hash", "ulid", document.ULID.String(), "path", document.Path, "error", err)
+				stats.Skipped++
+				continue
+			}
+			if hashes.MD5 != document.Hash {
+				Logger.Error("Document no longer matches its MD5 hash, not rehashing", "ulid", document.ULID.String(), "path", document.Path)
+				stats.Mismatched++
+				continue
+			}
+			err = serverHandler.DB.UpdateDocumentHash(document.ULID.String(), hashes.SHA256, database.HashAlgorithmSHA256)
+			if err != nil {
+				Logger.Error("Unable to save SHA-256 hash", "ulid", document.ULID.String(), "error", err)
+				stats.Skipped++
+				continue
+			}
+			stats.Rehashed++
+		}
+	}
+	if stats != (rehashStats{}) {
+		Logger.Info("Rehashed documents with SHA-256", "rehashed", stats.Rehashed, "skipped", stats.Skipped, "mismatched", stats.Mismatched)
+	}
+	return stats
+}
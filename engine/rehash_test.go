@@ -0,0 +1,80 @@
+package engine
+
+import (
+	"crypto/md5"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/drummonds/goEDMS/database"
+)
+
+func TestRehashLegacyDocuments(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	database.Logger = logger
+	Logger = logger
+
+	ephemeralDB, err := database.SetupEphemeralPostgresDatabase()
+	if err != nil {
+		t.Fatalf("Failed to set up ephemeral database: %v", err)
+	}
+	defer ephemeralDB.Close()
+	serverHandler := &ServerHandler{DB: ephemeralDB}
+
+	tempDir := t.TempDir()
+	// saveLegacy stores a document as it was before the switch to SHA-256, hashed with MD5 from originalContent
+	saveLegacy := func(name string, originalContent string, currentContent *string) *database.Document {
+		t.Helper()
+		path := filepath.Join(tempDir, name)
+		if currentContent != nil {
+			if err := os.WriteFile(path, []byte(*currentContent), 0644); err != nil {
+				t.Fatalf("Failed to write %s: %v", name, err)
+			}
+		}
+		id, _ := database.CalculateUUID(time.Now())
+		document := &database.Document{
+			Name: name, Path: path, Folder: tempDir, IngressTime: time.Now(), ULID: id, DocumentType: ".txt",
+			Hash: fmt.Sprintf("%x", md5.Sum([]byte(originalContent))), HashAlgorithm: database.HashAlgorithmMD5,
+		}
+		if err := ephemeralDB.SaveDocument(document); err != nil {
+			t.Fatalf("Failed to save %s: %v", name, err)
+		}
+		return document
+	}
+	unchanged, changed := "unchanged contents", "changed contents"
+	intact := saveLegacy("intact.txt", unchanged, &unchanged)
+	tampered := saveLegacy("tampered.txt", unchanged, &changed)
+	missing := saveLegacy("missing.txt", unchanged, nil)
+
+	stats := serverHandler.rehashLegacyDocuments()
+	if stats != (rehashStats{Rehashed: 1, Skipped: 1, Mismatched: 1}) {
+		t.Errorf("Expected 1 rehashed, 1 skipped and 1 mismatched, got %+v", stats)
+	}
+
+	hashes, _ := database.CalculateFileHashes(intact.Path)
+	for _, tt := range []struct {
+		document      *database.Document
+		wantHash      string
+		wantAlgorithm string
+	}{
+		{intact, hashes.SHA256, database.HashAlgorithmSHA256},
+		{tampered, tampered.Hash, database.HashAlgorithmMD5},
+		{missing, missing.Hash, database.HashAlgorithmMD5},
+	} {
+		stored, err := ephemeralDB.GetDocumentByULID(tt.document.ULID.String())
+		if err != nil {
+			t.Fatalf("Failed to fetch %s: %v", tt.document.Name, err)
+		}
+		if stored.Hash != tt.wantHash || stored.HashAlgorithm != tt.wantAlgorithm {
+			t.Errorf("%s: expected %s hash %s, got %s hash %s", tt.document.Name, tt.wantAlgorithm, tt.wantHash, stored.HashAlgorithm, stored.Hash)
+		}
+	}
+
+	// Nothing is left that can be rehashed, so a second run changes nothing
+	if stats := serverHandler.rehashLegacyDocuments(); stats.Rehashed != 0 {
+		t.Errorf("Expected second run to rehash nothing, got %+v", stats)
+	}
+}
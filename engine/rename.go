@@ -0,0 +1,96 @@
+package engine
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/drummonds/goEDMS/database"
+)
+
+var errInvalidName = errors.New("name must be a single file or folder name")
+
+// renamedFileName checks a new name for a document and gives it the document's extension if it does not already
+// have it, so a rename cannot change the document type
+func renamedFileName(name string, documentType string) (string, error) {
+	name = strings.TrimSpace(name)
+	if !validName(name) {
+		return "", errInvalidName
+	}
+	if documentType != "" && strings.ToLower(filepath.Ext(name)) != strings.ToLower(documentType) {
+		name += documentType
+	}
+	return name, nil
+}
+
+// validName reports whether name can be used as a file or folder name without leaving its folder
+func validName(name string) bool {
+	return name != "" && name != "." && name != ".." && !strings.ContainsAny(name, "/\\\x00")
+}
+
+// renameDocument renames a document's file and its companions in place and updates its Name and Path, putting the
+// files back if the database cannot be updated
+func (serverHandler *ServerHandler) renameDocument(document database.Document, name string) (*database.Document, error) {
+	name, err := renamedFileName(name, document.DocumentType)
+	if err != nil {
+		return nil, err
+	}
+	storeMu.Lock()
+	defer storeMu.Unlock()
+
+	newPath := filepath.ToSlash(filepath.Join(filepath.Dir(document.Path), name))
+	if newPath == document.Path {
+		return &document, nil
+	}
+	files, err := moveDocumentFiles(document.Path, newPath)
+	if err != nil {
+		return nil, err
+	}
+	if err := serverHandler.DB.RenameDocument(document.ULID.String(), name, newPath); err != nil {
+		undoFileMoves(files)
+		return nil, fmt.Errorf("unable to update document: %w", err)
+	}
+	Logger.Info("Renamed document", "ulid", document.ULID.String(), "from", document.Path, "to", newPath)
+
+	document.Name = name
+	document.Path = newPath
+	return &document, nil
+}
+
+// renameFolder renames a folder in the document folder and rewrites the location of every document in it, renaming
+// the folder back if the database cannot be updated.  It returns the new path of the folder and the number of
+// documents moved with it.
+func (serverHandler *ServerHandler) renameFolder(folder string, name string) (string, int, error) {
+	name = strings.TrimSpace(name)
+	if !validName(name) {
+		return "", 0, errInvalidName
+	}
+	folder, err := serverHandler.resolveDocumentFolder(folder)
+	if err != nil {
+		return "", 0, err
+	}
+	if folder == filepath.Clean(serverHandler.ServerConfig.DocumentPath) {
+		return "", 0, errFolderOutsideStore //the document folder itself is configured, not renamed
+	}
+	storeMu.Lock()
+	defer storeMu.Unlock()
+
+	newFolder := filepath.Join(filepath.Dir(folder), name)
+	if _, err := os.Lstat(newFolder); err == nil {
+		return "", 0, fmt.Errorf("%w: %s", errMoveConflict, name)
+	}
+	if err := os.Rename(folder, newFolder); err != nil {
+		return "", 0, err
+	}
+	moved, err := serverHandler.DB.RenameFolder(filepath.ToSlash(folder), filepath.ToSlash(newFolder))
+	if err != nil {
+		if undoErr := os.Rename(newFolder, folder); undoErr != nil {
+			Logger.Error("Unable to rename folder back", "from", newFolder, "to", folder, "error", undoErr)
+		}
+		return "", 0, fmt.Errorf("unable to update documents: %w", err)
+	}
+	Logger.Info("Renamed folder", "from", folder, "to", newFolder, "documents", len(moved))
+	return newFolder, len(moved), nil
+}
@@ -0,0 +1,62 @@
+package engine
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/drummonds/goEDMS/database"
+	"github.com/labstack/echo/v4"
+)
+
+// RenameDocument renames a document to ?name, on disk and in the database.  The document keeps its extension, it
+// is added to the new name if missing.
+func (serverHandler *ServerHandler) RenameDocument(c echo.Context) error {
+	document, httpStatus, err := database.FetchDocument(c.Param("id"), serverHandler.DB)
+	if err != nil {
+		return c.JSON(httpStatus, map[string]interface{}{
+			"error": "Document not found",
+		})
+	}
+	if document.DeletedAt != nil {
+		return c.JSON(http.StatusNotFound, map[string]interface{}{
+			"error": errAlreadyTrashed.Error(),
+		})
+	}
+	renamed, err := serverHandler.renameDocument(document, c.QueryParam("name"))
+	if err != nil {
+		return serverHandler.renameError(c, document.Path, err)
+	}
+	return c.JSON(http.StatusOK, renamed)
+}
+
+// RenameFolder renames the folder ?folder (relative to the document folder) to ?name, moving every document in it
+func (serverHandler *ServerHandler) RenameFolder(c echo.Context) error {
+	folder := c.QueryParam("folder")
+	newFolder, count, err := serverHandler.renameFolder(folder, c.QueryParam("name"))
+	if err != nil {
+		return serverHandler.renameError(c, folder, err)
+	}
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"message":   "Folder renamed",
+		"folder":    newFolder,
+		"documents": count,
+	})
+}
+
+// renameError responds to a failed rename
+func (serverHandler *ServerHandler) renameError(c echo.Context, path string, err error) error {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, errInvalidName), errors.Is(err, errFolderOutsideStore):
+		status = http.StatusBadRequest
+	case errors.Is(err, errFolderNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, errMoveConflict):
+		status = http.StatusConflict
+	default:
+		Logger.Error("Rename failed", "path", path, "error", err)
+	}
+	return c.JSON(status, map[string]interface{}{
+		"error": err.Error(),
+	})
+}
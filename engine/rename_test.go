@@ -0,0 +1,29 @@
+package engine
+
+import "testing"
+
+func TestRenamedFileName(t *testing.T) {
+	tests := []struct {
+		name         string
+		documentType string
+		want         string
+		wantErr      error
+	}{
+		{"Electricity bill", ".pdf", "Electricity bill.pdf", nil},
+		{"Electricity bill.pdf", ".pdf", "Electricity bill.pdf", nil},
+		{"Electricity bill.PDF", ".pdf", "Electricity bill.PDF", nil},
+		{"bill.v2", ".pdf", "bill.v2.pdf", nil},
+		{"  padded  ", ".txt", "padded.txt", nil},
+		{"notes", "", "notes", nil},
+		{"", ".pdf", "", errInvalidName},
+		{"..", ".pdf", "", errInvalidName},
+		{"../escape", ".pdf", "", errInvalidName},
+		{`sub\name`, ".pdf", "", errInvalidName},
+	}
+	for _, tt := range tests {
+		got, err := renamedFileName(tt.name, tt.documentType)
+		if got != tt.want || err != tt.wantErr {
+			t.Errorf("renamedFileName(%q, %q) = %q, %v; want %q, %v", tt.name, tt.documentType, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
@@ -1,10 +1,10 @@
 package engine
 
 import (
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
-	"net/url"
 	"os"
 	"path/filepath"
 	"strconv"
@@ -54,35 +54,26 @@ type fileTreeStruct struct {
 	ChildrenIDs []string `json:"childrenIDs"`
 	FullPath    string   `json:"fullPath"`
 	FileURL     string   `json:"fileURL"`
+	Snippet     string   `json:"snippet,omitempty"` //search results only, matched words wrapped in <mark></mark>
+	Rank        float64  `json:"rank,omitempty"`
+	Page        int      `json:"page,omitempty"`       //best matching page, when the document has per page text
+	Similarity  float64  `json:"similarity,omitempty"` //fuzzy search only, 0 to 1
 }
 
-// AddDocumentViewRoutes adds all of the current documents to an echo route
-func (serverHandler *ServerHandler) AddDocumentViewRoutes() error {
-	documents, err := database.FetchAllDocuments(serverHandler.DB)
-	if err != nil {
-		return err
-	}
-	for _, document := range *documents {
-		documentURL := "/document/view/" + document.ULID.String()
-		serverHandler.Echo.File(documentURL, document.Path)
-	}
-	return nil
-}
-
-// DeleteFile deletes a folder or file from the database (and all children if folder) (and on disc and from bleve search if document)
+// DeleteFile moves a document, or every document in a folder, to the trash.  They are removed for good when the trash
+// is emptied.
 func (serverHandler *ServerHandler) DeleteFile(context echo.Context) error {
-	var err error
 	params := context.QueryParams()
 	ulidStr := params.Get("id")
-	path := params.Get("path")
-	path = filepath.Join(serverHandler.ServerConfig.DocumentPath, path)
-	path, err = filepath.Abs(path)
-	if err != nil {
-		return context.JSON(http.StatusInternalServerError, err)
+	path, err := safePath(serverHandler.ServerConfig.DocumentPath, params.Get("path"))
+	if err == nil && path == filepath.Clean(serverHandler.ServerConfig.DocumentPath) { //never delete the whole document folder
+		err = errPathOutsideRoot
 	}
-	fmt.Println("PATH", path)
-	if path == serverHandler.ServerConfig.DocumentPath { //TODO: IMPORTANT: Make this MUCH safer so we don't literally purge everything in root lol (side note, yes I did discover that the hard way)
-		return context.JSON(http.StatusInternalServerError, err)
+	if err != nil {
+		Logger.Warn("Refusing to delete path", "path", params.Get("path"), "error", err)
+		return context.JSON(http.StatusBadRequest, map[string]interface{}{
+			"error": err.Error(),
+		})
 	}
 
 	fileInfo, err := os.Stat(path)
@@ -90,115 +81,242 @@ func (serverHandler *ServerHandler) DeleteFile(context echo.Context) error {
 		Logger.Error("Unable to get information for file", "path", path, "error", err)
 		return context.JSON(http.StatusNotFound, err)
 	}
-	if fileInfo.IsDir() { //If a directory, just delete it and all children
-		err = DeleteFile(path)
+	if fileInfo.IsDir() { //If a directory, move every document in it to the trash and remove it once empty
+		trashed, err := serverHandler.trashFolder(path)
 		if err != nil {
-			Logger.Error("Unable to delete folder from document filesystem", "path", path, "error", err)
-			return context.JSON(http.StatusInternalServerError, err)
-		}
-		return context.JSON(http.StatusOK, "Folder Deleted")
+			status := http.StatusInternalServerError
+			switch {
+			case errors.Is(err, errFolderOutsideStore):
+				status = http.StatusBadRequest
+			case errors.Is(err, errMoveConflict):
+				status = http.StatusConflict
+			default:
+				Logger.Error("Unable to move folder to the trash", "path", path, "error", err)
+			}
+			return context.JSON(status, map[string]interface{}{
+				"error": err.Error(),
+			})
+		}
+		if len(trashed) > 0 {
+			go func() {
+				if err := serverHandler.DB.RecalculateAllWordFrequencies(); err != nil {
+					Logger.Error("Word cloud recalculation failed after folder deletion", "error", err)
+				}
+			}()
+		}
+		documents := make([]trashedDocument, 0, len(trashed)) //not the whole document, which includes its full text
+		for _, document := range trashed {
+			_, statErr := os.Stat(document.Path)
+			documents = append(documents, trashedDocument{
+				ULID: document.ULID.String(), Name: document.Name, TrashPath: document.Path, FileMissing: os.IsNotExist(statErr),
+			})
+		}
+		_, statErr := os.Stat(path)
+		return context.JSON(http.StatusOK, map[string]interface{}{
+			"message":       "Folder Deleted",
+			"folder":        path,
+			"documents":     documents,
+			"count":         len(trashed),
+			"folderRemoved": os.IsNotExist(statErr),
+		})
 	}
 	document, _, err := database.FetchDocument(ulidStr, serverHandler.DB)
 	if err != nil {
 		Logger.Error("Unable to delete folder from document filesystem", "path", path, "error", err)
 		return context.JSON(http.StatusNotFound, err)
 	}
-	err = database.DeleteDocument(ulidStr, serverHandler.DB)
-	if err != nil {
-		Logger.Error("Unable to delete document from database", "name", document.Name, "error", err)
-		return context.JSON(http.StatusNotFound, err)
+	_, err = serverHandler.trashDocument(document)
+	if errors.Is(err, errAlreadyTrashed) {
+		return context.JSON(http.StatusNotFound, err.Error())
 	}
-	err = DeleteFile(document.Path)
 	if err != nil {
-		Logger.Error("Unable to delete document from file system", "path", document.Path, "error", err)
-		return context.JSON(http.StatusNotFound, err)
+		Logger.Error("Unable to move document to the trash", "name", document.Name, "error", err)
+		return context.JSON(http.StatusInternalServerError, err)
 	}
-	// PostgreSQL full-text search index is automatically updated via trigger when document is deleted
+	// Trashed documents are left out of full-text search until they are restored
 	return context.JSON(http.StatusOK, "Document Deleted")
 }
 
-// UploadDocuments handles documents uploaded from the frontend
+// UploadDocuments streams every "file" part of a multipart upload into the ingress folder, below the folder given
+// in an earlier "path" part, and ingests each one.  Every file gets its own result with the ULID of the stored
+// document or the error that stopped it.
 func (serverHandler *ServerHandler) UploadDocuments(context echo.Context) error {
-	request := context.Request()
-	uploadPath := request.FormValue("path")
-	file, fileHeader, err := request.FormFile("file")
+	reader, err := context.Request().MultipartReader()
 	if err != nil {
-		fmt.Println("Problem finding file, ", err)
-		return err
+		return context.JSON(http.StatusBadRequest, map[string]interface{}{
+			"error": "Expected a multipart/form-data upload",
+		})
 	}
-	defer file.Close()
-	//Upload it to the ingress folder so if there is an issue it will stick there and not in the documents folder which will cause issues.
-	path := filepath.ToSlash(serverHandler.ServerConfig.IngressPath + "/" + uploadPath + fileHeader.Filename)
-	_, err = os.Stat(filepath.Dir(path)) //since this is the ingress folder we MAY need to create the directory path.
-	if err != nil {
-		if os.IsNotExist(err) {
-			err := os.MkdirAll(filepath.Dir(path), os.ModePerm)
+	folder := ""
+	results := make([]uploadResult, 0)
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			Logger.Error("Unable to read upload", "error", err)
+			return context.JSON(http.StatusBadRequest, map[string]interface{}{
+				"error": "Malformed upload: " + err.Error(),
+				"files": results,
+			})
+		}
+		switch {
+		case part.FormName() == "path" && part.FileName() == "":
+			value, _ := io.ReadAll(io.LimitReader(part, 4096))
+			folder, err = uploadFolder(string(value))
 			if err != nil {
-				Logger.Error("Unable to create filepath for upload", "path", path, "error", err)
-				return err
+				part.Close()
+				return context.JSON(http.StatusBadRequest, map[string]interface{}{
+					"error": "path must be a folder inside the ingress folder",
+					"files": results,
+				})
 			}
+		case part.FormName() == "file":
+			result := serverHandler.ingestUpload(part.FileName(), folder, func(filePath string) error {
+				return writeUpload(part, filePath, serverHandler.uploadMaxSize())
+			})
+			Logger.Info("Uploaded file", "fileName", result.FileName, "ulid", result.ULID, "error", result.Error)
+			results = append(results, result)
+		}
+		part.Close()
+	}
+	if len(results) == 0 {
+		return context.JSON(http.StatusBadRequest, map[string]interface{}{
+			"error": "No file in upload",
+		})
+	}
+
+	stored := 0
+	for _, result := range results {
+		if result.Error == "" {
+			stored++
 		}
 	}
-	Logger.Debug("Creating path for file upload to ingress", "dir", filepath.Dir(path))
-	body, err := io.ReadAll(file) //get the file, write it to the filesystem
-	err = os.WriteFile(path, body, 0644)
-	if err != nil {
-		Logger.Error("Unable to write uploaded file", "path", path, "error", err)
-		return err
+	status := http.StatusOK
+	if stored == 0 { //nothing worked so report why the first file failed
+		status = results[0].status
 	}
-	serverHandler.ingressDocument(path, "upload") //ingress the document into the database
-	return context.JSON(http.StatusOK, path)
+	return context.JSON(status, map[string]interface{}{
+		"files":  results,
+		"stored": stored,
+		"failed": len(results) - stored,
+	})
 }
 
-// MoveDocuments will accept an API call from the frontend to move a document or documents
+// MoveDocuments will accept an API call from the frontend to move a document or documents.  The files (and any
+// .yaml/.txt companions) are moved on disk with the database, if any document cannot be moved none are.
 func (serverHandler *ServerHandler) MoveDocuments(context echo.Context) error {
-	var docIDs url.Values
-	var newFolder string
-	docIDs = context.QueryParams()
-	newFolder = docIDs.Get("folder")
-	fmt.Println("newfolder: ", newFolder)
-	fmt.Println("ID's: ", docIDs["id"])
-	for _, docID := range docIDs["id"] { //fetching all the needed documents
-		//document, httpStatus, err := database.FetchDocument(docID, serverHandler.DB)
-		//if err != nil {
-		//	Logger.Error("GetDocument API call failed (MoveDocuments)", "error", err)
-		//	return context.JSON(httpStatus, err)
-		//}
-		//foundDocuments = append(foundDocuments, document)
-		httpStatus, err := database.UpdateDocumentField(docID, "Folder", newFolder, serverHandler.DB)
-		if err != nil {
-			Logger.Error("GetDocument API call failed (MoveDocuments)", "error", err)
-			return context.JSON(httpStatus, err)
+	params := context.QueryParams()
+	docIDs := params["id"]
+	if len(docIDs) == 0 || params.Get("folder") == "" {
+		return context.JSON(http.StatusBadRequest, map[string]interface{}{
+			"error": "folder and at least one id are required",
+		})
+	}
+	folder, err := serverHandler.resolveDocumentFolder(params.Get("folder"))
+	if err != nil {
+		status := http.StatusNotFound
+		if err == errFolderOutsideStore {
+			status = http.StatusBadRequest
+		}
+		return context.JSON(status, map[string]interface{}{
+			"error": err.Error(),
+		})
+	}
+	documents, httpStatus, err := database.FetchDocuments(docIDs, serverHandler.DB)
+	if err != nil {
+		Logger.Error("Unable to fetch documents to move", "error", err)
+		return context.JSON(httpStatus, map[string]interface{}{
+			"error": "Document not found",
+		})
+	}
+	for _, document := range documents {
+		if document.DeletedAt != nil {
+			return context.JSON(http.StatusNotFound, map[string]interface{}{
+				"error": errAlreadyTrashed.Error(),
+			})
+		}
+	}
+
+	moved, err := serverHandler.moveDocuments(documents, folder)
+	if err != nil {
+		Logger.Error("Move failed, documents left where they were", "folder", folder, "error", err)
+		status := http.StatusInternalServerError
+		if errors.Is(err, errMoveConflict) {
+			status = http.StatusConflict
 		}
+		return context.JSON(status, map[string]interface{}{
+			"error": err.Error(),
+		})
 	}
-	return context.JSON(http.StatusOK, "Ok")
+	return context.JSON(http.StatusOK, map[string]interface{}{
+		"message":   "Documents moved",
+		"folder":    folder,
+		"documents": moved,
+	})
 }
 
-// SearchDocuments will take the search terms and search all documents using PostgreSQL full-text search
+// SearchDocuments will take the search terms and search all documents using PostgreSQL full-text search.  The
+// filters in searchFilter narrow the results further, or can be used without a term.  When nothing matches exactly
+// the search is tried again with fuzzy matching, unless the fuzzy parameter is given.  With facets=true the results
+// come in a searchResponse along with their facet counts.
 func (serverHandler *ServerHandler) SearchDocuments(context echo.Context) error {
-	searchParams := context.QueryParams()
-	searchTerm := searchParams.Get("term")
-	if searchTerm == "" {
+	filter, err := serverHandler.searchFilter(context.QueryParams())
+	if err != nil {
+		return context.JSON(http.StatusBadRequest, map[string]interface{}{
+			"error": err.Error(),
+		})
+	}
+	if !hasSearchCriteria(filter) {
 		return context.JSON(http.StatusNotFound, "Empty search term")
 	}
 
-	Logger.Debug("Performing PostgreSQL full-text search", "searchTerm", searchTerm)
-	documents, err := serverHandler.DB.SearchDocuments(searchTerm)
+	withFacets, _ := strconv.ParseBool(context.QueryParam("facets"))
+
+	Logger.Debug("Performing PostgreSQL full-text search", "searchTerm", filter.Term, "filter", filter)
+	var documents []database.SearchResult
+	var facets database.SearchFacets
+	search := func() {
+		if withFacets {
+			documents, facets, err = serverHandler.DB.SearchDocumentsWithFacets(filter)
+		} else {
+			documents, err = serverHandler.DB.SearchDocumentsFiltered(filter)
+		}
+	}
+	search()
+	// OCR errors keep words out of the full-text index, so unless fuzzy was asked about try similar words
+	if err == nil && len(documents) == 0 && filter.Term != "" && context.QueryParam("fuzzy") == "" {
+		Logger.Debug("No exact matches, searching for similar words", "searchTerm", filter.Term)
+		filter.Fuzzy = true
+		search()
+	}
+	if errors.Is(err, database.ErrInvalidQuery) {
+		return context.JSON(http.StatusBadRequest, map[string]interface{}{
+			"error": err.Error(),
+		})
+	}
 	if err != nil {
 		Logger.Error("Search failed", "error", err)
 		return context.JSON(http.StatusInternalServerError, err)
 	}
 
 	if len(documents) == 0 {
-		Logger.Info("Search returned no results", "searchTerm", searchTerm)
+		Logger.Info("Search returned no results", "searchTerm", filter.Term)
 		return context.JSON(http.StatusNoContent, nil)
 	}
 
-	fullResults, err := convertDocumentsToFileTree(documents)
+	fullResults, err := convertSearchResultsToFileTree(documents)
 	if err != nil {
 		Logger.Error("Unable to convert search results to file tree", "error", err)
 		return context.JSON(http.StatusNotFound, err)
 	}
+	if withFacets {
+		return context.JSON(http.StatusOK, searchResponse{
+			FileSystem: *fullResults,
+			Facets:     serverHandler.relativeFacets(facets),
+		})
+	}
 	return context.JSON(http.StatusOK, fullResults)
 }
 
@@ -245,10 +363,11 @@ func (serverHandler *ServerHandler) GetDocumentFileSystem(context echo.Context)
 
 }
 
-func convertDocumentsToFileTree(documents []database.Document) (fullFileTree *[]fileTreeStruct, err error) {
+func convertSearchResultsToFileTree(results []database.SearchResult) (fullFileTree *[]fileTreeStruct, err error) {
 	var fileTree []fileTreeStruct
 	var currentFile fileTreeStruct
-	for _, document := range documents {
+	for _, result := range results {
+		document := result.Document
 		documentInfo, err := os.Stat(document.Path)
 		if err != nil {
 			return nil, err
@@ -263,6 +382,10 @@ func convertDocumentsToFileTree(documents []database.Document) (fullFileTree *[]
 		currentFile.FullPath = document.Path
 		currentFile.FileURL = document.URL
 		currentFile.ParentID = "SearchResults"
+		currentFile.Snippet = result.Snippet
+		currentFile.Rank = result.Rank
+		currentFile.Page = result.Page
+		currentFile.Similarity = result.Similarity
 		fileTree = append(fileTree, currentFile)
 	}
 	childrenIDs := func() []string {
@@ -420,11 +543,19 @@ func (serverHandler *ServerHandler) CreateFolder(context echo.Context) error {
 	params := context.QueryParams()
 	folderName := params.Get("folder")
 	folderPath := params.Get("path")
-	fullFolder := filepath.Join(folderPath, folderName)
-	fullFolder = filepath.Join(serverHandler.ServerConfig.DocumentPath, fullFolder)
-	fullFolder = filepath.Clean(fullFolder)
-	fmt.Println("fullfolder: ", fullFolder, " folderName: ", folderName, "Path: ", folderPath)
-	err := os.Mkdir(fullFolder, os.ModePerm)
+	if !validName(folderName) {
+		return context.JSON(http.StatusBadRequest, map[string]interface{}{
+			"error": errInvalidName.Error(),
+		})
+	}
+	fullFolder, err := safePath(serverHandler.ServerConfig.DocumentPath, folderPath+"/"+folderName)
+	if err != nil {
+		Logger.Warn("Refusing to create folder", "path", folderPath, "folder", folderName, "error", err)
+		return context.JSON(http.StatusBadRequest, map[string]interface{}{
+			"error": err.Error(),
+		})
+	}
+	err = os.Mkdir(fullFolder, os.ModePerm)
 	if err != nil {
 		Logger.Error("Unable to create directory", "error", err)
 		return err
@@ -581,14 +712,15 @@ func (serverHandler *ServerHandler) GetAboutInfo(c echo.Context) error {
 	}
 
 	aboutInfo := map[string]interface{}{
-		"version":       gitVersion,
-		"ocrConfigured": ocrConfigured,
-		"ocrPath":       serverHandler.ServerConfig.TesseractPath,
-		"databaseType":  dbType,
-		"databaseHost":  dbHost,
-		"databasePort":  dbPort,
-		"databaseName":  dbName,
-		"isEphemeral":   isEphemeral,
+		"version":             gitVersion,
+		"ocrConfigured":       ocrConfigured,
+		"ocrPath":             serverHandler.ServerConfig.TesseractPath,
+		"databaseType":        dbType,
+		"databaseHost":        dbHost,
+		"databasePort":        dbPort,
+		"databaseName":        dbName,
+		"isEphemeral":         isEphemeral,
+		"supportedExtensions": SupportedExtensions(),
 	}
 
 	return c.JSON(http.StatusOK, aboutInfo)
@@ -765,14 +897,7 @@ func (serverHandler *ServerHandler) findOrphanedDocuments(documents []database.D
 
 // isProcessableDocument checks if a file is a document type that can be processed
 func isProcessableDocument(path string) bool {
-	ext := strings.ToLower(filepath.Ext(path))
-	processableExts := []string{".pdf", ".txt", ".rtf", ".doc", ".docx", ".odf", ".tiff", ".jpg", ".jpeg", ".png"}
-	for _, validExt := range processableExts {
-		if ext == validExt {
-			return true
-		}
-	}
-	return false
+	return isSupportedExtension(path)
 }
 
 // moveOrphanToIngress moves an orphaned document (and its companion files) to the ingress folder
@@ -0,0 +1,87 @@
+package engine
+
+import (
+	"errors"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+var errPathOutsideRoot = errors.New("path is outside the folder it must stay in")
+
+// safePath resolves a client supplied path against root, the document, ingress or another configured folder, and
+// refuses anything that would end up outside it.  The path is taken relative to root unless it is already an
+// absolute path inside root, as stored in the database, so a leading / means root itself ("/invoices/2024") and
+// /etc/passwd is root/etc/passwd.  Backslashes count as separators whatever the platform, and a path that would
+// climb out once percent-decoded is refused too, in case something along the way decodes it again.
+// Symlinks are followed as far as the path exists so a link inside root cannot lead out of it.  The returned path is
+// cleaned but not symlink resolved, it is root itself when userPath is empty.
+func safePath(root string, userPath string) (string, error) {
+	root, err := filepath.Abs(root)
+	if err != nil {
+		return "", err
+	}
+	if strings.ContainsRune(userPath, 0) {
+		return "", errPathOutsideRoot
+	}
+	for _, candidate := range []string{userPath, decodedPath(userPath)} {
+		if _, err := joinInRoot(root, candidate); err != nil {
+			return "", err
+		}
+	}
+	path, _ := joinInRoot(root, userPath)
+	if err := checkSymlinks(root, path); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// joinInRoot is the lexical part of safePath
+func joinInRoot(root string, userPath string) (string, error) {
+	userPath = filepath.FromSlash(strings.ReplaceAll(userPath, `\`, "/"))
+	path := filepath.Clean(userPath)
+	if !isInsideFolder(root, path) {
+		path = filepath.Join(root, userPath)
+	}
+	if !isInsideFolder(root, path) {
+		return "", errPathOutsideRoot
+	}
+	return path, nil
+}
+
+// decodedPath percent-decodes a path, returning it unchanged if it is not valid percent encoding
+func decodedPath(userPath string) string {
+	decoded, err := url.PathUnescape(userPath)
+	if err != nil {
+		return userPath
+	}
+	return decoded
+}
+
+// checkSymlinks makes sure the deepest existing part of path, with every symlink followed, is still inside root
+func checkSymlinks(root string, path string) error {
+	realRoot, err := filepath.EvalSymlinks(root)
+	if err != nil {
+		return err
+	}
+	existing := path
+	for {
+		if _, err := os.Lstat(existing); err == nil {
+			break
+		}
+		parent := filepath.Dir(existing)
+		if parent == existing {
+			return nil
+		}
+		existing = parent
+	}
+	realPath, err := filepath.EvalSymlinks(existing)
+	if err != nil {
+		return err
+	}
+	if !isInsideFolder(realRoot, realPath) {
+		return errPathOutsideRoot
+	}
+	return nil
+}
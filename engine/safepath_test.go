@@ -0,0 +1,61 @@
+package engine
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSafePath(t *testing.T) {
+	dir := t.TempDir()
+	root := filepath.Join(dir, "documents")
+	outside := filepath.Join(dir, "outside")
+	for _, folder := range []string{filepath.Join(root, "invoices"), outside} {
+		if err := os.MkdirAll(folder, os.ModePerm); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Symlink(outside, filepath.Join(root, "escape")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(filepath.Join(root, "invoices"), filepath.Join(root, "linked")); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		path    string
+		want    string
+		wantErr error
+	}{
+		{"", root, nil},
+		{"invoices", filepath.Join(root, "invoices"), nil},
+		{"/invoices/2024", filepath.Join(root, "invoices", "2024"), nil},
+		{`invoices\2024`, filepath.Join(root, "invoices", "2024"), nil},
+		{"invoices/../receipts", filepath.Join(root, "receipts"), nil},
+		{filepath.Join(root, "invoices"), filepath.Join(root, "invoices"), nil},
+		{"linked/new", filepath.Join(root, "linked", "new"), nil},
+		{"invoices/100%25.pdf", filepath.Join(root, "invoices", "100%25.pdf"), nil},
+		{"..", "", errPathOutsideRoot},
+		{"invoices/../../outside", "", errPathOutsideRoot},
+		{`..\outside`, "", errPathOutsideRoot},
+		{outside, filepath.Join(root, outside), nil}, //an absolute path outside root is taken relative to it
+		{"/../" + outside, "", errPathOutsideRoot},
+		{"/", root, nil},
+		{"/etc/passwd", filepath.Join(root, "etc", "passwd"), nil},
+		{"/nosuch/folder", filepath.Join(root, "nosuch", "folder"), nil},
+		{"/../etc/passwd", "", errPathOutsideRoot},
+		{"/invoices/../../etc", "", errPathOutsideRoot},
+		{"%2e%2e/outside", "", errPathOutsideRoot},
+		{"..%2foutside", "", errPathOutsideRoot},
+		{"%2e%2e%5coutside", "", errPathOutsideRoot},
+		{"invoices\x00/../..", "", errPathOutsideRoot},
+		{"escape", "", errPathOutsideRoot},
+		{"escape/new/folder", "", errPathOutsideRoot},
+	}
+	for _, tt := range tests {
+		got, err := safePath(root, tt.path)
+		if got != tt.want || err != tt.wantErr {
+			t.Errorf("safePath(%q) = %q, %v; want %q, %v", tt.path, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
@@ -11,17 +11,43 @@ import (
 // Logger is global since we will need it everywhere
 var Logger *slog.Logger
 
-// InitializeSchedules starts all the cron jobs (currently just one)
+// InitializeSchedules starts all the cron jobs (ingress, integrity, upload and trash expiry), the ingress folder watcher, the rehash of
+// legacy document hashes and the recording of their sizes
 func (serverHandler *ServerHandler) InitializeSchedules(db database.DBInterface) {
 	serverConfig, err := database.FetchConfigFromDB(db)
 	if err != nil {
 		fmt.Println("Error reading db when initializing")
 	}
 
+	interrupted, err := db.FailInterruptedIngestJobs()
+	if err != nil {
+		Logger.Error("Unable to clear ingest jobs interrupted by restart", "error", err)
+	} else if interrupted > 0 {
+		Logger.Warn("Marked ingest jobs interrupted by restart as failed", "count", interrupted)
+	}
+	interrupted, err = db.FailInterruptedIntegrityRuns()
+	if err != nil {
+		Logger.Error("Unable to clear integrity runs interrupted by restart", "error", err)
+	} else if interrupted > 0 {
+		Logger.Warn("Marked integrity runs interrupted by restart as failed", "count", interrupted)
+	}
+
+	// Replace the MD5 hashes of documents stored before the switch to SHA-256, a no-op once they are all done
+	go serverHandler.rehashLegacyDocuments()
+	// Record the file size of documents stored before sizes were, so search can filter on it
+	go serverHandler.recordDocumentSizes()
+
 	// Run ingress job immediately at startup in a goroutine
 	Logger.Info("Running ingress job at startup")
 	go serverHandler.ingressJobFunc(serverConfig, db)
 
+	if serverHandler.ServerConfig.IngressWatch {
+		err = serverHandler.startIngressWatcher()
+		if err != nil {
+			Logger.Error("Unable to watch ingress folder, relying on the scheduled scan", "path", serverHandler.ServerConfig.IngressPath, "error", err)
+		}
+	}
+
 	c := cron.New()
 	var ingressJob cron.Job
 	ingressJob = cron.FuncJob(func() { serverHandler.ingressJobFunc(serverConfig, db) })
@@ -29,5 +55,20 @@ func (serverHandler *ServerHandler) InitializeSchedules(db database.DBInterface)
 	c.AddJob(fmt.Sprintf("@every %dm", serverConfig.IngressInterval), ingressJob)
 	//c.AddJob("@every 1m", ingressJob)
 	Logger.Info("Adding Ingress Job scheduler", "interval_minutes", serverConfig.IngressInterval)
+	if serverHandler.ServerConfig.IntegrityInterval > 0 { //not stored in the database so read from the live config
+		integrityJob := cron.FuncJob(func() {
+			if _, err := serverHandler.verifyIntegrity(); err != nil {
+				Logger.Error("Scheduled integrity check did not run", "error", err)
+			}
+		})
+		c.AddJob(fmt.Sprintf("@every %dh", serverHandler.ServerConfig.IntegrityInterval), integrityJob)
+		Logger.Info("Adding Integrity Job scheduler", "interval_hours", serverHandler.ServerConfig.IntegrityInterval)
+	}
+	c.AddFunc("@every 1h", serverHandler.expireUploads)
+	Logger.Info("Adding Upload expiry scheduler", "expiry_hours", serverHandler.ServerConfig.UploadExpiry)
+	if serverHandler.ServerConfig.TrashRetention > 0 {
+		c.AddFunc("@every 1h", serverHandler.expireTrash)
+		Logger.Info("Adding Trash expiry scheduler", "retention_days", serverHandler.ServerConfig.TrashRetention)
+	}
 	c.Start()
 }
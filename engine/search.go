@@ -0,0 +1,120 @@
+package engine
+
+import (
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/drummonds/goEDMS/database"
+)
+
+// searchDateLayout is the format of the from and to search parameters
+const searchDateLayout = "2006-01-02"
+
+// searchFilter reads the search parameters.  from and to are ingress dates, both inclusive, document_type a comma
+// separated list of extensions, folder a folder in the document folder that results must be in or below, min_size
+// and max_size are in bytes, sort one of relevance, newest or name and fuzzy true to match similar words.
+func (serverHandler *ServerHandler) searchFilter(params url.Values) (database.SearchFilter, error) {
+	filter := database.SearchFilter{Term: strings.TrimSpace(params.Get("term"))}
+	if from := params.Get("from"); from != "" {
+		date, err := time.ParseInLocation(searchDateLayout, from, time.Local)
+		if err != nil {
+			return filter, fmt.Errorf("from must be a date like 2024-01-31: %q", from)
+		}
+		filter.IngressAfter = &date
+	}
+	if to := params.Get("to"); to != "" {
+		date, err := time.ParseInLocation(searchDateLayout, to, time.Local)
+		if err != nil {
+			return filter, fmt.Errorf("to must be a date like 2024-01-31: %q", to)
+		}
+		date = date.AddDate(0, 0, 1) //include the whole of the last day
+		filter.IngressBefore = &date
+	}
+	if filter.IngressAfter != nil && filter.IngressBefore != nil && !filter.IngressAfter.Before(*filter.IngressBefore) {
+		return filter, fmt.Errorf("from must not be after to")
+	}
+	for _, documentType := range strings.Split(params.Get("document_type"), ",") {
+		documentType = strings.ToLower(strings.TrimSpace(documentType))
+		if documentType == "" {
+			continue
+		}
+		if !strings.HasPrefix(documentType, ".") {
+			documentType = "." + documentType
+		}
+		filter.DocumentTypes = append(filter.DocumentTypes, documentType)
+	}
+	if folder := strings.TrimSpace(params.Get("folder")); folder != "" {
+		path, err := safePath(serverHandler.ServerConfig.DocumentPath, folder)
+		if err != nil {
+			return filter, fmt.Errorf("folder %q: %w", folder, err)
+		}
+		filter.Folder = filepath.ToSlash(path)
+	}
+	for _, size := range []struct {
+		name  string
+		value *int64
+	}{{"min_size", &filter.MinSize}, {"max_size", &filter.MaxSize}} {
+		param := params.Get(size.name)
+		if param == "" {
+			continue
+		}
+		value, err := strconv.ParseInt(param, 10, 64)
+		if err != nil || value < 0 {
+			return filter, fmt.Errorf("%s must be a number of bytes: %q", size.name, param)
+		}
+		*size.value = value
+	}
+	if filter.MaxSize > 0 && filter.MinSize > filter.MaxSize {
+		return filter, fmt.Errorf("min_size must not be more than max_size")
+	}
+	if fuzzy := params.Get("fuzzy"); fuzzy != "" {
+		value, err := strconv.ParseBool(fuzzy)
+		if err != nil {
+			return filter, fmt.Errorf("fuzzy must be true or false: %q", fuzzy)
+		}
+		filter.Fuzzy = value
+	}
+	switch sort := params.Get("sort"); sort {
+	case "", database.SortRelevance, database.SortNewest, database.SortName:
+		filter.Sort = sort
+	default:
+		return filter, fmt.Errorf("sort must be %s, %s or %s: %q", database.SortRelevance, database.SortNewest, database.SortName, sort)
+	}
+	return filter, nil
+}
+
+// hasSearchCriteria reports whether a filter narrows the documents at all, sort and fuzzy on their own do not
+func hasSearchCriteria(filter database.SearchFilter) bool {
+	filter.Sort, filter.Fuzzy = "", false
+	return filter.Term != "" || filter.IngressAfter != nil || filter.IngressBefore != nil ||
+		len(filter.DocumentTypes) > 0 || filter.Folder != "" || filter.MinSize > 0 || filter.MaxSize > 0
+}
+
+// searchResponse is the search results as a file tree along with the facets they can be narrowed down by
+type searchResponse struct {
+	FileSystem []fileTreeStruct      `json:"fileSystem"`
+	Facets     database.SearchFacets `json:"facets"`
+}
+
+// relativeFacets makes the folder facets relative to the document folder, as the folder search parameter takes
+// them, "/" being the document folder itself
+func (serverHandler *ServerHandler) relativeFacets(facets database.SearchFacets) database.SearchFacets {
+	folders := make([]database.FacetCount, len(facets.Folder))
+	for i, folder := range facets.Folder {
+		folders[i] = folder
+		relative, err := filepath.Rel(serverHandler.ServerConfig.DocumentPath, filepath.FromSlash(folder.Value))
+		if err != nil || relative == ".." || strings.HasPrefix(relative, ".."+string(filepath.Separator)) {
+			continue //not in the document folder, left as it is
+		}
+		folders[i].Value = "/"
+		if relative != "." {
+			folders[i].Value += filepath.ToSlash(relative)
+		}
+	}
+	facets.Folder = folders
+	return facets
+}
@@ -0,0 +1,95 @@
+package engine
+
+import (
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/drummonds/goEDMS/config"
+	"github.com/drummonds/goEDMS/database"
+)
+
+func TestSearchFilter(t *testing.T) {
+	documentPath := t.TempDir()
+	os.MkdirAll(filepath.Join(documentPath, "bills", "2024"), os.ModePerm)
+	serverHandler := &ServerHandler{ServerConfig: config.ServerConfig{DocumentPath: documentPath}}
+	date := func(value string) *time.Time {
+		parsed, _ := time.ParseInLocation(searchDateLayout, value, time.Local)
+		return &parsed
+	}
+
+	tests := []struct {
+		name    string
+		query   string
+		want    database.SearchFilter
+		wantErr bool
+	}{
+		{"Term only", "term=+invoice+", database.SearchFilter{Term: "invoice"}, false},
+		{"Date range includes the last day", "from=2024-01-01&to=2024-01-31",
+			database.SearchFilter{IngressAfter: date("2024-01-01"), IngressBefore: date("2024-02-01")}, false},
+		{"Single day", "from=2024-03-05&to=2024-03-05",
+			database.SearchFilter{IngressAfter: date("2024-03-05"), IngressBefore: date("2024-03-06")}, false},
+		{"Document types are normalised", "document_type=PDF,.png,+txt+",
+			database.SearchFilter{DocumentTypes: []string{".pdf", ".png", ".txt"}}, false},
+		{"Folder is resolved in the document folder", "folder=bills/2024",
+			database.SearchFilter{Folder: filepath.ToSlash(filepath.Join(documentPath, "bills", "2024"))}, false},
+		{"Sizes and sort", "term=tax&min_size=1024&max_size=2048&sort=name",
+			database.SearchFilter{Term: "tax", MinSize: 1024, MaxSize: 2048, Sort: database.SortName}, false},
+		{"Fuzzy", "term=lnvoice&fuzzy=true", database.SearchFilter{Term: "lnvoice", Fuzzy: true}, false},
+		{"Not fuzzy", "term=invoice&fuzzy=false", database.SearchFilter{Term: "invoice"}, false},
+		{"Bad date", "from=31/01/2024", database.SearchFilter{}, true},
+		{"From after to", "from=2024-02-01&to=2024-01-01", database.SearchFilter{}, true},
+		{"Folder outside the document folder", "folder=../..", database.SearchFilter{}, true},
+		{"Negative size", "min_size=-1", database.SearchFilter{}, true},
+		{"Size not in bytes", "max_size=10MB", database.SearchFilter{}, true},
+		{"Min size above max size", "min_size=10&max_size=5", database.SearchFilter{}, true},
+		{"Unknown sort", "sort=size", database.SearchFilter{}, true},
+		{"Fuzzy not a boolean", "term=invoice&fuzzy=maybe", database.SearchFilter{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			params, _ := url.ParseQuery(tt.query)
+			got, err := serverHandler.searchFilter(params)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("searchFilter(%q) error = %v, wantErr %v", tt.query, err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("searchFilter(%q) = %+v, want %+v", tt.query, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHasSearchCriteria(t *testing.T) {
+	if hasSearchCriteria(database.SearchFilter{Sort: database.SortName, Fuzzy: true}) {
+		t.Error("Sort and fuzzy on their own should not count as a search")
+	}
+	if !hasSearchCriteria(database.SearchFilter{DocumentTypes: []string{".pdf"}}) {
+		t.Error("A filter without a term should count as a search")
+	}
+}
+
+func TestRelativeFacets(t *testing.T) {
+	documentPath := t.TempDir()
+	serverHandler := &ServerHandler{ServerConfig: config.ServerConfig{DocumentPath: documentPath}}
+	facets := database.SearchFacets{
+		DocumentType: []database.FacetCount{{Value: ".pdf", Count: 4}},
+		Folder: []database.FacetCount{
+			{Value: filepath.ToSlash(filepath.Join(documentPath, "bills", "2024")), Count: 3},
+			{Value: filepath.ToSlash(documentPath), Count: 2},
+			{Value: filepath.ToSlash(filepath.Join(documentPath, ".hidden")), Count: 1},
+			{Value: "/elsewhere", Count: 1},
+		},
+	}
+	got := serverHandler.relativeFacets(facets)
+	want := []database.FacetCount{{Value: "/bills/2024", Count: 3}, {Value: "/", Count: 2}, {Value: "/.hidden", Count: 1}, {Value: "/elsewhere", Count: 1}}
+	if !reflect.DeepEqual(got.Folder, want) || !reflect.DeepEqual(got.DocumentType, facets.DocumentType) {
+		t.Errorf("relativeFacets() = %+v, want folders %+v", got, want)
+	}
+	if facets.Folder[0].Value == "/bills/2024" {
+		t.Error("relativeFacets() changed the facets it was given")
+	}
+}
@@ -0,0 +1,37 @@
+package engine
+
+import "os"
+
+// recordDocumentSizes reads the file size of documents stored before sizes were recorded.  A document whose file
+// cannot be read keeps size 0 and is tried again at the next start, the integrity check reports the missing file.
+func (serverHandler *ServerHandler) recordDocumentSizes() (recorded int, skipped int) {
+	afterID := 0
+	for {
+		documents, err := serverHandler.DB.GetDocumentsWithoutSize(afterID, rehashBatchSize)
+		if err != nil {
+			Logger.Error("Unable to read documents without a size", "error", err)
+			break
+		}
+		if len(documents) == 0 {
+			break
+		}
+		for _, document := range documents {
+			afterID = document.StormID
+			info, err := os.Stat(document.Path)
+			if err != nil || info.Size() == 0 {
+				skipped++
+				continue
+			}
+			if err := serverHandler.DB.UpdateDocumentSize(document.ULID.String(), info.Size()); err != nil {
+				Logger.Error("Unable to save document size", "ulid", document.ULID.String(), "error", err)
+				skipped++
+				continue
+			}
+			recorded++
+		}
+	}
+	if recorded > 0 || skipped > 0 {
+		Logger.Info("Recorded document sizes", "recorded", recorded, "skipped", skipped)
+	}
+	return recorded, skipped
+}
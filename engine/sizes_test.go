@@ -0,0 +1,72 @@
+package engine
+
+import (
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/drummonds/goEDMS/database"
+)
+
+func TestRecordDocumentSizes(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	database.Logger = logger
+	Logger = logger
+
+	ephemeralDB, err := database.SetupEphemeralPostgresDatabase()
+	if err != nil {
+		t.Fatalf("Failed to set up ephemeral database: %v", err)
+	}
+	defer ephemeralDB.Close()
+	serverHandler := &ServerHandler{DB: ephemeralDB}
+
+	tempDir := t.TempDir()
+	// saveUnsized stores a document as it was before sizes were recorded
+	saveUnsized := func(name string, content *string) *database.Document {
+		t.Helper()
+		path := filepath.Join(tempDir, name)
+		if content != nil {
+			if err := os.WriteFile(path, []byte(*content), 0644); err != nil {
+				t.Fatalf("Failed to write %s: %v", name, err)
+			}
+		}
+		id, _ := database.CalculateUUID(time.Now())
+		document := &database.Document{
+			Name: name, Path: path, Folder: tempDir, IngressTime: time.Now(), ULID: id, DocumentType: ".txt",
+			Hash: name, HashAlgorithm: database.HashAlgorithmSHA256,
+		}
+		if err := ephemeralDB.SaveDocument(document); err != nil {
+			t.Fatalf("Failed to save %s: %v", name, err)
+		}
+		return document
+	}
+	content := "twelve bytes"
+	present := saveUnsized("present.txt", &content)
+	missing := saveUnsized("missing.txt", nil)
+
+	if recorded, skipped := serverHandler.recordDocumentSizes(); recorded != 1 || skipped != 1 {
+		t.Errorf("Expected 1 recorded and 1 skipped, got %d and %d", recorded, skipped)
+	}
+	for _, tt := range []struct {
+		document *database.Document
+		wantSize int64
+	}{
+		{present, int64(len(content))},
+		{missing, 0},
+	} {
+		stored, err := ephemeralDB.GetDocumentByULID(tt.document.ULID.String())
+		if err != nil {
+			t.Fatalf("Failed to fetch %s: %v", tt.document.Name, err)
+		}
+		if stored.Size != tt.wantSize {
+			t.Errorf("%s: expected size %d, got %d", tt.document.Name, tt.wantSize, stored.Size)
+		}
+	}
+
+	// Only the missing file is left, so a second run records nothing
+	if recorded, _ := serverHandler.recordDocumentSizes(); recorded != 0 {
+		t.Errorf("Expected second run to record nothing, got %d", recorded)
+	}
+}
@@ -0,0 +1,302 @@
+package engine
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding/charmap"
+)
+
+// textProcessing reads a plain text or RTF document and returns its text content
+func textProcessing(file string) (*string, error) {
+	fileName := filepath.Base(file)
+	Logger.Debug("Working on current file", "fileName", fileName)
+	fileBytes, err := os.ReadFile(file)
+	if err != nil {
+		Logger.Error("Unable to read text file", "fileName", fileName, "error", err)
+		return nil, err
+	}
+	var fullText string
+	if strings.ToLower(filepath.Ext(file)) == ".rtf" || isRTF(fileBytes) {
+		fullText = rtfToText(decodeText(fileBytes))
+	} else {
+		fullText = decodeText(fileBytes)
+	}
+	fullText = strings.TrimSpace(fullText)
+	if fullText == "" {
+		err = errors.New("text result is empty")
+		Logger.Warn("Text document contains no text", "fileName", fileName, "error", err)
+		return nil, err
+	}
+	Logger.Info("Text processed from text document", "fileName", fileName)
+	return &fullText, nil
+}
+
+// isRTF checks for the RTF signature at the start of the file
+func isRTF(data []byte) bool {
+	return bytes.HasPrefix(bytes.TrimLeft(data, " \t\r\n\xef\xbb\xbf"), []byte(`{\rtf`))
+}
+
+// decodeText converts raw bytes to a UTF-8 string, detecting UTF-8, UTF-16 (LE/BE) and falling back to Windows-1252
+func decodeText(data []byte) string {
+	switch {
+	case bytes.HasPrefix(data, []byte{0xEF, 0xBB, 0xBF}): //UTF-8 BOM
+		return string(data[3:])
+	case bytes.HasPrefix(data, []byte{0xFF, 0xFE}): //UTF-16 little endian BOM
+		return decodeUTF16(data[2:], binary.LittleEndian)
+	case bytes.HasPrefix(data, []byte{0xFE, 0xFF}): //UTF-16 big endian BOM
+		return decodeUTF16(data[2:], binary.BigEndian)
+	}
+	if utf8.Valid(data) {
+		return string(data)
+	}
+	if order, ok := guessUTF16(data); ok { //no BOM, but lots of zero bytes in alternating positions
+		return decodeUTF16(data, order)
+	}
+	return decodeCharmap(charmap.Windows1252, data)
+}
+
+// guessUTF16 detects BOM-less UTF-16 by looking at where the zero bytes fall (mostly-ASCII text has one zero byte per character)
+func guessUTF16(data []byte) (binary.ByteOrder, bool) {
+	if len(data) < 2 || len(data)%2 != 0 {
+		return nil, false
+	}
+	var evenZeros, oddZeros int
+	for i := 0; i+1 < len(data); i += 2 {
+		if data[i] == 0 {
+			evenZeros++
+		}
+		if data[i+1] == 0 {
+			oddZeros++
+		}
+	}
+	pairs := len(data) / 2
+	switch {
+	case oddZeros*2 > pairs && evenZeros*10 < pairs:
+		return binary.LittleEndian, true
+	case evenZeros*2 > pairs && oddZeros*10 < pairs:
+		return binary.BigEndian, true
+	}
+	return nil, false
+}
+
+func decodeUTF16(data []byte, order binary.ByteOrder) string {
+	units := make([]uint16, 0, len(data)/2)
+	for i := 0; i+1 < len(data); i += 2 {
+		units = append(units, order.Uint16(data[i:]))
+	}
+	return string(utf16.Decode(units))
+}
+
+// decodeCharmap maps every byte to its code point in a single byte codepage
+func decodeCharmap(codepage *charmap.Charmap, data []byte) string {
+	var builder strings.Builder
+	builder.Grow(len(data))
+	for _, b := range data {
+		builder.WriteRune(codepage.DecodeByte(b))
+	}
+	return builder.String()
+}
+
+// rtfCodepages are the \ansicpgN codepages that \'hh escapes can be decoded from, anything else is read as Windows-1252
+var rtfCodepages = map[int]*charmap.Charmap{
+	437: charmap.CodePage437, 850: charmap.CodePage850, 874: charmap.Windows874,
+	1250: charmap.Windows1250, 1251: charmap.Windows1251, 1252: charmap.Windows1252, 1253: charmap.Windows1253,
+	1254: charmap.Windows1254, 1255: charmap.Windows1255, 1256: charmap.Windows1256, 1257: charmap.Windows1257,
+	1258: charmap.Windows1258, 10000: charmap.Macintosh,
+}
+
+// rtfSkipDestinations are RTF groups that hold formatting or embedded data rather than document text
+var rtfSkipDestinations = map[string]bool{
+	"fonttbl": true, "colortbl": true, "stylesheet": true, "info": true, "pict": true,
+	"object": true, "header": true, "footer": true, "headerl": true, "headerr": true,
+	"headerf": true, "footerl": true, "footerr": true, "footerf": true, "listtable": true,
+	"listoverridetable": true, "revtbl": true, "rsidtbl": true, "generator": true,
+	"xmlnstbl": true, "themedata": true, "colorschememapping": true, "datastore": true,
+	"latentstyles": true, "filetbl": true, "fldinst": true, "pgdsctbl": true,
+}
+
+// rtfSpecialWords are control words that stand for a character in the output
+var rtfSpecialWords = map[string]string{
+	"par": "\n", "line": "\n", "sect": "\n", "page": "\n", "row": "\n", "tab": "\t", "cell": "\t",
+	"emdash": "—", "endash": "–", "bullet": "•", "lquote": "‘",
+	"rquote": "’", "ldblquote": "“", "rdblquote": "”", "emspace": " ", "enspace": " ",
+}
+
+// rtfState is the per-group parser state, pushed on { and popped on }
+type rtfState struct {
+	skip        bool
+	unicodeSkip int //number of fallback characters to skip after a \u control word (\ucN)
+}
+
+// rtfToText strips RTF control words and groups, returning the plain text of the document
+func rtfToText(rtf string) string {
+	var out strings.Builder
+	state := rtfState{unicodeSkip: 1}
+	var stack []rtfState
+	pendingSkip := 0 //fallback characters still to drop after a \uN
+	pendingHighSurrogate := rune(0)
+	groupStart := false             //true directly after a {, used to detect \* and destination words
+	codepage := charmap.Windows1252 //Word writes cp1252 unless the header says otherwise with \ansicpgN
+
+	emit := func(text string) {
+		if state.skip {
+			return
+		}
+		out.WriteString(text)
+	}
+	for i := 0; i < len(rtf); {
+		c := rtf[i]
+		switch c {
+		case '{':
+			stack = append(stack, state)
+			groupStart = true
+			pendingSkip = 0
+			i++
+			continue
+		case '}':
+			if len(stack) > 0 {
+				state = stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+			}
+			groupStart = false
+			pendingSkip = 0
+			i++
+			continue
+		case '\r', '\n':
+			i++
+			continue
+		case '\\':
+			if i+1 >= len(rtf) {
+				i++
+				continue
+			}
+			next := rtf[i+1]
+			switch {
+			case next == '\\' || next == '{' || next == '}':
+				if pendingSkip > 0 {
+					pendingSkip--
+				} else {
+					emit(string(next))
+				}
+				i += 2
+			case next == '\'': //hex escaped character in the document codepage
+				if i+4 <= len(rtf) {
+					if value, err := strconv.ParseUint(rtf[i+2:i+4], 16, 8); err == nil {
+						if pendingSkip > 0 {
+							pendingSkip--
+						} else {
+							emit(string(codepage.DecodeByte(byte(value))))
+						}
+					}
+				}
+				i += 4
+			case next == '*': //ignorable destination
+				if groupStart {
+					state.skip = true
+				}
+				i += 2
+			case next == '~':
+				emit(" ")
+				i += 2
+			case next == '_':
+				emit("‑")
+				i += 2
+			case next == '-':
+				i += 2
+			case next == '\r' || next == '\n':
+				emit("\n")
+				i += 2
+			case isASCIILetter(next):
+				j := i + 1
+				for j < len(rtf) && isASCIILetter(rtf[j]) {
+					j++
+				}
+				word := rtf[i+1 : j]
+				k := j
+				if k < len(rtf) && rtf[k] == '-' {
+					k++
+				}
+				for k < len(rtf) && rtf[k] >= '0' && rtf[k] <= '9' {
+					k++
+				}
+				param := rtf[j:k]
+				hasParam := param != "" && param != "-"
+				if k < len(rtf) && rtf[k] == ' ' { //a single space delimits the control word and is swallowed
+					k++
+				}
+				i = k
+
+				if groupStart && rtfSkipDestinations[word] {
+					state.skip = true
+				}
+				groupStart = false
+				switch word {
+				case "ansicpg":
+					if number, err := strconv.Atoi(param); err == nil && rtfCodepages[number] != nil {
+						codepage = rtfCodepages[number]
+					}
+				case "mac":
+					codepage = charmap.Macintosh
+				case "pc":
+					codepage = charmap.CodePage437
+				case "pca":
+					codepage = charmap.CodePage850
+				case "uc":
+					if hasParam {
+						state.unicodeSkip, _ = strconv.Atoi(param)
+					}
+				case "u":
+					if !hasParam {
+						continue
+					}
+					code, _ := strconv.Atoi(param)
+					if code < 0 { //RTF writes code points above 32767 as negative numbers
+						code += 65536
+					}
+					r := rune(code)
+					switch {
+					case utf16.IsSurrogate(r) && r < 0xDC00:
+						pendingHighSurrogate = r
+					case utf16.IsSurrogate(r) && pendingHighSurrogate != 0:
+						emit(string(utf16.DecodeRune(pendingHighSurrogate, r)))
+						pendingHighSurrogate = 0
+					default:
+						emit(string(r))
+					}
+					pendingSkip = state.unicodeSkip
+				default:
+					if special, ok := rtfSpecialWords[word]; ok {
+						emit(special)
+					}
+				}
+				continue
+			default:
+				i += 2
+			}
+			groupStart = false
+			continue
+		}
+		groupStart = false
+		if pendingSkip > 0 {
+			pendingSkip--
+			i++
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(rtf[i:])
+		emit(string(r))
+		i += size
+	}
+	return out.String()
+}
+
+func isASCIILetter(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
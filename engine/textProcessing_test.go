@@ -0,0 +1,116 @@
+package engine
+
+import (
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDecodeText(t *testing.T) {
+	tests := []struct {
+		name  string
+		input []byte
+		want  string
+	}{
+		{"UTF-8", []byte("Meeting notes – café"), "Meeting notes – café"},
+		{"UTF-8 BOM", []byte("\xef\xbb\xbfHello"), "Hello"},
+		{"UTF-16LE BOM", []byte{0xFF, 0xFE, 'H', 0, 'i', 0}, "Hi"},
+		{"UTF-16BE BOM", []byte{0xFE, 0xFF, 0, 'H', 0, 'i'}, "Hi"},
+		{"UTF-16LE no BOM", []byte{'N', 0, 'o', 0, 't', 0, 'e', 0, 0xE9, 0}, "Noteé"},
+		{"Windows-1252", []byte("caf\xe9 r\xe9sum\xe9 \x93quoted\x94 \x80"), "café résumé “quoted” €"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := decodeText(tt.input); got != tt.want {
+				t.Errorf("decodeText() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRTFToText(t *testing.T) {
+	tests := []struct {
+		name string
+		rtf  string
+		want string
+	}{
+		{
+			"Simple paragraphs",
+			`{\rtf1\ansi\deff0{\fonttbl{\f0 Times New Roman;}}\f0\fs24 Dear Sir,\par Please find enclosed.\par}`,
+			"Dear Sir,\nPlease find enclosed.\n",
+		},
+		{
+			"Hex escapes and special characters",
+			`{\rtf1\ansi Caf\'e9 \endash  done\tab x\~y}`,
+			"Café – done\tx\u00a0y",
+		},
+		{
+			"Windows-1252 hex escapes",
+			`{\rtf1\ansi\ansicpg1252 It\'92s \'93quoted\'94 \'96 \'805}`,
+			"It’s “quoted” – €5",
+		},
+		{
+			"Codepage from the header",
+			`{\rtf1\ansi\ansicpg1251 \'cf\'f0\'e8\'e2\'e5\'f2}`,
+			"Привет",
+		},
+		{
+			"Unicode with fallback",
+			`{\rtf1\ansi\uc1 Na\u239?ve \u8364?}`,
+			"Naïve €",
+		},
+		{
+			"Ignorable destinations and info",
+			`{\rtf1{\info{\title Secret}{\author Bob}}{\*\generator Writer;}Body text}`,
+			"Body text",
+		},
+		{
+			"Escaped braces",
+			`{\rtf1 a \{b\} \\c}`,
+			"a {b} \\c",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := rtfToText(tt.rtf); got != tt.want {
+				t.Errorf("rtfToText() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTextProcessing(t *testing.T) {
+	Logger = slog.New(slog.NewTextHandler(os.Stdout, nil))
+	tempDir := t.TempDir()
+
+	rtfPath := filepath.Join(tempDir, "letter.RTF")
+	if err := os.WriteFile(rtfPath, []byte(`{\rtf1\ansi{\fonttbl{\f0 Arial;}}\f0 Invoice 42\par}`), 0644); err != nil {
+		t.Fatalf("Failed to write RTF file: %v", err)
+	}
+	fullText, err := textProcessing(rtfPath)
+	if err != nil {
+		t.Fatalf("textProcessing returned error: %v", err)
+	}
+	if *fullText != "Invoice 42" {
+		t.Errorf("Expected RTF text %q, got %q", "Invoice 42", *fullText)
+	}
+
+	emptyPath := filepath.Join(tempDir, "empty.txt")
+	if err := os.WriteFile(emptyPath, []byte("  \n"), 0644); err != nil {
+		t.Fatalf("Failed to write text file: %v", err)
+	}
+	if _, err := textProcessing(emptyPath); err == nil {
+		t.Error("Expected error for empty text file, got nil")
+	}
+
+	txtPath := filepath.Join(tempDir, "notes.txt")
+	if err := os.WriteFile(txtPath, []byte{0xFF, 0xFE, 'o', 0, 'k', 0}, 0644); err != nil {
+		t.Fatalf("Failed to write text file: %v", err)
+	}
+	fullText, err = textProcessing(txtPath)
+	if err != nil || !strings.Contains(*fullText, "ok") {
+		t.Errorf("Expected UTF-16 text to decode, got %v, %v", fullText, err)
+	}
+}
@@ -0,0 +1,137 @@
+package engine
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"os"
+	"path/filepath"
+
+	"github.com/disintegration/imaging"
+	"github.com/drummonds/goEDMS/database"
+	"github.com/gen2brain/go-fitz"
+)
+
+var (
+	errNoPreview    = errors.New("no preview can be made of this type of document")
+	errPageNotFound = errors.New("document has no such page")
+)
+
+const (
+	thumbnailWidth   = 256  //pixels, the height is at most twice this
+	pagePreviewWidth = 1024 //pixels, enough to read a page on screen
+)
+
+// previewPath is where a document's preview is cached, page 0 being its thumbnail
+func (serverHandler *ServerHandler) previewPath(ulid string, page int) string {
+	name := "thumbnail.jpg"
+	if page > 0 {
+		name = fmt.Sprintf("page-%04d.jpg", page)
+	}
+	return filepath.Join(serverHandler.ServerConfig.ThumbnailFolder, ulid, name)
+}
+
+// documentPreview returns the path of the cached thumbnail (page 0) or preview of a page of a document, rendering
+// it first if it is missing or older than the document file
+func (serverHandler *ServerHandler) documentPreview(document database.Document, page int) (string, error) {
+	info, err := os.Stat(document.Path)
+	if err != nil {
+		return "", err
+	}
+	path := serverHandler.previewPath(document.ULID.String(), page)
+	if cached, err := os.Stat(path); err == nil && !cached.ModTime().Before(info.ModTime()) {
+		return path, nil
+	}
+	width := pagePreviewWidth
+	if page == 0 {
+		page, width = 1, thumbnailWidth
+	}
+	preview, err := renderPreview(document.Path, page, width)
+	if err != nil {
+		return "", err
+	}
+	if err := writePreview(path, preview); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// generateThumbnail renders the thumbnail of a newly stored document so the first page showing it does not wait
+func (serverHandler *ServerHandler) generateThumbnail(document *database.Document) {
+	_, err := serverHandler.documentPreview(*document, 0)
+	if err != nil && !errors.Is(err, errNoPreview) {
+		Logger.Warn("Unable to create thumbnail, it will be tried again when asked for", "ulid", document.ULID.String(), "path", document.Path, "error", err)
+	}
+}
+
+// removePreviews throws away every cached preview of a document
+func (serverHandler *ServerHandler) removePreviews(ulid string) {
+	if err := os.RemoveAll(filepath.Join(serverHandler.ServerConfig.ThumbnailFolder, ulid)); err != nil {
+		Logger.Error("Unable to remove document previews", "ulid", ulid, "error", err)
+	}
+}
+
+// renderPreview renders page (counting from 1) of a file, scaled down to width.  PDFs are rendered with go-fitz,
+// images only have a page 1, anything else returns errNoPreview.
+func renderPreview(filePath string, page int, width int) (image.Image, error) {
+	extractor, err := ExtractorFor(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errNoPreview, err)
+	}
+	var rendered image.Image
+	switch extractor.Name() {
+	case "pdf":
+		doc, err := fitz.New(filePath)
+		if err != nil {
+			return nil, err
+		}
+		defer doc.Close()
+		if page < 1 || page > doc.NumPage() {
+			return nil, errPageNotFound
+		}
+		dpi := 150.0 //an A4 page is 1240 pixels wide
+		if width <= thumbnailWidth {
+			dpi = 72
+		}
+		if rendered, err = doc.ImageDPI(page-1, dpi); err != nil {
+			return nil, err
+		}
+	case "image":
+		if page != 1 {
+			return nil, errPageNotFound
+		}
+		if rendered, err = imaging.Open(filePath, imaging.AutoOrientation(true)); err != nil {
+			return nil, fmt.Errorf("%w: %v", errNoPreview, err)
+		}
+	default:
+		return nil, errNoPreview
+	}
+	rendered = imaging.Fit(rendered, width, width*2, imaging.Lanczos) //never scaled up
+	//JPEG has no transparency so put the page on white rather than the black it would otherwise get
+	bounds := rendered.Bounds()
+	return imaging.Overlay(imaging.New(bounds.Dx(), bounds.Dy(), color.White), rendered, image.Point{}, 1), nil
+}
+
+// writePreview saves a preview as a JPEG, replacing any older one in a single rename
+func writePreview(path string, preview image.Image) error {
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return err
+	}
+	temp, err := os.CreateTemp(filepath.Dir(path), ".preview-*")
+	if err != nil {
+		return err
+	}
+	err = imaging.Encode(temp, preview, imaging.JPEG, imaging.JPEGQuality(80))
+	if closeErr := temp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(temp.Name(), path)
+	}
+	if err != nil {
+		os.Remove(temp.Name())
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,53 @@
+package engine
+
+import (
+	"errors"
+	"net/http"
+	"os"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+)
+
+// DocumentThumbnail serves a small JPEG of the first page of a document, rendering it if it is not cached
+func (serverHandler *ServerHandler) DocumentThumbnail(c echo.Context) error {
+	return serverHandler.servePreview(c, 0)
+}
+
+// DocumentPage serves a JPEG preview of page n (counting from 1) of a document, rendering it if it is not cached
+func (serverHandler *ServerHandler) DocumentPage(c echo.Context) error {
+	page, err := strconv.Atoi(c.Param("n"))
+	if err != nil || page < 1 {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"error": "page must be a number from 1",
+		})
+	}
+	return serverHandler.servePreview(c, page)
+}
+
+// servePreview looks a document up by ULID and serves its cached preview of page, 0 for the thumbnail
+func (serverHandler *ServerHandler) servePreview(c echo.Context, page int) error {
+	document, httpStatus, err := fetchStoredDocument(c.Param("id"), serverHandler.DB)
+	if err != nil {
+		return c.JSON(httpStatus, map[string]interface{}{
+			"error": "Document not found",
+		})
+	}
+	path, err := serverHandler.documentPreview(document, page)
+	if err != nil {
+		status := http.StatusInternalServerError
+		switch {
+		case errors.Is(err, errNoPreview):
+			status = http.StatusUnsupportedMediaType
+		case errors.Is(err, errPageNotFound), os.IsNotExist(err):
+			status = http.StatusNotFound
+		default:
+			Logger.Error("Unable to render document preview", "ulid", document.ULID.String(), "page", page, "error", err)
+		}
+		return c.JSON(status, map[string]interface{}{
+			"error": err.Error(),
+		})
+	}
+	c.Response().Header().Set("Cache-Control", "private, max-age=3600") //a document's file can be replaced by a new version
+	return c.File(path)
+}
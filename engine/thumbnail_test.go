@@ -0,0 +1,114 @@
+package engine
+
+import (
+	"errors"
+	"image"
+	"image/color"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/disintegration/imaging"
+	"github.com/drummonds/goEDMS/config"
+	"github.com/drummonds/goEDMS/database"
+	"github.com/oklog/ulid/v2"
+)
+
+func TestRenderPreview(t *testing.T) {
+	dir := t.TempDir()
+	pngPath := filepath.Join(dir, "photo.png")
+	if err := imaging.Save(imaging.New(800, 600, color.Black), pngPath); err != nil {
+		t.Fatal(err)
+	}
+	pdfPath := filepath.Join(dir, "letter.pdf")
+	writeTestPDF(t, pdfPath, []string{"First page", "Second page"})
+	textPath := filepath.Join(dir, "notes.txt")
+	os.WriteFile(textPath, []byte("Just text"), 0644)
+
+	tests := []struct {
+		name    string
+		path    string
+		page    int
+		width   int
+		wantErr error
+	}{
+		{"Image scaled to thumbnail", pngPath, 1, thumbnailWidth, nil},
+		{"Small image is not scaled up", pngPath, 1, 2048, nil},
+		{"Image has only one page", pngPath, 2, pagePreviewWidth, errPageNotFound},
+		{"PDF first page", pdfPath, 1, thumbnailWidth, nil},
+		{"PDF later page", pdfPath, 2, pagePreviewWidth, nil},
+		{"PDF page past the end", pdfPath, 3, pagePreviewWidth, errPageNotFound},
+		{"Text has no preview", textPath, 1, thumbnailWidth, errNoPreview},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			preview, err := renderPreview(tt.path, tt.page, tt.width)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("renderPreview() error = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if width := preview.Bounds().Dx(); width > tt.width || width == 0 {
+				t.Errorf("Preview is %d pixels wide, want at most %d", width, tt.width)
+			}
+		})
+	}
+}
+
+func TestDocumentPreview(t *testing.T) {
+	Logger = slog.New(slog.NewTextHandler(os.Stdout, nil))
+	dir := t.TempDir()
+	serverHandler := &ServerHandler{ServerConfig: config.ServerConfig{ThumbnailFolder: filepath.Join(dir, "thumbnails")}}
+	path := filepath.Join(dir, "photo.png")
+	if err := imaging.Save(imaging.New(800, 600, color.White), path); err != nil {
+		t.Fatal(err)
+	}
+	document := database.Document{ULID: ulid.Make(), Path: path}
+
+	preview, err := serverHandler.documentPreview(document, 0)
+	if err != nil {
+		t.Fatalf("documentPreview() error = %v", err)
+	}
+	if preview != serverHandler.previewPath(document.ULID.String(), 0) {
+		t.Errorf("Thumbnail cached at %s, want %s", preview, serverHandler.previewPath(document.ULID.String(), 0))
+	}
+	cached, err := imaging.Open(preview)
+	if err != nil {
+		t.Fatalf("Unable to open cached thumbnail: %v", err)
+	}
+	if cached.Bounds() != image.Rect(0, 0, thumbnailWidth, thumbnailWidth*600/800) {
+		t.Errorf("Thumbnail is %v", cached.Bounds())
+	}
+
+	t.Run("Cached thumbnail is reused", func(t *testing.T) {
+		old := time.Now().Add(time.Hour)
+		os.Chtimes(preview, old, old)
+		if _, err := serverHandler.documentPreview(document, 0); err != nil {
+			t.Fatal(err)
+		}
+		if info, _ := os.Stat(preview); !info.ModTime().Equal(old) {
+			t.Error("Thumbnail was rendered again although it is newer than the document")
+		}
+	})
+
+	t.Run("Missing or stale thumbnail is rendered again", func(t *testing.T) {
+		old := time.Now().Add(-time.Hour)
+		os.Chtimes(preview, old, old)
+		if _, err := serverHandler.documentPreview(document, 0); err != nil {
+			t.Fatal(err)
+		}
+		if info, _ := os.Stat(preview); info.ModTime().Equal(old) {
+			t.Error("Thumbnail older than the document was not rendered again")
+		}
+		serverHandler.removePreviews(document.ULID.String())
+		if _, err := serverHandler.documentPreview(document, 1); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := os.Stat(serverHandler.previewPath(document.ULID.String(), 1)); err != nil {
+			t.Errorf("Page preview not cached: %v", err)
+		}
+	})
+}
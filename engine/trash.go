@@ -0,0 +1,238 @@
+package engine
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/drummonds/goEDMS/config"
+	"github.com/drummonds/goEDMS/database"
+)
+
+var (
+	errNotInTrash     = errors.New("document is not in the trash")
+	errAlreadyTrashed = errors.New("document is already in the trash")
+	errInTrash        = errors.New("document is in the trash")
+)
+
+// trashedDocument is what deleting a folder reports for each document it moved to the trash, FileMissing is set for
+// a document whose file was already gone so only its record was trashed
+type trashedDocument struct {
+	ULID        string `json:"ulid"`
+	Name        string `json:"name"`
+	TrashPath   string `json:"trashPath"`
+	FileMissing bool   `json:"fileMissing"`
+}
+
+// trashDocument moves a document's file and companions into the trash folder as <ULID>_<name> and marks it deleted,
+// which hides it from browsing and search until it is restored or purged
+func (serverHandler *ServerHandler) trashDocument(document database.Document) (*database.Document, error) {
+	if document.DeletedAt != nil {
+		return nil, errAlreadyTrashed
+	}
+	storeMu.Lock()
+	defer storeMu.Unlock()
+
+	trashFolder := serverHandler.ServerConfig.TrashFolder //not stored in the database so read from the live config
+	if err := os.MkdirAll(trashFolder, os.ModePerm); err != nil {
+		return nil, err
+	}
+	trashPath := filepath.ToSlash(filepath.Join(trashFolder, document.ULID.String()+"_"+filepath.Base(document.Path)))
+	files, err := moveDocumentFiles(document.Path, trashPath)
+	if err != nil {
+		return nil, fmt.Errorf("unable to move %s to the trash: %w", document.Name, err)
+	}
+	if err := serverHandler.DB.TrashDocument(document.ULID.String(), trashPath); err != nil {
+		undoFileMoves(files)
+		return nil, fmt.Errorf("unable to mark %s as deleted: %w", document.Name, err)
+	}
+	Logger.Info("Moved document to the trash", "ulid", document.ULID.String(), "from", document.Path, "to", trashPath)
+
+	deletedAt := time.Now()
+	document.DeletedAt = &deletedAt
+	document.DeletedFrom = document.Path
+	document.Path = trashPath
+	return &document, nil
+}
+
+// restoreDocument moves a document out of the trash back to where it was deleted from, recreating its folder if
+// that has since been removed.  Unless duplicates are kept as versions a document whose content has been ingested
+// again while it was in the trash is not restored, the DuplicateError names the copy that replaced it.  A document
+// with no file in the trash, one whose file was already missing when its folder was deleted, has only its record
+// restored.
+func (serverHandler *ServerHandler) restoreDocument(document database.Document) (*database.Document, error) {
+	if document.DeletedAt == nil {
+		return nil, errNotInTrash
+	}
+	storeMu.Lock()
+	defer storeMu.Unlock()
+
+	if serverHandler.ServerConfig.DuplicatePolicy != config.DuplicatePolicyVersion { //not stored in the database so read from the live config
+		existing, err := serverHandler.DB.GetDocumentByHash(document.Hash)
+		if err != nil {
+			return nil, err
+		}
+		if existing != nil {
+			return nil, &database.DuplicateError{FilePath: document.Path, Hash: document.Hash, Existing: existing}
+		}
+	}
+
+	restorePath := document.DeletedFrom
+	var files []fileMove
+	if _, err := os.Stat(document.Path); os.IsNotExist(err) {
+		Logger.Warn("Document has no file in the trash, restoring the record only", "ulid", document.ULID.String(), "path", document.Path)
+	} else {
+		if err := os.MkdirAll(filepath.Dir(restorePath), os.ModePerm); err != nil {
+			return nil, err
+		}
+		files, err = moveDocumentFiles(document.Path, restorePath)
+		if err != nil {
+			return nil, fmt.Errorf("unable to restore %s: %w", document.Name, err)
+		}
+	}
+	if err := serverHandler.DB.RestoreDocument(document.ULID.String(), restorePath); err != nil {
+		undoFileMoves(files)
+		return nil, fmt.Errorf("unable to mark %s as restored: %w", document.Name, err)
+	}
+	Logger.Info("Restored document from the trash", "ulid", document.ULID.String(), "path", restorePath)
+
+	document.DeletedAt = nil
+	document.DeletedFrom = ""
+	document.Path = restorePath
+	return &document, nil
+}
+
+// purgeDocument removes a document in the trash for good, from the database and then from disk
+func (serverHandler *ServerHandler) purgeDocument(document database.Document) error {
+	if document.DeletedAt == nil {
+		return errNotInTrash
+	}
+	if err := database.DeleteDocument(document.ULID.String(), serverHandler.DB); err != nil {
+		return err
+	}
+	for _, path := range append([]string{document.Path}, companionPaths(document.Path)...) {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			Logger.Error("Unable to remove purged document file", "path", path, "error", err)
+		}
+	}
+	serverHandler.removePreviews(document.ULID.String())
+	Logger.Info("Purged document from the trash", "ulid", document.ULID.String(), "name", document.Name)
+	return nil
+}
+
+// emptyTrash purges every document deleted before cutoff, returning how many were purged
+func (serverHandler *ServerHandler) emptyTrash(cutoff time.Time) (int, error) {
+	documents, err := serverHandler.DB.GetTrashedDocuments()
+	if err != nil {
+		return 0, err
+	}
+	purged := 0
+	for _, document := range documents {
+		if !document.DeletedAt.Before(cutoff) {
+			continue
+		}
+		if err := serverHandler.purgeDocument(document); err != nil {
+			Logger.Error("Unable to purge document from the trash", "ulid", document.ULID.String(), "error", err)
+			continue
+		}
+		purged++
+	}
+	return purged, nil
+}
+
+// expireTrash purges documents that have been in the trash longer than the retention period, it is what the
+// scheduled job runs
+func (serverHandler *ServerHandler) expireTrash() {
+	days := serverHandler.ServerConfig.TrashRetention
+	if days <= 0 {
+		return
+	}
+	purged, err := serverHandler.emptyTrash(time.Now().AddDate(0, 0, -days))
+	if err != nil {
+		Logger.Error("Unable to empty expired documents from the trash", "error", err)
+		return
+	}
+	if purged > 0 {
+		Logger.Info("Emptied expired documents from the trash", "purged", purged, "retention_days", days)
+	}
+}
+
+// trashFolder moves every document in a folder and its sub folders to the trash, marking them all deleted in one
+// transaction, then removes the folders left empty.  If a file cannot be moved or the database cannot be updated
+// the files already moved are put back and nothing is trashed.  Files that are not documents are left where they
+// are, and so are their folders.
+func (serverHandler *ServerHandler) trashFolder(folder string) ([]database.Document, error) {
+	folder, err := serverHandler.resolveDocumentFolder(folder)
+	if err != nil {
+		return nil, err
+	}
+	if folder == filepath.Clean(serverHandler.ServerConfig.DocumentPath) {
+		return nil, errFolderOutsideStore //never empty the whole document folder in one go
+	}
+	storeMu.Lock()
+	defer storeMu.Unlock()
+
+	documents, err := serverHandler.DB.GetDocumentsInFolder(filepath.ToSlash(folder))
+	if err != nil {
+		return nil, err
+	}
+	trashFolder := serverHandler.ServerConfig.TrashFolder
+	if err := os.MkdirAll(trashFolder, os.ModePerm); err != nil {
+		return nil, err
+	}
+	var moved []fileMove
+	trashPaths := make(map[string]string, len(documents))
+	for i, document := range documents {
+		trashPath := filepath.ToSlash(filepath.Join(trashFolder, document.ULID.String()+"_"+filepath.Base(document.Path)))
+		if _, err := os.Stat(document.Path); os.IsNotExist(err) {
+			Logger.Warn("Document file already missing, trashing the record only", "ulid", document.ULID.String(), "path", document.Path)
+		} else {
+			files, err := moveDocumentFiles(document.Path, trashPath)
+			if err != nil {
+				undoFileMoves(moved)
+				return nil, fmt.Errorf("unable to move %s to the trash: %w", document.Name, err)
+			}
+			moved = append(moved, files...)
+		}
+		trashPaths[document.ULID.String()] = trashPath
+		documents[i].DeletedFrom = document.Path
+		documents[i].Path = trashPath
+	}
+	if err := serverHandler.DB.TrashDocuments(trashPaths); err != nil {
+		undoFileMoves(moved)
+		return nil, fmt.Errorf("unable to mark documents as deleted: %w", err)
+	}
+	deletedAt := time.Now()
+	for i := range documents {
+		documents[i].DeletedAt = &deletedAt
+	}
+	removeEmptyFolders(folder)
+	Logger.Info("Moved folder to the trash", "folder", folder, "documents", len(documents))
+	return documents, nil
+}
+
+// removeEmptyFolders removes folder and the folders below it that are empty, deepest first
+func removeEmptyFolders(folder string) {
+	var folders []string
+	filepath.WalkDir(folder, func(path string, entry fs.DirEntry, err error) error {
+		if err == nil && entry.IsDir() {
+			folders = append(folders, path)
+		}
+		return nil
+	})
+	for i := len(folders) - 1; i >= 0; i-- {
+		os.Remove(folders[i]) //fails, leaving the folder, if anything is still in it
+	}
+}
+
+// companionPaths returns the paths the companion files of a document would have
+func companionPaths(path string) []string {
+	paths := make([]string, 0, len(companionExtensions))
+	for _, ext := range companionExtensions {
+		paths = append(paths, path+ext)
+	}
+	return paths
+}
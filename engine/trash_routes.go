@@ -0,0 +1,90 @@
+package engine
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/drummonds/goEDMS/database"
+	"github.com/labstack/echo/v4"
+)
+
+// ListTrash returns the documents in the trash, most recently deleted first
+func (serverHandler *ServerHandler) ListTrash(c echo.Context) error {
+	documents, err := serverHandler.DB.GetTrashedDocuments()
+	if err != nil {
+		Logger.Error("Failed to list trash", "error", err)
+		return c.JSON(http.StatusInternalServerError, map[string]interface{}{
+			"error": "Failed to read trash",
+		})
+	}
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"documents":     documents,
+		"count":         len(documents),
+		"retentionDays": serverHandler.ServerConfig.TrashRetention,
+	})
+}
+
+// RestoreTrashed moves a document out of the trash back to the folder it was deleted from
+func (serverHandler *ServerHandler) RestoreTrashed(c echo.Context) error {
+	document, httpStatus, err := database.FetchDocument(c.Param("id"), serverHandler.DB)
+	if err != nil {
+		return c.JSON(httpStatus, map[string]interface{}{
+			"error": "Document not found",
+		})
+	}
+	restored, err := serverHandler.restoreDocument(document)
+	if err != nil {
+		return serverHandler.trashError(c, document.ULID.String(), err)
+	}
+	return c.JSON(http.StatusOK, restored)
+}
+
+// PurgeTrashed removes a document in the trash for good
+func (serverHandler *ServerHandler) PurgeTrashed(c echo.Context) error {
+	document, httpStatus, err := database.FetchDocument(c.Param("id"), serverHandler.DB)
+	if err != nil {
+		return c.JSON(httpStatus, map[string]interface{}{
+			"error": "Document not found",
+		})
+	}
+	if err := serverHandler.purgeDocument(document); err != nil {
+		return serverHandler.trashError(c, document.ULID.String(), err)
+	}
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"message": "Document purged",
+		"id":      document.ULID.String(),
+	})
+}
+
+// EmptyTrash removes every document in the trash for good
+func (serverHandler *ServerHandler) EmptyTrash(c echo.Context) error {
+	purged, err := serverHandler.emptyTrash(time.Now())
+	if err != nil {
+		Logger.Error("Failed to empty trash", "error", err)
+		return c.JSON(http.StatusInternalServerError, map[string]interface{}{
+			"error": "Failed to empty trash",
+		})
+	}
+	Logger.Info("Emptied trash via API", "purged", purged)
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"message": "Trash emptied",
+		"purged":  purged,
+	})
+}
+
+// trashError responds to a failed restore or purge
+func (serverHandler *ServerHandler) trashError(c echo.Context, id string, err error) error {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, errNotInTrash):
+		status = http.StatusBadRequest
+	case errors.Is(err, errMoveConflict), errors.As(err, new(*database.DuplicateError)):
+		status = http.StatusConflict
+	default:
+		Logger.Error("Trash operation failed", "id", id, "error", err)
+	}
+	return c.JSON(status, map[string]interface{}{
+		"error": err.Error(),
+	})
+}
@@ -0,0 +1,314 @@
+package engine
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/drummonds/goEDMS/database"
+)
+
+// tusVersion is the version of the tus resumable upload protocol served at /api/upload
+const tusVersion = "1.0.0"
+
+var (
+	errUploadNotFound = errors.New("upload not found")
+	errUploadOffset   = errors.New("upload offset does not match the data received so far")
+	errUploadComplete = errors.New("upload is already complete")
+)
+
+// tusUpload is a resumable upload, saved as <id>.json beside the partly received file <id> in the upload folder.
+// Offset is worked out from the size of that file whenever the upload is loaded.
+type tusUpload struct {
+	ID        string        `json:"id"`
+	Length    int64         `json:"length"`
+	Offset    int64         `json:"offset"`
+	Metadata  string        `json:"metadata,omitempty"` //Upload-Metadata header as sent, returned on HEAD
+	FileName  string        `json:"fileName"`
+	Folder    string        `json:"folder,omitempty"`
+	CreatedAt time.Time     `json:"createdAt"`
+	UpdatedAt time.Time     `json:"updatedAt"`
+	Result    *uploadResult `json:"result,omitempty"` //set once the upload is complete and has been ingested
+}
+
+// uploadLock serialises requests for the same upload, a client retrying a chunk must not race the original.  Users
+// counts the requests holding or waiting for it, the lock is dropped from uploadLocks once there are none.
+type uploadLock struct {
+	sync.Mutex
+	users int
+}
+
+var (
+	uploadLocksMu sync.Mutex
+	uploadLocks   = make(map[string]*uploadLock)
+)
+
+// lockUpload locks an upload by id, returning the function that unlocks it.  Callers check the upload exists first
+// so ids made up by a client never get a lock.
+func lockUpload(id string) func() {
+	uploadLocksMu.Lock()
+	lock := uploadLocks[id]
+	if lock == nil {
+		lock = &uploadLock{}
+		uploadLocks[id] = lock
+	}
+	lock.users++
+	uploadLocksMu.Unlock()
+
+	lock.Lock()
+	return func() {
+		lock.Unlock()
+		uploadLocksMu.Lock()
+		lock.users--
+		if lock.users == 0 {
+			delete(uploadLocks, id)
+		}
+		uploadLocksMu.Unlock()
+	}
+}
+
+// uploadExists reports whether id names an upload that has a record, it is checked before taking the upload's lock
+// and checked again by loadUpload once the lock is held
+func (serverHandler *ServerHandler) uploadExists(id string) bool {
+	if !validName(id) {
+		return false
+	}
+	_, err := os.Stat(serverHandler.uploadDataPath(id) + ".json")
+	return err == nil
+}
+
+// parseTusMetadata decodes an Upload-Metadata header, comma separated keys each followed by an optional base64 value
+func parseTusMetadata(header string) (map[string]string, error) {
+	metadata := make(map[string]string)
+	for _, pair := range strings.Split(header, ",") {
+		fields := strings.Fields(pair)
+		switch len(fields) {
+		case 0:
+			continue
+		case 1:
+			metadata[fields[0]] = ""
+		case 2:
+			value, err := base64.StdEncoding.DecodeString(fields[1])
+			if err != nil {
+				return nil, fmt.Errorf("metadata %s is not base64: %w", fields[0], err)
+			}
+			metadata[fields[0]] = string(value)
+		default:
+			return nil, fmt.Errorf("malformed metadata %q", pair)
+		}
+	}
+	return metadata, nil
+}
+
+// uploadDataPath is where the data received for an upload is kept
+func (serverHandler *ServerHandler) uploadDataPath(id string) string {
+	return filepath.Join(serverHandler.ServerConfig.UploadFolder, id)
+}
+
+// createUpload records a new upload of length bytes and creates its empty data file
+func (serverHandler *ServerHandler) createUpload(length int64, metadataHeader string) (*tusUpload, error) {
+	metadata, err := parseTusMetadata(metadataHeader)
+	if err != nil {
+		return nil, err
+	}
+	fileName := metadata["filename"]
+	if fileName == "" {
+		fileName = metadata["name"] //Uppy sends both, other clients only name
+	}
+	fileName, err = uploadFileName(fileName)
+	if err != nil {
+		return nil, err
+	}
+	folder, err := uploadFolder(metadata["path"])
+	if err != nil {
+		return nil, err
+	}
+	id, err := database.CalculateUUID(time.Now())
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	upload := &tusUpload{
+		ID: id.String(), Length: length, Metadata: metadataHeader, FileName: fileName, Folder: folder,
+		CreatedAt: now, UpdatedAt: now,
+	}
+	if err := os.MkdirAll(serverHandler.ServerConfig.UploadFolder, os.ModePerm); err != nil {
+		return nil, err
+	}
+	data, err := os.OpenFile(serverHandler.uploadDataPath(upload.ID), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return nil, err
+	}
+	data.Close()
+	if err := serverHandler.saveUpload(upload); err != nil {
+		os.Remove(serverHandler.uploadDataPath(upload.ID))
+		return nil, err
+	}
+	Logger.Info("Created resumable upload", "id", upload.ID, "fileName", fileName, "length", length)
+	return upload, nil
+}
+
+// saveUpload writes an upload's record, replacing the old one in a single rename
+func (serverHandler *ServerHandler) saveUpload(upload *tusUpload) error {
+	body, err := json.MarshalIndent(upload, "", "  ")
+	if err != nil {
+		return err
+	}
+	infoPath := serverHandler.uploadDataPath(upload.ID) + ".json"
+	if err := os.WriteFile(infoPath+".tmp", body, 0644); err != nil {
+		return err
+	}
+	return os.Rename(infoPath+".tmp", infoPath)
+}
+
+// getUpload loads an upload once no request is writing to it
+func (serverHandler *ServerHandler) getUpload(id string) (*tusUpload, error) {
+	if !serverHandler.uploadExists(id) {
+		return nil, errUploadNotFound
+	}
+	defer lockUpload(id)()
+	return serverHandler.loadUpload(id)
+}
+
+// loadUpload reads an upload's record and works out how much of it has arrived
+func (serverHandler *ServerHandler) loadUpload(id string) (*tusUpload, error) {
+	if !validName(id) {
+		return nil, errUploadNotFound
+	}
+	body, err := os.ReadFile(serverHandler.uploadDataPath(id) + ".json")
+	if os.IsNotExist(err) {
+		return nil, errUploadNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	upload := &tusUpload{}
+	if err := json.Unmarshal(body, upload); err != nil {
+		return nil, err
+	}
+	if upload.Result != nil { //the data has been handed over to ingestion
+		upload.Offset = upload.Length
+		return upload, nil
+	}
+	info, err := os.Stat(serverHandler.uploadDataPath(id))
+	if err != nil {
+		return nil, err
+	}
+	upload.Offset = info.Size()
+	return upload, nil
+}
+
+// appendUpload writes the next chunk of an upload, which must start at offset.  Whatever arrives is kept even if
+// the connection drops, so the client can carry on from the new offset.  Once every byte is in the file is ingested.
+func (serverHandler *ServerHandler) appendUpload(id string, offset int64, chunk io.Reader) (*tusUpload, error) {
+	if !serverHandler.uploadExists(id) {
+		return nil, errUploadNotFound
+	}
+	defer lockUpload(id)()
+	upload, err := serverHandler.loadUpload(id)
+	if err != nil {
+		return nil, err
+	}
+	if upload.Result != nil {
+		return upload, errUploadComplete
+	}
+	if offset != upload.Offset {
+		return upload, errUploadOffset
+	}
+	data, err := os.OpenFile(serverHandler.uploadDataPath(id), os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, err
+	}
+	written, copyErr := io.Copy(data, io.LimitReader(chunk, upload.Length-upload.Offset))
+	if err := data.Close(); copyErr == nil {
+		copyErr = err
+	}
+	upload.Offset += written
+	upload.UpdatedAt = time.Now()
+	if upload.Offset == upload.Length {
+		serverHandler.finishUpload(upload)
+	}
+	if err := serverHandler.saveUpload(upload); err != nil {
+		return nil, err
+	}
+	return upload, copyErr
+}
+
+// finishUpload moves a complete upload into the ingress folder and ingests it the same way as a form upload
+func (serverHandler *ServerHandler) finishUpload(upload *tusUpload) {
+	dataPath := serverHandler.uploadDataPath(upload.ID)
+	result := serverHandler.ingestUpload(upload.FileName, upload.Folder, func(filePath string) error {
+		if _, err := os.Lstat(filePath); err == nil {
+			return fmt.Errorf("%w: %s", errMoveConflict, filepath.Base(filePath))
+		}
+		return os.Rename(dataPath, filePath)
+	})
+	os.Remove(dataPath) //already gone unless it could not be moved into ingress
+	Logger.Info("Resumable upload complete", "id", upload.ID, "fileName", result.FileName, "ulid", result.ULID, "error", result.Error)
+	upload.Result = &result
+}
+
+// removeUpload deletes an upload's data and record
+func (serverHandler *ServerHandler) removeUpload(id string) error {
+	if !serverHandler.uploadExists(id) {
+		return errUploadNotFound
+	}
+	defer lockUpload(id)()
+	if _, err := serverHandler.loadUpload(id); err != nil {
+		return err
+	}
+	return serverHandler.removeUploadFiles(id)
+}
+
+// removeUploadFiles deletes the data and record of an upload the caller holds the lock for
+func (serverHandler *ServerHandler) removeUploadFiles(id string) error {
+	if err := os.Remove(serverHandler.uploadDataPath(id)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return os.Remove(serverHandler.uploadDataPath(id) + ".json")
+}
+
+// uploadExpires is when an upload that was last written at updatedAt is thrown away
+func (serverHandler *ServerHandler) uploadExpires(updatedAt time.Time) time.Time {
+	return updatedAt.Add(time.Duration(serverHandler.ServerConfig.UploadExpiry) * time.Hour)
+}
+
+// expireUploads throws away uploads that have had no data for longer than the expiry period, and the records of
+// finished uploads once they are as old, it is what the scheduled job runs
+func (serverHandler *ServerHandler) expireUploads() {
+	records, err := filepath.Glob(filepath.Join(serverHandler.ServerConfig.UploadFolder, "*.json"))
+	if err != nil {
+		Logger.Error("Unable to list resumable uploads", "error", err)
+		return
+	}
+	for _, record := range records {
+		serverHandler.expireUpload(strings.TrimSuffix(filepath.Base(record), ".json"), time.Now())
+	}
+}
+
+// expireUpload removes an upload if it has expired by now, checking under its lock so a PATCH that arrives at the
+// same time is not thrown away
+func (serverHandler *ServerHandler) expireUpload(id string, now time.Time) {
+	if !serverHandler.uploadExists(id) {
+		return
+	}
+	defer lockUpload(id)()
+	upload, err := serverHandler.loadUpload(id)
+	if err != nil || now.Before(serverHandler.uploadExpires(upload.UpdatedAt)) {
+		return
+	}
+	if err := serverHandler.removeUploadFiles(id); err != nil {
+		Logger.Error("Unable to remove expired upload", "id", id, "error", err)
+		return
+	}
+	if upload.Result == nil {
+		Logger.Info("Removed abandoned upload", "id", id, "fileName", upload.FileName, "received", upload.Offset, "length", upload.Length)
+	}
+}
@@ -0,0 +1,168 @@
+package engine
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// tusHeaders sets the headers every tus response carries
+func tusHeaders(c echo.Context) {
+	c.Response().Header().Set("Tus-Resumable", tusVersion)
+	c.Response().Header().Set("Cache-Control", "no-store")
+}
+
+// tusVersionOK checks the client speaks our version of tus, responding 412 if it does not
+func tusVersionOK(c echo.Context) bool {
+	if c.Request().Header.Get("Tus-Resumable") == tusVersion {
+		return true
+	}
+	c.Response().Header().Set("Tus-Version", tusVersion)
+	c.NoContent(http.StatusPreconditionFailed)
+	return false
+}
+
+// TusOptions describes the resumable upload server to tus clients
+func (serverHandler *ServerHandler) TusOptions(c echo.Context) error {
+	tusHeaders(c)
+	header := c.Response().Header()
+	header.Set("Tus-Version", tusVersion)
+	header.Set("Tus-Extension", "creation,expiration,termination")
+	if maxSize := serverHandler.uploadMaxSize(); maxSize > 0 {
+		header.Set("Tus-Max-Size", strconv.FormatInt(maxSize, 10))
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+// TusCreate starts a resumable upload of Upload-Length bytes.  The file name, and optionally a folder below the
+// ingress folder, are taken from the filename (or name) and path keys of Upload-Metadata.
+func (serverHandler *ServerHandler) TusCreate(c echo.Context) error {
+	tusHeaders(c)
+	if !tusVersionOK(c) {
+		return nil
+	}
+	length, err := strconv.ParseInt(c.Request().Header.Get("Upload-Length"), 10, 64)
+	if err != nil || length < 0 {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"error": "Upload-Length must be given, deferred lengths are not supported",
+		})
+	}
+	if maxSize := serverHandler.uploadMaxSize(); maxSize > 0 && length > maxSize {
+		return c.JSON(http.StatusRequestEntityTooLarge, map[string]interface{}{
+			"error": errUploadTooLarge.Error(),
+		})
+	}
+	upload, err := serverHandler.createUpload(length, c.Request().Header.Get("Upload-Metadata"))
+	if err != nil {
+		Logger.Warn("Unable to create resumable upload", "error", err)
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"error": err.Error(),
+		})
+	}
+	c.Response().Header().Set("Location", strings.TrimSuffix(c.Request().URL.Path, "/")+"/"+upload.ID)
+	c.Response().Header().Set("Upload-Expires", serverHandler.uploadExpires(upload.UpdatedAt).UTC().Format(http.TimeFormat))
+	if length == 0 { //nothing to wait for
+		serverHandler.appendUpload(upload.ID, 0, http.NoBody)
+	}
+	return c.NoContent(http.StatusCreated)
+}
+
+// TusHead reports how much of an upload has arrived, so the client knows where to resume
+func (serverHandler *ServerHandler) TusHead(c echo.Context) error {
+	tusHeaders(c)
+	if !tusVersionOK(c) {
+		return nil
+	}
+	upload, err := serverHandler.getUpload(c.Param("id"))
+	if err != nil {
+		return serverHandler.tusError(c, c.Param("id"), err)
+	}
+	setUploadHeaders(c, upload, serverHandler.uploadExpires(upload.UpdatedAt))
+	c.Response().Header().Set("Upload-Length", strconv.FormatInt(upload.Length, 10))
+	if upload.Metadata != "" {
+		c.Response().Header().Set("Upload-Metadata", upload.Metadata)
+	}
+	return c.NoContent(http.StatusOK)
+}
+
+// TusPatch appends a chunk to an upload at Upload-Offset.  The chunk that completes the upload ingests the file
+// before the response is sent, GET on the upload then gives the result.
+func (serverHandler *ServerHandler) TusPatch(c echo.Context) error {
+	tusHeaders(c)
+	if !tusVersionOK(c) {
+		return nil
+	}
+	if c.Request().Header.Get("Content-Type") != "application/offset+octet-stream" {
+		return c.JSON(http.StatusUnsupportedMediaType, map[string]interface{}{
+			"error": "Content-Type must be application/offset+octet-stream",
+		})
+	}
+	offset, err := strconv.ParseInt(c.Request().Header.Get("Upload-Offset"), 10, 64)
+	if err != nil || offset < 0 {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"error": "Upload-Offset must be given",
+		})
+	}
+	upload, err := serverHandler.appendUpload(c.Param("id"), offset, c.Request().Body)
+	if errors.Is(err, errUploadComplete) && offset == upload.Length { //a retry of the last chunk after a lost response
+		err = nil
+	}
+	if err != nil {
+		if upload != nil && !errors.Is(err, errUploadOffset) && !errors.Is(err, errUploadComplete) {
+			Logger.Warn("Resumable upload chunk interrupted", "id", upload.ID, "offset", upload.Offset, "error", err)
+		}
+		return serverHandler.tusError(c, c.Param("id"), err)
+	}
+	setUploadHeaders(c, upload, serverHandler.uploadExpires(upload.UpdatedAt))
+	return c.NoContent(http.StatusNoContent)
+}
+
+// TusDelete abandons an upload, throwing away what has arrived
+func (serverHandler *ServerHandler) TusDelete(c echo.Context) error {
+	tusHeaders(c)
+	if !tusVersionOK(c) {
+		return nil
+	}
+	if err := serverHandler.removeUpload(c.Param("id")); err != nil {
+		return serverHandler.tusError(c, c.Param("id"), err)
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+// GetUpload returns an upload's progress and, once it is complete, the ULID of the stored document or the error
+// that stopped it.  It is not part of tus so can be called without the tus headers.
+func (serverHandler *ServerHandler) GetUpload(c echo.Context) error {
+	upload, err := serverHandler.getUpload(c.Param("id"))
+	if err != nil {
+		return serverHandler.tusError(c, c.Param("id"), err)
+	}
+	return c.JSON(http.StatusOK, upload)
+}
+
+// setUploadHeaders sets the offset and expiry of an upload on the response
+func setUploadHeaders(c echo.Context, upload *tusUpload, expires time.Time) {
+	c.Response().Header().Set("Upload-Offset", strconv.FormatInt(upload.Offset, 10))
+	if upload.Result == nil {
+		c.Response().Header().Set("Upload-Expires", expires.UTC().Format(http.TimeFormat))
+	}
+}
+
+// tusError responds to a failed upload request
+func (serverHandler *ServerHandler) tusError(c echo.Context, id string, err error) error {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, errUploadNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, errUploadOffset), errors.Is(err, errUploadComplete):
+		status = http.StatusConflict
+	default:
+		Logger.Error("Resumable upload failed", "id", id, "error", err)
+	}
+	return c.JSON(status, map[string]interface{}{
+		"error": err.Error(),
+	})
+}
@@ -0,0 +1,114 @@
+package engine
+
+import (
+	"errors"
+	"log/slog"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseTusMetadata(t *testing.T) {
+	metadata, err := parseTusMetadata("filename c2Nhbi5wZGY=,path aW52b2ljZXM=, is_confidential")
+	if err != nil {
+		t.Fatalf("parseTusMetadata failed: %v", err)
+	}
+	want := map[string]string{"filename": "scan.pdf", "path": "invoices", "is_confidential": ""}
+	for key, value := range want {
+		if metadata[key] != value {
+			t.Errorf("metadata[%q] = %q, want %q", key, metadata[key], value)
+		}
+	}
+	if _, err := parseTusMetadata("filename not-base64!"); err == nil {
+		t.Errorf("Expected an error for a value that is not base64")
+	}
+}
+
+func TestLockUpload(t *testing.T) {
+	unlock := lockUpload("upload")
+	locked, done := make(chan struct{}), make(chan struct{})
+	go func() {
+		unlockSecond := lockUpload("upload")
+		close(locked)
+		unlockSecond()
+		close(done)
+	}()
+	select {
+	case <-locked:
+		t.Fatal("Expected a second request to wait for the lock")
+	case <-time.After(50 * time.Millisecond):
+	}
+	unlock() //the waiting request must get the same lock, not a new one
+	select {
+	case <-locked:
+	case <-time.After(time.Second):
+		t.Fatal("Expected the waiting request to get the lock once it was released")
+	}
+	<-done
+	uploadLocksMu.Lock()
+	defer uploadLocksMu.Unlock()
+	if _, ok := uploadLocks["upload"]; ok {
+		t.Error("Expected the lock to be dropped once nothing holds it")
+	}
+}
+
+func TestResumableUpload(t *testing.T) {
+	Logger = slog.New(slog.NewTextHandler(os.Stdout, nil))
+	serverHandler := &ServerHandler{}
+	serverHandler.ServerConfig.UploadFolder = t.TempDir()
+	serverHandler.ServerConfig.UploadExpiry = 24
+
+	upload, err := serverHandler.createUpload(10, "filename c2Nhbi5wZGY=")
+	if err != nil {
+		t.Fatalf("createUpload failed: %v", err)
+	}
+	if upload.FileName != "scan.pdf" || upload.Offset != 0 {
+		t.Errorf("Expected an empty upload of scan.pdf, got %+v", upload)
+	}
+	if _, err := serverHandler.createUpload(10, "filename Li4v"); err == nil { //"../"
+		t.Errorf("Expected an upload without a usable file name to be refused")
+	}
+
+	upload, err = serverHandler.appendUpload(upload.ID, 0, strings.NewReader("first"))
+	if err != nil || upload.Offset != 5 {
+		t.Fatalf("Expected offset 5 after the first chunk, got %v (%v)", upload, err)
+	}
+	if _, err := serverHandler.appendUpload(upload.ID, 0, strings.NewReader("first")); !errors.Is(err, errUploadOffset) {
+		t.Errorf("Expected errUploadOffset resending a chunk already received, got %v", err)
+	}
+	loaded, err := serverHandler.getUpload(upload.ID)
+	if err != nil || loaded.Offset != 5 || loaded.Length != 10 {
+		t.Errorf("Expected the upload to resume at 5 of 10, got %+v (%v)", loaded, err)
+	}
+	if _, err := serverHandler.getUpload("../" + upload.ID); !errors.Is(err, errUploadNotFound) {
+		t.Errorf("Expected errUploadNotFound for an id outside the upload folder, got %v", err)
+	}
+	for _, id := range []string{"01K7WTQXY83JPQRHTXEADHQW4V", "not-an-upload"} {
+		if _, err := serverHandler.appendUpload(id, 0, strings.NewReader("data")); !errors.Is(err, errUploadNotFound) {
+			t.Errorf("Expected errUploadNotFound for unknown upload %q, got %v", id, err)
+		}
+	}
+	uploadLocksMu.Lock()
+	locks := len(uploadLocks)
+	uploadLocksMu.Unlock()
+	if locks != 0 {
+		t.Errorf("Expected no upload locks left once requests finish, got %d", locks)
+	}
+
+	serverHandler.expireUploads()
+	if _, err := serverHandler.getUpload(upload.ID); err != nil {
+		t.Fatalf("Expected a recent upload to be kept: %v", err)
+	}
+	loaded.UpdatedAt = time.Now().Add(-25 * time.Hour)
+	if err := serverHandler.saveUpload(loaded); err != nil {
+		t.Fatalf("saveUpload failed: %v", err)
+	}
+	serverHandler.expireUploads()
+	if _, err := serverHandler.getUpload(upload.ID); !errors.Is(err, errUploadNotFound) {
+		t.Errorf("Expected an abandoned upload to expire, got %v", err)
+	}
+	if _, err := os.Stat(serverHandler.uploadDataPath(upload.ID)); !os.IsNotExist(err) {
+		t.Errorf("Expected the data of an expired upload to be removed")
+	}
+}
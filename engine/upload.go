@@ -0,0 +1,147 @@
+package engine
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/drummonds/goEDMS/database"
+)
+
+var errUploadTooLarge = errors.New("file is larger than the upload limit")
+
+// uploadResult is the outcome of one file in an upload, ULID is the stored document or, for a duplicate, the
+// document it duplicates
+type uploadResult struct {
+	FileName string `json:"fileName"`
+	ULID     string `json:"ulid,omitempty"`
+	Error    string `json:"error,omitempty"`
+	status   int
+}
+
+// uploadFileName reduces a client supplied file name to its last element, whichever separator the client used.
+// It returns errInvalidName if nothing usable is left.
+func uploadFileName(name string) (string, error) {
+	name = strings.ReplaceAll(name, `\`, "/")
+	name = strings.TrimSpace(name[strings.LastIndex(name, "/")+1:])
+	if !validName(name) {
+		return "", errInvalidName
+	}
+	return name, nil
+}
+
+// uploadFolder checks a client supplied folder below the ingress folder, returning it cleaned with forward slashes.
+// Leading slashes are dropped so it is always taken relative to the ingress folder, anything that climbs out of it
+// is refused with errFolderOutsideStore.
+func uploadFolder(folder string) (string, error) {
+	folder = strings.Trim(strings.ReplaceAll(strings.TrimSpace(folder), `\`, "/"), "/")
+	if folder == "" {
+		return "", nil
+	}
+	if !filepath.IsLocal(filepath.FromSlash(folder)) {
+		return "", errFolderOutsideStore
+	}
+	return filepath.ToSlash(filepath.Clean(folder)), nil
+}
+
+// writeUpload streams an uploaded file to filePath.  It is written to a .part file alongside, claimed so the ingress
+// scan and watcher leave it alone, and only renamed into place once complete.  maxSize is in bytes, 0 for no limit.
+func writeUpload(src io.Reader, filePath string, maxSize int64) error {
+	partPath := filePath + ".part"
+	if !claimIngressFile(partPath) {
+		return fmt.Errorf("%w: %s", errMoveConflict, filepath.Base(filePath))
+	}
+	defer releaseIngressFile(partPath)
+	part, err := os.OpenFile(partPath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return err
+	}
+	if maxSize > 0 {
+		src = io.LimitReader(src, maxSize+1) //one byte over the limit is enough to know it is too large
+	}
+	written, err := io.Copy(part, src)
+	if closeErr := part.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil && maxSize > 0 && written > maxSize {
+		err = errUploadTooLarge
+	}
+	if err == nil {
+		if _, statErr := os.Lstat(filePath); statErr == nil {
+			err = fmt.Errorf("%w: %s", errMoveConflict, filepath.Base(filePath))
+		} else {
+			err = os.Rename(partPath, filePath)
+		}
+	}
+	if err != nil {
+		os.Remove(partPath)
+		return err
+	}
+	return nil
+}
+
+// uploadMaxSize is the largest file accepted by upload in bytes, 0 for no limit
+func (serverHandler *ServerHandler) uploadMaxSize() int64 {
+	return int64(serverHandler.ServerConfig.UploadMaxSize) << 20 //not stored in the database so read from the live config
+}
+
+// ingestUpload puts one uploaded file into folder below the ingress folder, with place writing it to the path it is
+// given, and ingests it straight away
+func (serverHandler *ServerHandler) ingestUpload(fileName string, folder string, place func(filePath string) error) uploadResult {
+	result := uploadResult{FileName: fileName, status: http.StatusOK}
+	fail := func(status int, err error) uploadResult {
+		result.status = status
+		result.Error = err.Error()
+		return result
+	}
+	name, err := uploadFileName(fileName)
+	if err != nil {
+		return fail(http.StatusBadRequest, err)
+	}
+	result.FileName = name
+	//Upload it to the ingress folder so if there is an issue it will stick there and not in the documents folder
+	filePath, err := safePath(serverHandler.ServerConfig.IngressPath, folder+"/"+name)
+	if err != nil {
+		return fail(http.StatusBadRequest, err)
+	}
+	if err := os.MkdirAll(filepath.Dir(filePath), os.ModePerm); err != nil { //since this is the ingress folder we MAY need to create the directory path
+		Logger.Error("Unable to create filepath for upload", "path", filePath, "error", err)
+		return fail(http.StatusInternalServerError, err)
+	}
+	if !claimIngressFile(filePath) { //keep the ingress watcher off the file while we write and ingest it
+		return fail(http.StatusConflict, errors.New("a file with this name is already being ingested"))
+	}
+	defer releaseIngressFile(filePath)
+
+	if err := place(filePath); err != nil {
+		switch {
+		case errors.Is(err, errUploadTooLarge):
+			return fail(http.StatusRequestEntityTooLarge, err)
+		case errors.Is(err, errMoveConflict):
+			return fail(http.StatusConflict, err)
+		}
+		Logger.Error("Unable to place uploaded file in ingress", "path", filePath, "error", err)
+		return fail(http.StatusInternalServerError, err)
+	}
+	if _, err := ExtractorFor(filePath); err != nil { //refuse anything we have no extractor for rather than leaving it in ingress
+		Logger.Warn("Rejecting upload of unsupported file type", "path", filePath, "error", err)
+		os.Remove(filePath)
+		return fail(http.StatusUnsupportedMediaType, err)
+	}
+
+	document, err := serverHandler.ingressDocument(filePath, "upload")
+	var duplicate *database.DuplicateError
+	if errors.As(err, &duplicate) {
+		result.ULID = duplicate.Existing.ULID.String()
+		return fail(http.StatusConflict, err)
+	}
+	if err != nil {
+		return fail(http.StatusUnprocessableEntity, err)
+	}
+	result.ULID = document.ULID.String()
+	return result
+}
@@ -0,0 +1,98 @@
+package engine
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestUploadFileName(t *testing.T) {
+	tests := []struct {
+		name    string
+		want    string
+		wantErr error
+	}{
+		{"invoice.pdf", "invoice.pdf", nil},
+		{"scans/invoice.pdf", "invoice.pdf", nil},
+		{`C:\Users\me\invoice.pdf`, "invoice.pdf", nil},
+		{"../../etc/passwd", "passwd", nil},
+		{" padded.txt ", "padded.txt", nil},
+		{"", "", errInvalidName},
+		{"..", "", errInvalidName},
+		{"folder/", "", errInvalidName},
+	}
+	for _, tt := range tests {
+		got, err := uploadFileName(tt.name)
+		if got != tt.want || err != tt.wantErr {
+			t.Errorf("uploadFileName(%q) = %q, %v; want %q, %v", tt.name, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestUploadFolder(t *testing.T) {
+	tests := []struct {
+		folder  string
+		want    string
+		wantErr error
+	}{
+		{"", "", nil},
+		{"invoices/", "invoices", nil},
+		{`invoices\2024`, "invoices/2024", nil},
+		{"invoices/./2024", "invoices/2024", nil},
+		{"/invoices", "invoices", nil},
+		{"invoices/../receipts", "receipts", nil},
+		{"..", "", errFolderOutsideStore},
+		{"invoices/../../outside", "", errFolderOutsideStore},
+	}
+	for _, tt := range tests {
+		got, err := uploadFolder(tt.folder)
+		if got != tt.want || err != tt.wantErr {
+			t.Errorf("uploadFolder(%q) = %q, %v; want %q, %v", tt.folder, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestWriteUpload(t *testing.T) {
+	dir := t.TempDir()
+
+	t.Run("Writes the whole file", func(t *testing.T) {
+		filePath := filepath.Join(dir, "notes.txt")
+		if err := writeUpload(strings.NewReader("some notes"), filePath, 10); err != nil {
+			t.Fatalf("writeUpload failed: %v", err)
+		}
+		if body, err := os.ReadFile(filePath); err != nil || string(body) != "some notes" {
+			t.Errorf("Expected file contents %q, got %q (%v)", "some notes", body, err)
+		}
+		if _, err := os.Stat(filePath + ".part"); !os.IsNotExist(err) {
+			t.Errorf("Expected the .part file to be gone")
+		}
+	})
+
+	t.Run("Refuses a file over the limit", func(t *testing.T) {
+		filePath := filepath.Join(dir, "large.txt")
+		err := writeUpload(strings.NewReader("eleven byte"), filePath, 10)
+		if !errors.Is(err, errUploadTooLarge) {
+			t.Errorf("Expected errUploadTooLarge, got %v", err)
+		}
+		for _, path := range []string{filePath, filePath + ".part"} {
+			if _, err := os.Stat(path); !os.IsNotExist(err) {
+				t.Errorf("Expected %s not to be left behind", path)
+			}
+		}
+	})
+
+	t.Run("Leaves an existing file alone", func(t *testing.T) {
+		filePath := filepath.Join(dir, "taken.txt")
+		if err := os.WriteFile(filePath, []byte("original"), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", filePath, err)
+		}
+		if err := writeUpload(strings.NewReader("replacement"), filePath, 0); !errors.Is(err, errMoveConflict) {
+			t.Errorf("Expected errMoveConflict, got %v", err)
+		}
+		if body, _ := os.ReadFile(filePath); string(body) != "original" {
+			t.Errorf("Expected existing file to be kept, got %q", body)
+		}
+	})
+}
@@ -0,0 +1,66 @@
+package engine
+
+import (
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"github.com/drummonds/goEDMS/database"
+	"github.com/labstack/echo/v4"
+)
+
+// ViewDocument serves the file of a document, looking up where it is now so moved and renamed documents keep their
+// URL.  Range requests and conditional GETs (the ETag is the document hash) are handled by http.ServeContent.  The
+// file is shown inline unless ?download=1 asks for it as an attachment.
+func (serverHandler *ServerHandler) ViewDocument(c echo.Context) error {
+	document, httpStatus, err := fetchStoredDocument(c.Param("id"), serverHandler.DB)
+	if err != nil {
+		return c.JSON(httpStatus, map[string]interface{}{
+			"error": "Document not found",
+		})
+	}
+	file, err := os.Open(document.Path)
+	if err != nil {
+		Logger.Error("Unable to open document file", "ulid", document.ULID.String(), "path", document.Path, "error", err)
+		return c.JSON(http.StatusNotFound, map[string]interface{}{
+			"error": "Document file not found",
+		})
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil || info.IsDir() {
+		return c.JSON(http.StatusNotFound, map[string]interface{}{
+			"error": "Document file not found",
+		})
+	}
+
+	name := filepath.Base(document.Path)
+	response := c.Response()
+	if contentType := mime.TypeByExtension(filepath.Ext(name)); contentType != "" {
+		response.Header().Set(echo.HeaderContentType, contentType)
+	} //otherwise ServeContent sniffs it from the first bytes of the file
+	if document.Hash != "" {
+		response.Header().Set("ETag", `"`+document.Hash+`"`)
+	}
+	disposition := "inline"
+	if c.QueryParam("download") == "1" {
+		disposition = "attachment"
+	}
+	response.Header().Set(echo.HeaderContentDisposition, mime.FormatMediaType(disposition, map[string]string{"filename": name}))
+
+	http.ServeContent(response, c.Request(), name, info.ModTime(), file)
+	return nil
+}
+
+// fetchStoredDocument fetches a document by ULID for viewing, a document in the trash is not found
+func fetchStoredDocument(docULIDSt string, db database.DBInterface) (database.Document, int, error) {
+	document, httpStatus, err := database.FetchDocument(docULIDSt, db)
+	if err != nil {
+		return document, httpStatus, err
+	}
+	if document.DeletedAt != nil {
+		return database.Document{}, http.StatusNotFound, errInTrash
+	}
+	return document, http.StatusOK, nil
+}
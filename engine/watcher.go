@@ -0,0 +1,218 @@
+package engine
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// ingressInFlight holds the paths currently being ingested so the watcher, the scheduled poll and uploads never
+// process the same file twice at once
+var ingressInFlight sync.Map
+
+// ingressWatchActive is set once the watcher is running, the scheduled poll then leaves freshly written files to it
+var ingressWatchActive atomic.Bool
+
+// claimIngressFile marks a file as being ingested, it returns false if someone else already has it
+func claimIngressFile(filePath string) bool {
+	_, loaded := ingressInFlight.LoadOrStore(filepath.Clean(filePath), true)
+	return !loaded
+}
+
+func releaseIngressFile(filePath string) {
+	ingressInFlight.Delete(filepath.Clean(filePath))
+}
+
+func ingressFileInFlight(filePath string) bool {
+	_, found := ingressInFlight.Load(filepath.Clean(filePath))
+	return found
+}
+
+// ingressWatcher ingests files dropped into the ingress folder as soon as they have finished being written
+type ingressWatcher struct {
+	watcher     *fsnotify.Watcher
+	quietPeriod time.Duration           //a file must not change for this long before it is ingested
+	pending     map[string]*pendingFile //files seen changing that have not settled yet
+	queue       chan string             //settled files waiting for a worker, full queues leave files pending
+	workers     int                     //number of goroutines draining the queue
+	ingest      func(filePath string)   //called by a worker for every settled file
+}
+
+// pendingFile is the last size and modification time seen for a file that is still being written
+type pendingFile struct {
+	lastChange time.Time
+	size       int64
+	modTime    time.Time
+}
+
+// startIngressWatcher watches the ingress folder (and every folder below it) and ingests new files once their size
+// has stopped changing for IngressQuietPeriod seconds.  The scheduled poll keeps running as a fallback.
+func (serverHandler *ServerHandler) startIngressWatcher() error {
+	quietPeriod := time.Duration(serverHandler.ServerConfig.IngressQuietPeriod) * time.Second
+	ingressWatcher, err := newIngressWatcher(serverHandler.ServerConfig.IngressPath, quietPeriod, serverHandler.ServerConfig.IngressWorkers, func(filePath string) {
+		serverHandler.acquireIngestSlot() //shared with the poll so a scanner batch can't swamp the machine
+		defer releaseIngestSlot()
+		Logger.Info("Ingesting file picked up by ingress watcher", "filePath", filePath)
+		serverHandler.ingressDocument(filePath, "ingress")
+	})
+	if err != nil {
+		return err
+	}
+	ingressWatchActive.Store(true)
+	go func() {
+		ingressWatcher.run()
+		ingressWatchActive.Store(false)
+	}()
+	Logger.Info("Watching ingress folder for new files", "path", serverHandler.ServerConfig.IngressPath, "quietPeriodSeconds", serverHandler.ServerConfig.IngressQuietPeriod)
+	return nil
+}
+
+func newIngressWatcher(root string, quietPeriod time.Duration, workers int, ingest func(filePath string)) (*ingressWatcher, error) {
+	if workers < 1 {
+		workers = 1
+	}
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	ingressWatcher := &ingressWatcher{
+		watcher:     watcher,
+		quietPeriod: quietPeriod,
+		pending:     make(map[string]*pendingFile),
+		queue:       make(chan string, workers),
+		workers:     workers,
+		ingest:      ingest,
+	}
+	err = filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return watcher.Add(path)
+		}
+		return nil
+	})
+	if err != nil {
+		watcher.Close()
+		return nil, err
+	}
+	return ingressWatcher, nil
+}
+
+// run handles watcher events until the watcher is closed, the workers finish the files already queued
+func (ingressWatcher *ingressWatcher) run() {
+	for i := 0; i < ingressWatcher.workers; i++ {
+		go func() {
+			for filePath := range ingressWatcher.queue {
+				ingressWatcher.ingest(filePath)
+				releaseIngressFile(filePath)
+			}
+		}()
+	}
+	defer close(ingressWatcher.queue)
+	checkInterval := ingressWatcher.quietPeriod / 4
+	if checkInterval < 50*time.Millisecond {
+		checkInterval = 50 * time.Millisecond
+	}
+	ticker := time.NewTicker(checkInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case event, ok := <-ingressWatcher.watcher.Events:
+			if !ok {
+				return
+			}
+			ingressWatcher.handleEvent(event)
+		case err, ok := <-ingressWatcher.watcher.Errors:
+			if !ok {
+				return
+			}
+			Logger.Error("Ingress watcher error", "error", err) //an overflow loses events, the scheduled poll picks those files up
+		case now := <-ticker.C:
+			ingressWatcher.ingestSettled(now)
+		}
+	}
+}
+
+func (ingressWatcher *ingressWatcher) close() error {
+	return ingressWatcher.watcher.Close()
+}
+
+func (ingressWatcher *ingressWatcher) handleEvent(event fsnotify.Event) {
+	if event.Has(fsnotify.Remove) || event.Has(fsnotify.Rename) { //the old name of a rename, the new name arrives as a Create
+		delete(ingressWatcher.pending, event.Name)
+		return
+	}
+	if !event.Has(fsnotify.Create) && !event.Has(fsnotify.Write) {
+		return
+	}
+	info, err := os.Stat(event.Name)
+	if err != nil {
+		return
+	}
+	if !info.IsDir() {
+		ingressWatcher.markChanged(event.Name)
+		return
+	}
+	// a new folder: watch it, and anything already inside it (a folder moved in arrives complete without events)
+	filepath.Walk(event.Name, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return nil
+		}
+		if info.IsDir() {
+			if err := ingressWatcher.watcher.Add(path); err != nil {
+				Logger.Error("Unable to watch ingress sub folder", "path", path, "error", err)
+			}
+			return nil
+		}
+		ingressWatcher.markChanged(path)
+		return nil
+	})
+}
+
+// markChanged (re)starts the quiet period for a file, files already being ingested are ignored
+func (ingressWatcher *ingressWatcher) markChanged(filePath string) {
+	if ingressFileInFlight(filePath) {
+		return
+	}
+	if pending, ok := ingressWatcher.pending[filePath]; ok {
+		pending.lastChange = time.Now()
+		return
+	}
+	ingressWatcher.pending[filePath] = &pendingFile{lastChange: time.Now(), size: -1}
+}
+
+// ingestSettled queues every pending file whose size and modification time have not changed for the quiet period.
+// Files that don't fit in the queue stay pending and are tried again on the next tick.
+func (ingressWatcher *ingressWatcher) ingestSettled(now time.Time) {
+	for filePath, pending := range ingressWatcher.pending {
+		info, err := os.Stat(filePath)
+		if err != nil || info.IsDir() {
+			delete(ingressWatcher.pending, filePath)
+			continue
+		}
+		if info.Size() != pending.size || !info.ModTime().Equal(pending.modTime) { //still growing
+			pending.size = info.Size()
+			pending.modTime = info.ModTime()
+			pending.lastChange = now
+			continue
+		}
+		if now.Sub(pending.lastChange) < ingressWatcher.quietPeriod {
+			continue
+		}
+		if !claimIngressFile(filePath) {
+			delete(ingressWatcher.pending, filePath)
+			continue
+		}
+		select {
+		case ingressWatcher.queue <- filePath:
+			delete(ingressWatcher.pending, filePath)
+		default: //every worker is busy
+			releaseIngressFile(filePath)
+		}
+	}
+}
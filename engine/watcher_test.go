@@ -0,0 +1,142 @@
+package engine
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestIngressWatcher(t *testing.T) {
+	Logger = slog.New(slog.NewTextHandler(os.Stdout, nil))
+	root := t.TempDir()
+	quietPeriod := 300 * time.Millisecond
+
+	ingested := make(chan string, 10)
+	ingressWatcher, err := newIngressWatcher(root, quietPeriod, 1, func(filePath string) { ingested <- filePath })
+	if err != nil {
+		t.Fatalf("Failed to start watcher: %v", err)
+	}
+	go ingressWatcher.run()
+	defer ingressWatcher.close()
+
+	t.Run("File is ingested only after it stops growing", func(t *testing.T) {
+		path := filepath.Join(root, "scan.pdf")
+		file, err := os.Create(path)
+		if err != nil {
+			t.Fatalf("Failed to create file: %v", err)
+		}
+		for i := 0; i < 5; i++ { //a slow scanner writing the file in chunks
+			file.Write([]byte("chunk of scanner output\n"))
+			select {
+			case got := <-ingested:
+				t.Fatalf("File %s ingested while still being written", got)
+			case <-time.After(quietPeriod / 2):
+			}
+		}
+		file.Close()
+		select {
+		case got := <-ingested:
+			if got != path {
+				t.Errorf("Expected %s to be ingested, got %s", path, got)
+			}
+		case <-time.After(5 * quietPeriod):
+			t.Fatal("File was not ingested after it settled")
+		}
+		select {
+		case got := <-ingested:
+			t.Errorf("File %s ingested twice", got)
+		case <-time.After(2 * quietPeriod):
+		}
+	})
+
+	t.Run("Files in new sub folders are picked up", func(t *testing.T) {
+		staging := filepath.Join(t.TempDir(), "batch")
+		os.MkdirAll(staging, 0755)
+		os.WriteFile(filepath.Join(staging, "page1.tiff"), []byte("II*\x00"), 0644)
+		if err := os.Rename(staging, filepath.Join(root, "batch")); err != nil { //moved in complete, no write events for the file
+			t.Skipf("Unable to move folder into watched folder: %v", err)
+		}
+		want := filepath.Join(root, "batch", "page1.tiff")
+		select {
+		case got := <-ingested:
+			if got != want {
+				t.Errorf("Expected %s to be ingested, got %s", want, got)
+			}
+		case <-time.After(5 * quietPeriod):
+			t.Fatal("File in moved folder was not ingested")
+		}
+		os.WriteFile(filepath.Join(root, "batch", "page2.tiff"), []byte("II*\x00"), 0644)
+		select {
+		case got := <-ingested:
+			if got != filepath.Join(root, "batch", "page2.tiff") {
+				t.Errorf("Unexpected file ingested %s", got)
+			}
+		case <-time.After(5 * quietPeriod):
+			t.Fatal("File written into new sub folder was not ingested")
+		}
+	})
+
+	t.Run("Files already being ingested are left alone", func(t *testing.T) {
+		path := filepath.Join(root, "upload.pdf")
+		claimIngressFile(path)
+		os.WriteFile(path, []byte("%PDF-1.4"), 0644)
+		select {
+		case got := <-ingested:
+			t.Errorf("File %s claimed elsewhere was ingested by the watcher", got)
+		case <-time.After(3 * quietPeriod):
+		}
+		releaseIngressFile(path)
+	})
+}
+
+// TestIngressWatcherWorkers checks a batch of settled files waits for the fixed pool of workers rather than each
+// file getting its own goroutine
+func TestIngressWatcherWorkers(t *testing.T) {
+	Logger = slog.New(slog.NewTextHandler(os.Stdout, nil))
+	root := t.TempDir()
+	quietPeriod := 100 * time.Millisecond
+
+	var running, maxRunning int32
+	ingested := make(chan string, 20)
+	ingressWatcher, err := newIngressWatcher(root, quietPeriod, 2, func(filePath string) {
+		current := atomic.AddInt32(&running, 1)
+		for {
+			previous := atomic.LoadInt32(&maxRunning)
+			if current <= previous || atomic.CompareAndSwapInt32(&maxRunning, previous, current) {
+				break
+			}
+		}
+		time.Sleep(quietPeriod)
+		atomic.AddInt32(&running, -1)
+		ingested <- filePath
+	})
+	if err != nil {
+		t.Fatalf("Failed to start watcher: %v", err)
+	}
+	go ingressWatcher.run()
+	defer ingressWatcher.close()
+
+	files := 8
+	for i := 0; i < files; i++ {
+		os.WriteFile(filepath.Join(root, fmt.Sprintf("scan%d.pdf", i)), []byte("%PDF-1.4"), 0644)
+	}
+	seen := make(map[string]bool)
+	for len(seen) < files {
+		select {
+		case got := <-ingested:
+			if seen[got] {
+				t.Errorf("File %s ingested twice", got)
+			}
+			seen[got] = true
+		case <-time.After(20 * quietPeriod):
+			t.Fatalf("Only %d of %d files ingested", len(seen), files)
+		}
+	}
+	if maxRunning > 2 {
+		t.Errorf("Expected at most 2 files in flight, saw %d", maxRunning)
+	}
+}
@@ -152,11 +152,21 @@ func main() {
 	e.GET("/api/document/:id", serverHandler.GetDocument)
 	e.DELETE("/api/document/*", serverHandler.DeleteFile)
 	e.PATCH("/api/document/move/*", serverHandler.MoveDocuments)
+	e.PATCH("/api/document/:id/rename", serverHandler.RenameDocument)
+	e.GET("/api/document/:id/thumbnail", serverHandler.DocumentThumbnail)
+	e.GET("/api/document/:id/page/:n", serverHandler.DocumentPage)
 	e.POST("/api/document/upload", serverHandler.UploadDocuments)
+	e.OPTIONS("/api/upload", serverHandler.TusOptions)
+	e.POST("/api/upload", serverHandler.TusCreate)
+	e.HEAD("/api/upload/:id", serverHandler.TusHead)
+	e.PATCH("/api/upload/:id", serverHandler.TusPatch)
+	e.DELETE("/api/upload/:id", serverHandler.TusDelete)
+	e.GET("/api/upload/:id", serverHandler.GetUpload)
 
 	// Folder API routes
 	e.GET("/api/folder/:folder", serverHandler.GetFolder)
 	e.POST("/api/folder/*", serverHandler.CreateFolder)
+	e.PATCH("/api/folder/*", serverHandler.RenameFolder)
 
 	// Search API routes
 	e.GET("/api/search", serverHandler.SearchDocuments)
@@ -164,6 +174,19 @@ func main() {
 
 	// Admin API routes
 	e.POST("/api/ingest", serverHandler.RunIngestNow)
+	e.GET("/api/ingest/jobs", serverHandler.ListIngestJobs)
+	e.GET("/api/ingest/jobs/:id", serverHandler.GetIngestJob)
+	e.POST("/api/ingest/jobs/:id/retry", serverHandler.RetryIngestJob)
+	e.GET("/api/quarantine", serverHandler.ListQuarantine)
+	e.POST("/api/quarantine/:id/retry", serverHandler.RetryQuarantined)
+	e.DELETE("/api/quarantine/:id", serverHandler.DiscardQuarantined)
+	e.GET("/api/duplicates", serverHandler.ListDuplicates)
+	e.GET("/api/integrity", serverHandler.GetIntegrityReport)
+	e.POST("/api/integrity", serverHandler.RunIntegrityCheck)
+	e.GET("/api/trash", serverHandler.ListTrash)
+	e.POST("/api/trash/:id/restore", serverHandler.RestoreTrashed)
+	e.DELETE("/api/trash/:id", serverHandler.PurgeTrashed)
+	e.DELETE("/api/trash", serverHandler.EmptyTrash)
 	e.POST("/api/clean", serverHandler.CleanDatabase)
 	e.GET("/api/about", serverHandler.GetAboutInfo)
 
@@ -172,7 +195,7 @@ func main() {
 	e.POST("/api/wordcloud/recalculate", serverHandler.RecalculateWordCloud)
 
 	// Document view routes (serve actual files - not JSON, so not under /api/*)
-	serverHandler.AddDocumentViewRoutes() //Add all existing documents to direct view links
+	e.GET("/document/view/:id", serverHandler.ViewDocument)
 
 	// Serve go-app handler for all other routes (must be last)
 	// The WASM app handles its own client-side routing and 404s via NotFoundPage component
@@ -114,7 +114,7 @@ func runFrontendRenderingTest(t *testing.T) {
 	e.DELETE("/api/document/*", serverHandler.DeleteFile)
 	e.PATCH("/api/document/move/*", serverHandler.MoveDocuments)
 	e.POST("/api/document/upload", serverHandler.UploadDocuments)
-	serverHandler.AddDocumentViewRoutes()
+	e.GET("/document/view/:id", serverHandler.ViewDocument)
 	e.GET("/api/folder/:folder", serverHandler.GetFolder)
 	e.POST("/api/folder/*", serverHandler.CreateFolder)
 	e.GET("/api/search", serverHandler.SearchDocuments)
@@ -772,7 +772,7 @@ func TestAboutPageWithChromedp(t *testing.T) {
 	e.GET("/api/documents/filesystem", serverHandler.GetDocumentFileSystem)
 	e.GET("/api/about", serverHandler.GetAboutInfo)
 	e.GET("/api/document/:id", serverHandler.GetDocument)
-	serverHandler.AddDocumentViewRoutes()
+	e.GET("/document/view/:id", serverHandler.ViewDocument)
 	e.GET("/api/search", serverHandler.SearchDocuments)
 
 	// Serve go-app handler for all other routes (must be last)
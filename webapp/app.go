@@ -37,6 +37,8 @@ func (a *App) renderPage() app.UI {
 		return &BrowsePage{}
 	case "/ingest":
 		return &IngestPage{}
+	case "/quarantine":
+		return &QuarantinePage{}
 	case "/clean":
 		return &CleanPage{}
 	case "/search":
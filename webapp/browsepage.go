@@ -20,11 +20,16 @@ type FileTreeNode struct {
 	ChildrenIDs []string `json:"childrenIDs"`
 	FullPath    string   `json:"fullPath"`
 	FileURL     string   `json:"fileURL"`
+	Snippet     string   `json:"snippet"` //search results only, matched words wrapped in <mark></mark>
+	Rank        float64  `json:"rank"`
+	Page        int      `json:"page"`       //best matching page, 0 when not known
+	Similarity  float64  `json:"similarity"` //fuzzy search only, 0 to 1
 }
 
 // FileSystem represents the API response
 type FileSystem struct {
 	FileSystem []FileTreeNode `json:"fileSystem"`
+	Facets     SearchFacets   `json:"facets"` //search results only
 	Error      string         `json:"error"`
 }
 
@@ -113,12 +118,12 @@ func (b *BrowsePage) renderNode(node FileTreeNode, depth int) app.UI {
 	isExpanded := b.expandedDirs[node.ID]
 	children := b.getChildren(node.ID)
 
-	iconText := "📄"
+	var iconUI app.UI = &Thumbnail{ULID: node.ULID, Name: node.Name, Small: true}
 	if node.IsDir {
 		if isExpanded {
-			iconText = "📂"
+			iconUI = app.Text("📂")
 		} else {
-			iconText = "📁"
+			iconUI = app.Text("📁")
 		}
 	}
 
@@ -150,7 +155,7 @@ func (b *BrowsePage) renderNode(node FileTreeNode, depth int) app.UI {
 			app.Div().Class("tree-node-content").Body(
 				app.Span().
 					Class("tree-node-icon").
-					Text(iconText).
+					Body(iconUI).
 					OnClick(func(ctx app.Context, e app.Event) {
 						if node.IsDir {
 							b.toggleDir(ctx, node.ID)
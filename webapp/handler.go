@@ -12,6 +12,7 @@ func Handler() http.Handler {
 	app.Route("/", func() app.Composer { return &App{} })
 	app.Route("/browse", func() app.Composer { return &App{} })
 	app.Route("/ingest", func() app.Composer { return &App{} })
+	app.Route("/quarantine", func() app.Composer { return &App{} })
 	app.Route("/clean", func() app.Composer { return &App{} })
 	app.Route("/search", func() app.Composer { return &App{} })
 	app.Route("/wordcloud", func() app.Composer { return &App{} })
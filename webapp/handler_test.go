@@ -27,6 +27,10 @@ func TestHandlerRoutes(t *testing.T) {
 			name: "Ingest page",
 			path: "/ingest",
 		},
+		{
+			name: "Quarantine page",
+			path: "/quarantine",
+		},
 		{
 			name: "Clean page",
 			path: "/clean",
@@ -9,16 +9,17 @@ import (
 
 // Document represents a document from the API
 type Document struct {
-	StormID      int    `json:"StormID"`
-	Name         string `json:"Name"`
-	Path         string `json:"Path"`
-	IngressTime  string `json:"IngressTime"`
-	Folder       string `json:"Folder"`
-	Hash         string `json:"Hash"`
-	ULID         string `json:"ULID"`
-	DocumentType string `json:"DocumentType"`
-	FullText     string `json:"FullText"`
-	URL          string `json:"URL"`
+	StormID       int    `json:"StormID"`
+	Name          string `json:"Name"`
+	Path          string `json:"Path"`
+	IngressTime   string `json:"IngressTime"`
+	Folder        string `json:"Folder"`
+	Hash          string `json:"Hash"`
+	HashAlgorithm string `json:"HashAlgorithm"`
+	ULID          string `json:"ULID"`
+	DocumentType  string `json:"DocumentType"`
+	FullText      string `json:"FullText"`
+	URL           string `json:"URL"`
 }
 
 // PaginatedResponse represents the paginated API response
@@ -197,7 +198,7 @@ func (d *DocumentCard) Render() app.UI {
 		Class("document-card").
 		Body(
 			app.Div().Class("document-icon").Body(
-				app.Text("📄"),
+				&Thumbnail{ULID: d.Document.ULID, Name: d.Document.Name},
 			),
 			app.Div().Class("document-info").Body(
 				app.H3().Text(d.Document.Name),
@@ -212,3 +213,36 @@ func (d *DocumentCard) Render() app.UI {
 			),
 		)
 }
+
+// Thumbnail shows the thumbnail of a document, falling back to an icon for documents that have none
+type Thumbnail struct {
+	app.Compo
+	ULID   string
+	Name   string
+	Small  bool //sized for a line of the file tree rather than a card
+	failed bool
+}
+
+// thumbnailURL is where the server serves the thumbnail of a document
+func thumbnailURL(ulid string) string {
+	return "/api/document/" + ulid + "/thumbnail"
+}
+
+// Render renders the thumbnail, or the icon once the image has failed to load
+func (t *Thumbnail) Render() app.UI {
+	if t.ULID == "" || t.failed {
+		return app.Span().Class("document-thumbnail-icon").Text("📄")
+	}
+	class := "document-thumbnail"
+	if t.Small {
+		class += " document-thumbnail-small"
+	}
+	return app.Img().
+		Class(class).
+		Src(thumbnailURL(t.ULID)).
+		Alt(t.Name).
+		Loading("lazy").
+		OnError(func(ctx app.Context, e app.Event) {
+			t.failed = true
+		})
+}
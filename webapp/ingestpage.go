@@ -1,22 +1,69 @@
 package webapp
 
 import (
+	"encoding/json"
+	"fmt"
+	"path"
+	"time"
+
 	"github.com/maxence-charriere/go-app/v10/pkg/app"
 )
 
-// IngestPage allows users to trigger the ingestion process manually
+// ingestPollInterval is how often the ingest page refreshes the job queue
+const ingestPollInterval = 2 * time.Second
+
+// ingestJobStates lists the job states in the order a file moves through them
+var ingestJobStates = []string{"queued", "extracting", "ocr", "stored", "failed", "quarantined", "duplicate"}
+
+// IngestJob represents an ingest job from the API
+type IngestJob struct {
+	ID           int        `json:"id"`
+	FilePath     string     `json:"filePath"`
+	Source       string     `json:"source"`
+	State        string     `json:"state"`
+	Error        string     `json:"error"`
+	Attempts     int        `json:"attempts"`
+	DocumentULID string     `json:"documentULID"`
+	QueuedAt     time.Time  `json:"queuedAt"`
+	StartedAt    *time.Time `json:"startedAt"`
+	FinishedAt   *time.Time `json:"finishedAt"`
+}
+
+// IngestJobsResponse represents the ingest job list API response
+type IngestJobsResponse struct {
+	Jobs   []IngestJob    `json:"jobs"`
+	Counts map[string]int `json:"counts"`
+	Count  int            `json:"count"`
+}
+
+// IngestPage allows users to trigger the ingestion process manually and follow the job queue
 type IngestPage struct {
 	app.Compo
 	running bool
 	result  string
 	error   string
+	jobs    []IngestJob
+	counts  map[string]int
+	loaded  bool
+	mounted bool
+}
+
+// OnMount is called when the component is mounted
+func (i *IngestPage) OnMount(ctx app.Context) {
+	i.mounted = true
+	i.pollJobs(ctx)
+}
+
+// OnDismount stops polling when the user leaves the page
+func (i *IngestPage) OnDismount() {
+	i.mounted = false
 }
 
 // Render renders the ingest page
 func (i *IngestPage) Render() app.UI {
 	buttonText := "Run Ingestion Now"
 	if i.running {
-		buttonText = "Running..."
+		buttonText = "Starting..."
 	}
 
 	return app.Div().
@@ -34,20 +81,15 @@ func (i *IngestPage) Render() app.UI {
 			),
 
 			i.renderStatus(),
+			i.renderQueue(),
 		)
 }
 
 // renderStatus renders the status section
 func (i *IngestPage) renderStatus() app.UI {
-	if i.running {
-		return app.Div().Class("loading").Body(
-			app.Text("Ingestion in progress..."),
-		)
-	}
-
 	if i.error != "" {
 		return app.Div().Class("error").Body(
-			app.Text("Error: "+i.error),
+			app.Text("Error: " + i.error),
 		)
 	}
 
@@ -60,21 +102,135 @@ func (i *IngestPage) renderStatus() app.UI {
 	return app.Div()
 }
 
+// renderQueue renders the job counts and the most recent jobs
+func (i *IngestPage) renderQueue() app.UI {
+	if !i.loaded {
+		return app.Div().Class("loading").Body(app.Text("Loading ingest queue..."))
+	}
+
+	return app.Div().Class("ingest-queue").Body(
+		app.H3().Text("Ingest Queue"),
+		app.Div().Class("ingest-counts").Body(
+			app.Range(ingestJobStates).Slice(func(n int) app.UI {
+				state := ingestJobStates[n]
+				return app.Span().
+					Class("job-state job-state-" + state).
+					Text(fmt.Sprintf("%s: %d", state, i.counts[state]))
+			}),
+		),
+		app.If(len(i.jobs) == 0, func() app.UI {
+			return app.Div().Class("no-results").Body(app.Text("No files have been ingested yet."))
+		}).Else(func() app.UI {
+			return app.Table().Class("ingest-jobs").Body(
+				app.THead().Body(
+					app.Tr().Body(
+						app.Th().Text("File"),
+						app.Th().Text("State"),
+						app.Th().Text("Attempts"),
+						app.Th().Text("Queued"),
+						app.Th().Text("Time Taken"),
+						app.Th().Text("Error"),
+						app.Th(),
+					),
+				),
+				app.TBody().Body(
+					app.Range(i.jobs).Slice(func(n int) app.UI {
+						return i.renderJob(i.jobs[n])
+					}),
+				),
+			)
+		}),
+	)
+}
+
+// renderJob renders one row of the job table
+func (i *IngestPage) renderJob(job IngestJob) app.UI {
+	return app.Tr().Body(
+		app.Td().Title(job.FilePath).Text(path.Base(job.FilePath)),
+		app.Td().Body(
+			app.Span().Class("job-state job-state-"+job.State).Text(job.State),
+		),
+		app.Td().Text(fmt.Sprintf("%d", job.Attempts)),
+		app.Td().Text(job.QueuedAt.Local().Format("2006-01-02 15:04:05")),
+		app.Td().Text(jobDuration(job)),
+		app.Td().Class("job-error").Text(job.Error),
+		app.Td().Body(
+			app.If(job.State == "failed", func() app.UI {
+				return app.Button().
+					Class("btn-primary btn-small").
+					OnClick(func(ctx app.Context, e app.Event) { i.retryJob(ctx, job.ID) }).
+					Text("Retry")
+			}).ElseIf(job.State == "quarantined", func() app.UI {
+				return app.A().Href("/quarantine").Text("Quarantine")
+			}).ElseIf(job.DocumentULID != "", func() app.UI {
+				return app.A().Href("/document/view/" + job.DocumentULID).Target("_blank").Text("View")
+			}),
+		),
+	)
+}
+
+// jobDuration formats how long the job has been (or was) processing
+func jobDuration(job IngestJob) string {
+	if job.StartedAt == nil {
+		return ""
+	}
+	end := time.Now()
+	if job.FinishedAt != nil {
+		end = *job.FinishedAt
+	}
+	return end.Sub(*job.StartedAt).Round(100 * time.Millisecond).String()
+}
+
 // onIngestClick handles the ingest button click
 func (i *IngestPage) onIngestClick(ctx app.Context, e app.Event) {
 	i.running = true
 	i.result = ""
 	i.error = ""
-	
 
 	i.runIngest(ctx)
 }
 
-// runIngest calls the API to trigger ingestion
+// runIngest calls the API to trigger ingestion, progress then shows up in the queue
 func (i *IngestPage) runIngest(ctx app.Context) {
+	i.post(ctx, "/api/ingest", func(status int, text string) {
+		i.running = false
+		if status >= 200 && status < 300 {
+			i.result = "Ingestion started, files will appear in the queue below as they are processed."
+		} else {
+			i.error = "Ingestion failed: " + text
+		}
+		i.fetchJobs(ctx)
+	})
+}
+
+// retryJob asks the server to run a failed job again
+func (i *IngestPage) retryJob(ctx app.Context, id int) {
+	i.result = ""
+	i.error = ""
+	i.post(ctx, fmt.Sprintf("/api/ingest/jobs/%d/retry", id), func(status int, text string) {
+		if status >= 200 && status < 300 {
+			i.result = fmt.Sprintf("Job %d queued for retry.", id)
+		} else {
+			i.error = "Retry failed: " + text
+		}
+		i.fetchJobs(ctx)
+	})
+}
+
+// post sends a POST request and calls done (on the UI goroutine) with the status and body text
+func (i *IngestPage) post(ctx app.Context, url string, done func(status int, text string)) {
+	sendRequest(ctx, "POST", url, done, func() {
+		i.running = false
+		i.error = "Network error: Could not connect to server"
+	})
+}
+
+// sendRequest sends a request without a body and calls done (on the UI goroutine) with the status and body text,
+// or failed if the server could not be reached
+func sendRequest(ctx app.Context, method string, url string, done func(status int, text string), failed func()) {
 	ctx.Async(func() {
-		res := app.Window().Call("fetch", "/api/ingest", map[string]interface{}{
-			"method": "POST",
+		res := app.Window().Call("fetch", url, map[string]interface{}{
+			"method": method,
 		})
 
 		res.Call("then", app.FuncOf(func(this app.Value, args []app.Value) interface{} {
@@ -93,12 +249,59 @@ func (i *IngestPage) runIngest(ctx app.Context) {
 				text := args[0].String()
 
 				ctx.Dispatch(func(ctx app.Context) {
-					i.running = false
-					if status >= 200 && status < 300 {
-						i.result = "Ingestion completed successfully! " + text
-					} else {
-						i.error = "Ingestion failed: " + text
+					done(status, text)
+				})
+
+				return nil
+			}))
+
+			return nil
+		})).Call("catch", app.FuncOf(func(this app.Value, args []app.Value) interface{} {
+			ctx.Dispatch(func(ctx app.Context) {
+				failed()
+			})
+			return nil
+		}))
+	})
+}
+
+// pollJobs refreshes the job queue and schedules the next refresh while the page is open
+func (i *IngestPage) pollJobs(ctx app.Context) {
+	if !i.mounted {
+		return
+	}
+	i.fetchJobs(ctx)
+	ctx.After(ingestPollInterval, i.pollJobs)
+}
+
+// fetchJobs loads the most recent ingest jobs
+func (i *IngestPage) fetchJobs(ctx app.Context) {
+	ctx.Async(func() {
+		res := app.Window().Call("fetch", "/api/ingest/jobs?limit=50")
+
+		res.Call("then", app.FuncOf(func(this app.Value, args []app.Value) interface{} {
+			if len(args) == 0 {
+				return nil
+			}
+			response := args[0]
+
+			response.Call("json").Call("then", app.FuncOf(func(this app.Value, args []app.Value) interface{} {
+				if len(args) == 0 {
+					return nil
+				}
+
+				jsonData := args[0]
+				jsonStr := app.Window().Get("JSON").Call("stringify", jsonData).String()
+
+				var resp IngestJobsResponse
+				ctx.Dispatch(func(ctx app.Context) {
+					if err := json.Unmarshal([]byte(jsonStr), &resp); err != nil {
+						i.error = fmt.Sprintf("Failed to parse ingest queue: %v", err)
+						return
 					}
+					i.jobs = resp.Jobs
+					i.counts = resp.Counts
+					i.loaded = true
 				})
 
 				return nil
@@ -107,8 +310,7 @@ func (i *IngestPage) runIngest(ctx app.Context) {
 			return nil
 		})).Call("catch", app.FuncOf(func(this app.Value, args []app.Value) interface{} {
 			ctx.Dispatch(func(ctx app.Context) {
-				i.running = false
-				i.error = "Network error: Could not connect to server"
+				i.error = "Network error: Could not load ingest queue"
 			})
 			return nil
 		}))
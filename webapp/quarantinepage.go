@@ -0,0 +1,215 @@
+package webapp
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/maxence-charriere/go-app/v10/pkg/app"
+)
+
+// IngestAttempt represents one attempt at ingesting a file
+type IngestAttempt struct {
+	Attempt    int       `json:"attempt"`
+	StartedAt  time.Time `json:"startedAt"`
+	FinishedAt time.Time `json:"finishedAt"`
+	Error      string    `json:"error"`
+}
+
+// QuarantinedFile represents the error report of a quarantined file from the API
+type QuarantinedFile struct {
+	ID            string          `json:"id"`
+	FileName      string          `json:"fileName"`
+	OriginalPath  string          `json:"originalPath"`
+	Source        string          `json:"source"`
+	Reason        string          `json:"reason"`
+	QuarantinedAt time.Time       `json:"quarantinedAt"`
+	JobID         int             `json:"jobID"`
+	Attempts      []IngestAttempt `json:"attempts"`
+	Size          int64           `json:"size"`
+}
+
+// QuarantineResponse represents the quarantine list API response
+type QuarantineResponse struct {
+	Files []QuarantinedFile `json:"files"`
+	Count int               `json:"count"`
+}
+
+// QuarantinePage lists the files that kept failing ingestion so they can be retried or discarded
+type QuarantinePage struct {
+	app.Compo
+	files  []QuarantinedFile
+	loaded bool
+	result string
+	error  string
+}
+
+// OnMount is called when the component is mounted
+func (q *QuarantinePage) OnMount(ctx app.Context) {
+	q.fetchFiles(ctx)
+}
+
+// Render renders the quarantine page
+func (q *QuarantinePage) Render() app.UI {
+	return app.Div().
+		Class("quarantine-page").
+		Body(
+			app.H2().Text("Quarantine"),
+			app.P().Text("Files that failed ingestion too many times are moved here so they are not retried on every scan. Retry moves a file back to the ingress folder, if it fails again it returns straight here."),
+			q.renderStatus(),
+			q.renderFiles(),
+		)
+}
+
+// renderStatus renders the result of the last action
+func (q *QuarantinePage) renderStatus() app.UI {
+	if q.error != "" {
+		return app.Div().Class("error").Body(
+			app.Text("Error: " + q.error),
+		)
+	}
+
+	if q.result != "" {
+		return app.Div().Class("success").Body(
+			app.Text(q.result),
+		)
+	}
+
+	return app.Div()
+}
+
+// renderFiles renders the table of quarantined files
+func (q *QuarantinePage) renderFiles() app.UI {
+	if !q.loaded {
+		return app.Div().Class("loading").Body(app.Text("Loading quarantine..."))
+	}
+	if len(q.files) == 0 {
+		return app.Div().Class("no-results").Body(app.Text("No files are quarantined."))
+	}
+
+	return app.Table().Class("ingest-jobs quarantine-files").Body(
+		app.THead().Body(
+			app.Tr().Body(
+				app.Th().Text("File"),
+				app.Th().Text("Reason"),
+				app.Th().Text("Attempts"),
+				app.Th().Text("Quarantined"),
+				app.Th().Text("Size"),
+				app.Th(),
+			),
+		),
+		app.TBody().Body(
+			app.Range(q.files).Slice(func(n int) app.UI {
+				return q.renderFile(q.files[n])
+			}),
+		),
+	)
+}
+
+// renderFile renders one quarantined file with the history of its attempts
+func (q *QuarantinePage) renderFile(file QuarantinedFile) app.UI {
+	return app.Tr().Body(
+		app.Td().Title(file.OriginalPath).Text(file.FileName),
+		app.Td().Class("job-error").Text(file.Reason),
+		app.Td().Body(
+			app.Details().Body(
+				app.Summary().Text(fmt.Sprintf("%d", len(file.Attempts))),
+				app.Ol().Class("quarantine-attempts").Body(
+					app.Range(file.Attempts).Slice(func(n int) app.UI {
+						attempt := file.Attempts[n]
+						return app.Li().Text(fmt.Sprintf("%s: %s", attempt.FinishedAt.Local().Format("2006-01-02 15:04:05"), attempt.Error))
+					}),
+				),
+			),
+		),
+		app.Td().Text(file.QuarantinedAt.Local().Format("2006-01-02 15:04:05")),
+		app.Td().Text(formatBytes(file.Size)),
+		app.Td().Class("quarantine-actions").Body(
+			app.Button().
+				Class("btn-primary btn-small").
+				OnClick(func(ctx app.Context, e app.Event) { q.retryFile(ctx, file) }).
+				Text("Retry"),
+			app.Button().
+				Class("btn-danger btn-small").
+				OnClick(func(ctx app.Context, e app.Event) { q.discardFile(ctx, file) }).
+				Text("Discard"),
+		),
+	)
+}
+
+// retryFile moves a quarantined file back to ingress
+func (q *QuarantinePage) retryFile(ctx app.Context, file QuarantinedFile) {
+	q.result = ""
+	q.error = ""
+	sendRequest(ctx, "POST", "/api/quarantine/"+file.ID+"/retry", func(status int, text string) {
+		if status >= 200 && status < 300 {
+			q.result = file.FileName + " moved back to ingress, follow it on the Ingest page."
+		} else {
+			q.error = "Retry failed: " + text
+		}
+		q.fetchFiles(ctx)
+	}, q.networkError)
+}
+
+// discardFile deletes a quarantined file once the user confirms
+func (q *QuarantinePage) discardFile(ctx app.Context, file QuarantinedFile) {
+	if !app.Window().Call("confirm", "Permanently delete "+file.FileName+"?").Bool() {
+		return
+	}
+	q.result = ""
+	q.error = ""
+	sendRequest(ctx, "DELETE", "/api/quarantine/"+file.ID, func(status int, text string) {
+		if status >= 200 && status < 300 {
+			q.result = file.FileName + " discarded."
+		} else {
+			q.error = "Discard failed: " + text
+		}
+		q.fetchFiles(ctx)
+	}, q.networkError)
+}
+
+func (q *QuarantinePage) networkError() {
+	q.error = "Network error: Could not connect to server"
+}
+
+// fetchFiles loads the quarantined files
+func (q *QuarantinePage) fetchFiles(ctx app.Context) {
+	ctx.Async(func() {
+		res := app.Window().Call("fetch", "/api/quarantine")
+
+		res.Call("then", app.FuncOf(func(this app.Value, args []app.Value) interface{} {
+			if len(args) == 0 {
+				return nil
+			}
+			response := args[0]
+
+			response.Call("json").Call("then", app.FuncOf(func(this app.Value, args []app.Value) interface{} {
+				if len(args) == 0 {
+					return nil
+				}
+
+				jsonData := args[0]
+				jsonStr := app.Window().Get("JSON").Call("stringify", jsonData).String()
+
+				var resp QuarantineResponse
+				ctx.Dispatch(func(ctx app.Context) {
+					if err := json.Unmarshal([]byte(jsonStr), &resp); err != nil {
+						q.error = fmt.Sprintf("Failed to parse quarantine: %v", err)
+						return
+					}
+					q.files = resp.Files
+					q.loaded = true
+				})
+
+				return nil
+			}))
+
+			return nil
+		})).Call("catch", app.FuncOf(func(this app.Value, args []app.Value) interface{} {
+			ctx.Dispatch(func(ctx app.Context) {
+				q.error = "Network error: Could not load quarantine"
+			})
+			return nil
+		}))
+	})
+}
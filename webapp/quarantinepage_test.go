@@ -0,0 +1,42 @@
+package webapp
+
+import (
+	"testing"
+	"time"
+)
+
+// TestQuarantinePageRenderStates tests that the quarantine page renders in each state
+func TestQuarantinePageRenderStates(t *testing.T) {
+	quarantined := time.Now()
+
+	tests := []struct {
+		name string
+		page *QuarantinePage
+	}{
+		{"Loading", &QuarantinePage{}},
+		{"Empty", &QuarantinePage{loaded: true}},
+		{"Error", &QuarantinePage{error: "Network error", loaded: true}},
+		{"Quarantined files", &QuarantinePage{
+			loaded: true,
+			result: "broken.pdf moved back to ingress",
+			files: []QuarantinedFile{
+				{
+					ID: "01ARZ3NDEKTSV4RRFFQ69G5FAV", FileName: "broken.pdf", OriginalPath: "/ingress/scans/broken.pdf",
+					Reason: "malformed PDF", QuarantinedAt: quarantined, Size: 2048,
+					Attempts: []IngestAttempt{
+						{Attempt: 1, FinishedAt: quarantined.Add(-time.Hour), Error: "malformed PDF"},
+						{Attempt: 2, FinishedAt: quarantined, Error: "malformed PDF"},
+					},
+				},
+				{ID: "01ARZ3NDEKTSV4RRFFQ69G5FAW", FileName: "noattempts.xyz", Reason: "unsupported file type"},
+			},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if ui := tt.page.Render(); ui == nil {
+				t.Error("Render should return non-nil UI")
+			}
+		})
+	}
+}
@@ -4,6 +4,9 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/url"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/maxence-charriere/go-app/v10/pkg/app"
 )
@@ -12,24 +15,128 @@ import (
 type SearchPage struct {
 	app.Compo
 	searchTerm   string
+	from         string //ingress dates as YYYY-MM-DD
+	to           string
+	documentType string //comma separated extensions, one of searchTypes
+	folder       string
+	minSizeMB    string
+	maxSizeMB    string
+	sort         string //one of searchSorts, empty for relevance
+	fuzzy        bool   //always match similar words, otherwise only when nothing matches exactly
 	searchResult FileSystem
 	loading      bool
 	error        string
 	searched     bool
 }
 
+// searchTypes are the choices of the document type filter, the value is sent as document_type
+var searchTypes = []struct{ label, value string }{
+	{"Any type", ""},
+	{"PDF", "pdf"},
+	{"Image", "png,jpg,jpeg,tif,tiff"},
+	{"Text", "txt,rtf"},
+	{"Word processor", "docx,odt"},
+	{"Spreadsheet", "xlsx,ods"},
+	{"Presentation", "pptx,odp"},
+}
+
+// FacetCount is how many search results have a facet value
+type FacetCount struct {
+	Value string `json:"value"`
+	Count int    `json:"count"`
+}
+
+// SearchFacets are the facet counts /api/search returns with its results
+type SearchFacets struct {
+	DocumentType []FacetCount `json:"documentType"`
+	Folder       []FacetCount `json:"folder"`
+	Year         []FacetCount `json:"year"`
+	Month        []FacetCount `json:"month"` //YYYY-MM
+}
+
+// searchSorts are the choices of result order, relevance falls back to newest when there is no search term
+var searchSorts = []struct{ label, value string }{
+	{"Relevance", ""},
+	{"Newest", "newest"},
+	{"Name", "name"},
+}
+
 // OnMount is called when the component is mounted
 func (s *SearchPage) OnMount(ctx app.Context) {
-	// Check if there's a search term in the URL
+	// Check if there's a search in the URL
 	urlPath := ctx.Page().URL()
 	if urlObj, err := url.Parse(urlPath.String()); err == nil {
-		if term := urlObj.Query().Get("term"); term != "" {
-			s.searchTerm = term
+		s.setSearchParams(urlObj.Query())
+		if s.hasSearch() {
 			s.performSearch(ctx)
 		}
 	}
 }
 
+// searchParams are the search and its filters as sent to /api/search, and kept in the page URL so a search can be
+// bookmarked.  Sizes are entered in megabytes and sent in bytes.
+func (s *SearchPage) searchParams() url.Values {
+	params := url.Values{}
+	set := func(key string, value string) {
+		if value = strings.TrimSpace(value); value != "" {
+			params.Set(key, value)
+		}
+	}
+	set("term", s.searchTerm)
+	set("from", s.from)
+	set("to", s.to)
+	set("document_type", s.documentType)
+	set("folder", s.folder)
+	set("min_size", megabytesToBytes(s.minSizeMB))
+	set("max_size", megabytesToBytes(s.maxSizeMB))
+	set("sort", s.sort)
+	if s.fuzzy {
+		params.Set("fuzzy", "true")
+	}
+	return params
+}
+
+// setSearchParams fills in the search form from searchParams
+func (s *SearchPage) setSearchParams(params url.Values) {
+	s.searchTerm = params.Get("term")
+	s.from = params.Get("from")
+	s.to = params.Get("to")
+	s.documentType = params.Get("document_type")
+	s.folder = params.Get("folder")
+	s.minSizeMB = bytesToMegabytes(params.Get("min_size"))
+	s.maxSizeMB = bytesToMegabytes(params.Get("max_size"))
+	s.sort = params.Get("sort")
+	s.fuzzy = params.Get("fuzzy") == "true"
+}
+
+// hasSearch reports whether there is a term or a filter to search with, the order or fuzzy on their own are not
+// enough
+func (s *SearchPage) hasSearch() bool {
+	params := s.searchParams()
+	params.Del("sort")
+	params.Del("fuzzy")
+	return len(params) > 0
+}
+
+// megabytesToBytes converts a size entered in megabytes, anything that is not a number is passed on unchanged for
+// the server to reject
+func megabytesToBytes(megabytes string) string {
+	value, err := strconv.ParseFloat(strings.TrimSpace(megabytes), 64)
+	if err != nil {
+		return megabytes
+	}
+	return strconv.FormatInt(int64(value*1024*1024), 10)
+}
+
+// bytesToMegabytes is the reverse of megabytesToBytes
+func bytesToMegabytes(bytes string) string {
+	value, err := strconv.ParseInt(bytes, 10, 64)
+	if err != nil {
+		return bytes
+	}
+	return strconv.FormatFloat(float64(value)/(1024*1024), 'f', -1, 64)
+}
+
 // Render renders the search page
 func (s *SearchPage) Render() app.UI {
 	var content app.UI
@@ -39,18 +146,28 @@ func (s *SearchPage) Render() app.UI {
 	} else if s.error != "" {
 		content = app.Div().Class("error").Body(app.Text("Error: " + s.error))
 	} else if s.searched && len(s.searchResult.FileSystem) == 0 {
-		content = app.Div().Class("no-results").Body(app.Text("No results found for: " + s.searchTerm))
+		message := "No results found"
+		if s.searchTerm != "" {
+			message += " for: " + s.searchTerm
+		}
+		content = app.Div().Class("no-results").Body(app.Text(message))
 	} else if s.searched && len(s.searchResult.FileSystem) > 0 {
 		content = app.Div().Class("search-results").Body(
 			app.H3().Text(fmt.Sprintf("Found %d results", len(s.searchResult.FileSystem)-1)),
-			app.Div().Class("result-list").Body(
-				app.Range(s.searchResult.FileSystem).Slice(func(i int) app.UI {
-					node := s.searchResult.FileSystem[i]
-					if node.ID == "SearchResults" {
-						return nil
-					}
-					return &SearchResultItem{Node: node}
-				}),
+			app.If(!s.fuzzy && s.fuzzyResults(), func() app.UI {
+				return app.P().Class("search-fuzzy-notice").Text("Nothing matched exactly, these documents have words similar to those searched for.")
+			}),
+			app.Div().Class("search-results-body").Body(
+				s.renderFacets(),
+				app.Div().Class("result-list").Body(
+					app.Range(s.searchResult.FileSystem).Slice(func(i int) app.UI {
+						node := s.searchResult.FileSystem[i]
+						if node.ID == "SearchResults" {
+							return nil
+						}
+						return &SearchResultItem{Node: node}
+					}),
+				),
 			),
 		)
 	}
@@ -63,7 +180,8 @@ func (s *SearchPage) Render() app.UI {
 				app.Input().
 					Type("text").
 					Class("search-input").
-					Placeholder("Enter search term...").
+					Placeholder(`Enter search term... e.g. invoice OR receipt -draft "exact phrase" name:bill`).
+					Title("Words match as prefixes, quote a phrase to match it exactly. Combine with AND, OR, NOT or -word and brackets; name:, folder: and type: search those fields.").
 					Value(s.searchTerm).
 					OnInput(func(ctx app.Context, e app.Event) {
 						s.searchTerm = ctx.JSSrc().Get("value").String()
@@ -80,14 +198,150 @@ func (s *SearchPage) Render() app.UI {
 						s.performSearch(ctx)
 					}),
 			),
+			s.renderFilters(),
 			content,
 		)
 }
 
+// renderFilters renders the filters that narrow the search, they also work without a search term
+func (s *SearchPage) renderFilters() app.UI {
+	input := func(label string, inputType string, value *string, placeholder string) app.UI {
+		return app.Label().Class("search-filter").Body(
+			app.Span().Text(label),
+			app.Input().
+				Type(inputType).
+				Class("search-filter-input").
+				Placeholder(placeholder).
+				Value(*value).
+				OnChange(func(ctx app.Context, e app.Event) {
+					*value = ctx.JSSrc().Get("value").String()
+				}),
+		)
+	}
+	choice := func(label string, choices []struct{ label, value string }, value *string) app.UI {
+		return app.Label().Class("search-filter").Body(
+			app.Span().Text(label),
+			app.Select().
+				Class("search-filter-input").
+				OnChange(func(ctx app.Context, e app.Event) {
+					*value = ctx.JSSrc().Get("value").String()
+				}).
+				Body(
+					app.Range(choices).Slice(func(i int) app.UI {
+						return app.Option().Value(choices[i].value).Selected(choices[i].value == *value).Text(choices[i].label)
+					}),
+				),
+		)
+	}
+	return app.Div().Class("search-filters").Body(
+		input("Ingested from", "date", &s.from, ""),
+		input("to", "date", &s.to, ""),
+		choice("Type", searchTypes, &s.documentType),
+		input("Folder", "text", &s.folder, "e.g. bills/2024"),
+		input("Min size (MB)", "number", &s.minSizeMB, ""),
+		input("Max size (MB)", "number", &s.maxSizeMB, ""),
+		choice("Sort by", searchSorts, &s.sort),
+		app.Label().Class("search-filter search-filter-check").Body(
+			app.Input().
+				Type("checkbox").
+				Checked(s.fuzzy).
+				OnChange(func(ctx app.Context, e app.Event) {
+					s.fuzzy = ctx.JSSrc().Get("checked").Bool()
+				}),
+			app.Span().Text("Fuzzy (similar words, for OCR errors)"),
+		),
+	)
+}
+
+// fuzzyResults reports whether the results were found by fuzzy matching, which the server falls back to
+func (s *SearchPage) fuzzyResults() bool {
+	for _, node := range s.searchResult.FileSystem {
+		if node.Similarity > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// renderFacets renders the facet counts of the results, choosing one narrows the search down to it
+func (s *SearchPage) renderFacets() app.UI {
+	facets := s.searchResult.Facets
+	group := func(title string, facet string, counts []FacetCount) app.UI {
+		if len(counts) == 0 {
+			return nil
+		}
+		return app.Div().Class("search-facet").Body(
+			app.H4().Text(title),
+			app.Ul().Body(
+				app.Range(counts).Slice(func(i int) app.UI {
+					count := counts[i]
+					return app.Li().Body(
+						app.A().
+							Href("#").
+							Text(fmt.Sprintf("%s (%d)", facetLabel(facet, count.Value), count.Count)).
+							OnClick(func(ctx app.Context, e app.Event) {
+								e.PreventDefault()
+								s.applyFacet(facet, count.Value)
+								s.performSearch(ctx)
+							}),
+					)
+				}),
+			),
+		)
+	}
+	return app.Aside().Class("search-facets").Body(
+		group("Type", "documentType", facets.DocumentType),
+		group("Folder", "folder", facets.Folder),
+		group("Year", "year", facets.Year),
+		group("Month", "month", facets.Month),
+	)
+}
+
+// facetLabel is how a facet value is shown
+func facetLabel(facet string, value string) string {
+	switch facet {
+	case "documentType":
+		if label := strings.ToUpper(strings.TrimPrefix(value, ".")); label != "" {
+			return label
+		}
+		return "No extension"
+	case "month":
+		if month, err := time.Parse("2006-01", value); err == nil {
+			return month.Format("January 2006")
+		}
+	}
+	return value
+}
+
+// applyFacet narrows the search down to the results counted by a facet value, replacing that filter.  A year or
+// month becomes an ingress date range.
+func (s *SearchPage) applyFacet(facet string, value string) {
+	switch facet {
+	case "documentType":
+		s.documentType = strings.TrimPrefix(value, ".")
+	case "folder":
+		s.folder = value
+	case "year", "month":
+		layout := "2006-01"
+		if facet == "year" {
+			layout = "2006"
+		}
+		start, err := time.Parse(layout, value)
+		if err != nil {
+			return
+		}
+		end := start.AddDate(0, 1, -1)
+		if facet == "year" {
+			end = start.AddDate(1, 0, -1)
+		}
+		s.from, s.to = start.Format("2006-01-02"), end.Format("2006-01-02")
+	}
+}
+
 // performSearch executes the search
 func (s *SearchPage) performSearch(ctx app.Context) {
-	if s.searchTerm == "" {
-		s.error = "Please enter a search term"
+	if !s.hasSearch() {
+		s.error = "Please enter a search term or choose a filter"
 		return
 	}
 
@@ -95,9 +349,13 @@ func (s *SearchPage) performSearch(ctx app.Context) {
 	s.error = ""
 	s.searched = false
 
+	params := s.searchParams()
+	pageURL := *ctx.Page().URL()
+	pageURL.RawQuery = params.Encode()
+	ctx.Page().ReplaceURL(&pageURL) //keep the search in the address bar so it can be bookmarked
+
 	ctx.Async(func() {
-		encodedTerm := url.QueryEscape(s.searchTerm)
-		searchURL := fmt.Sprintf("/api/search?term=%s", encodedTerm)
+		searchURL := "/api/search?facets=true&" + params.Encode()
 
 		res := app.Window().Call("fetch", searchURL)
 
@@ -128,6 +386,8 @@ func (s *SearchPage) performSearch(ctx app.Context) {
 				ctx.Dispatch(func(ctx app.Context) {
 					if err := json.Unmarshal([]byte(jsonStr), &fs); err != nil {
 						s.error = fmt.Sprintf("Failed to parse response: %v", err)
+					} else if fs.Error != "" { //an invalid filter
+						s.error = fs.Error
 					} else {
 						s.searchResult = fs
 						s.searched = true
@@ -164,6 +424,30 @@ func (s *SearchResultItem) Render() app.UI {
 		nameUI = app.Text(s.Node.Name)
 	}
 
+	var snippetUI app.UI
+	if s.Node.Snippet != "" {
+		snippetUI = app.P().Class("result-snippet").Body(highlightSnippet(s.Node.Snippet)...)
+	}
+
+	var matchUI app.UI
+	if s.Node.Page > 0 || s.Node.Rank > 0 || s.Node.Similarity > 0 {
+		var pageUI app.UI
+		if s.Node.Page > 0 && s.Node.FileURL != "" {
+			pageUI = app.A().Href(fmt.Sprintf("%s#page=%d", s.Node.FileURL, s.Node.Page)).Target("_blank").Text(fmt.Sprintf("Page %d", s.Node.Page))
+		} else if s.Node.Page > 0 {
+			pageUI = app.Text(fmt.Sprintf("Page %d", s.Node.Page))
+		}
+		matchUI = app.P().Class("result-match").Body(
+			pageUI,
+			app.If(s.Node.Rank > 0, func() app.UI {
+				return app.Span().Class("result-rank").Text(fmt.Sprintf("Relevance %.3f", s.Node.Rank))
+			}),
+			app.If(s.Node.Similarity > 0, func() app.UI {
+				return app.Span().Class("result-rank").Text(fmt.Sprintf("%.0f%% similar", s.Node.Similarity*100))
+			}),
+		)
+	}
+
 	var sizeUI app.UI
 	if s.Node.Size > 0 {
 		sizeUI = app.P().Class("result-size").Text(fmt.Sprintf("Size: %s", formatBytes(s.Node.Size)))
@@ -182,9 +466,41 @@ func (s *SearchResultItem) Render() app.UI {
 			),
 			app.Div().Class("result-info").Body(
 				app.H4().Body(nameUI),
+				snippetUI,
+				matchUI,
 				app.P().Class("result-path").Text(s.Node.FullPath),
 				sizeUI,
 				dateUI,
 			),
 		)
 }
+
+// Markers the server wraps matched words of a snippet in
+const (
+	snippetStart = "<mark>"
+	snippetStop  = "</mark>"
+)
+
+// highlightSnippet turns a search snippet into text with the matched words in mark elements.  The snippet is
+// document text and is never rendered as HTML, only the markers are interpreted.
+func highlightSnippet(snippet string) []app.UI {
+	var parts []app.UI
+	for snippet != "" {
+		start := strings.Index(snippet, snippetStart)
+		if start < 0 {
+			parts = append(parts, app.Text(snippet))
+			break
+		}
+		if start > 0 {
+			parts = append(parts, app.Text(snippet[:start]))
+		}
+		snippet = snippet[start+len(snippetStart):]
+		stop := strings.Index(snippet, snippetStop)
+		if stop < 0 {
+			stop = len(snippet)
+		}
+		parts = append(parts, app.Mark().Text(snippet[:stop]))
+		snippet = strings.TrimPrefix(snippet[stop:], snippetStop)
+	}
+	return parts
+}
@@ -1,7 +1,10 @@
 package webapp
 
 import (
+	"strings"
 	"testing"
+
+	"github.com/maxence-charriere/go-app/v10/pkg/app"
 )
 
 // TestSearchPageInitialState tests the initial state of the search page
@@ -187,6 +190,9 @@ func TestSearchResultItemRender(t *testing.T) {
 				Openable: true,
 				FullPath: "/documents/Finance/Test_Document.pdf",
 				FileURL:  "/document/view/01ABCDEFGHIJKLMNOPQRSTUVWX",
+				Snippet:  "the <mark>test</mark> document",
+				Rank:     0.06,
+				Page:     2,
 			},
 		}
 
@@ -606,3 +612,100 @@ func TestFormatBytes(t *testing.T) {
 		})
 	}
 }
+
+// TestHighlightSnippet tests that matched words are marked and the rest of the snippet is kept as text
+func TestHighlightSnippet(t *testing.T) {
+	tests := []struct {
+		snippet string
+		want    string
+	}{
+		{"no matches here", "no matches here"},
+		{"the <mark>invoice</mark> total", "the <mark>invoice</mark> total"},
+		{"<mark>March</mark> <mark>invoice</mark>", "<mark>March</mark> <mark>invoice</mark>"},
+		{"<b>bold</b> <mark>invoice</mark>", "&lt;b&gt;bold&lt;/b&gt; <mark>invoice</mark>"},
+		{"unterminated <mark>invoice", "unterminated <mark>invoice</mark>"},
+	}
+	for _, tt := range tests {
+		var got strings.Builder
+		for _, part := range highlightSnippet(tt.snippet) {
+			got.WriteString(app.HTMLString(part))
+		}
+		if got.String() != tt.want {
+			t.Errorf("highlightSnippet(%q) = %q, want %q", tt.snippet, got.String(), tt.want)
+		}
+	}
+}
+
+// TestSearchParams tests that the search form round trips through the URL, with sizes in bytes
+func TestSearchParams(t *testing.T) {
+	page := &SearchPage{
+		searchTerm:   " invoice ",
+		from:         "2024-01-01",
+		documentType: "pdf",
+		minSizeMB:    "1.5",
+		sort:         "name",
+	}
+	params := page.searchParams()
+	want := "document_type=pdf&from=2024-01-01&min_size=1572864&sort=name&term=invoice"
+	if params.Encode() != want {
+		t.Errorf("searchParams() = %s, want %s", params.Encode(), want)
+	}
+
+	loaded := &SearchPage{}
+	loaded.setSearchParams(params)
+	if loaded.minSizeMB != "1.5" || loaded.maxSizeMB != "" || loaded.searchTerm != "invoice" || loaded.sort != "name" {
+		t.Errorf("setSearchParams() filled in %+v", loaded)
+	}
+
+	if (&SearchPage{sort: "newest", fuzzy: true}).hasSearch() {
+		t.Error("A sort order or fuzzy on their own should not be a search")
+	}
+	fuzzy := &SearchPage{searchTerm: "lnvoice", fuzzy: true}
+	if got := fuzzy.searchParams().Encode(); got != "fuzzy=true&term=lnvoice" {
+		t.Errorf("searchParams() = %s, want fuzzy=true&term=lnvoice", got)
+	}
+	loaded.setSearchParams(fuzzy.searchParams())
+	if !loaded.fuzzy {
+		t.Error("setSearchParams() did not fill in fuzzy")
+	}
+	if !(&SearchPage{folder: "bills"}).hasSearch() {
+		t.Error("A filter without a term should be a search")
+	}
+	if got := megabytesToBytes("lots"); got != "lots" {
+		t.Errorf("megabytesToBytes(\"lots\") = %s, want it passed on for the server to reject", got)
+	}
+}
+
+// TestApplyFacet tests that choosing a facet value narrows the search to what it counted
+func TestApplyFacet(t *testing.T) {
+	tests := []struct {
+		facet string
+		value string
+		want  string
+	}{
+		{"documentType", ".pdf", "document_type=pdf&term=bill"},
+		{"folder", "/bills/2024", "folder=%2Fbills%2F2024&term=bill"},
+		{"year", "2024", "from=2024-01-01&term=bill&to=2024-12-31"},
+		{"month", "2024-02", "from=2024-02-01&term=bill&to=2024-02-29"},
+		{"month", "not a month", "term=bill"},
+	}
+	for _, tt := range tests {
+		page := &SearchPage{searchTerm: "bill"}
+		page.applyFacet(tt.facet, tt.value)
+		if got := page.searchParams().Encode(); got != tt.want {
+			t.Errorf("applyFacet(%s, %s) searched %s, want %s", tt.facet, tt.value, got, tt.want)
+		}
+	}
+
+	labels := map[[2]string]string{
+		{"documentType", ".pdf"}: "PDF",
+		{"documentType", ""}:     "No extension",
+		{"month", "2024-03"}:     "March 2024",
+		{"folder", "/bills"}:     "/bills",
+	}
+	for facet, want := range labels {
+		if got := facetLabel(facet[0], facet[1]); got != want {
+			t.Errorf("facetLabel(%s, %s) = %s, want %s", facet[0], facet[1], got, want)
+		}
+	}
+}
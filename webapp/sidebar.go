@@ -37,6 +37,7 @@ func (s *Sidebar) Render() app.UI {
 				s.renderNavItem("🏠", "Home", "/"),
 				s.renderNavItem("📁", "Browse Documents", "/browse"),
 				s.renderNavItem("📥", "Ingest Now", "/ingest"),
+				s.renderNavItem("🚧", "Quarantine", "/quarantine"),
 				s.renderNavItem("🧹", "Clean Database", "/clean"),
 				s.renderNavItem("🔍", "Search", "/search"),
 				s.renderNavItem("📊", "Word Cloud", "/wordcloud"),